@@ -64,9 +64,7 @@ install -m 0644 -vp docs/man/*.1 %{buildroot}%{_mandir}/man1
 %{_bindir}/go-fdo-client
 # Man pages
 %{_mandir}/man1/go-fdo-client.1*
-%{_mandir}/man1/go-fdo-client-device-init.1*
-%{_mandir}/man1/go-fdo-client-onboard.1*
-%{_mandir}/man1/go-fdo-client-print.1*
+%{_mandir}/man1/go-fdo-client-*.1*
 
 %changelog
 %autochangelog
@@ -0,0 +1,133 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/viper"
+)
+
+// kernelCmdlineFile is the file the fdo.* kernel command line parameters
+// were read from by loadConfig, empty if the kernel command line is ignored.
+var kernelCmdlineFile string
+
+// kernelCmdlinePrefix is the prefix of the kernel command line parameters
+// that set configuration keys.
+const kernelCmdlinePrefix = "fdo."
+
+// splitKernelCmdline splits a kernel command line into parameters. Double
+// quotes group words into one parameter and are removed, and parsing stops at
+// "--", after which the parameters are passed to init.
+func splitKernelCmdline(cmdline string) []string {
+	var params []string
+	var param strings.Builder
+	inParam, quoted := false, false
+	for _, r := range cmdline {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inParam = true
+		case unicode.IsSpace(r) && !quoted:
+			if inParam {
+				if param.String() == "--" {
+					return params
+				}
+				params = append(params, param.String())
+				param.Reset()
+				inParam = false
+			}
+		default:
+			param.WriteRune(r)
+			inParam = true
+		}
+	}
+	if inParam && param.String() != "--" {
+		params = append(params, param.String())
+	}
+	return params
+}
+
+// kernelCmdlineKeys returns the configuration keys set by a kernel command
+// line parameter name, with the fdo. prefix removed and underscores standing
+// for dashes. A name without a section, such as kex or insecure-tls, sets the
+// key of that name in every section that has it.
+func kernelCmdlineKeys(name string) []string {
+	fileType := reflect.TypeFor[fileConfig]()
+	isValue := func(key string) bool {
+		t, ok := configKeyType(fileType, key)
+		return ok && t.Kind() != reflect.Struct && t.Kind() != reflect.Map
+	}
+
+	name = strings.ToLower(strings.ReplaceAll(name, "_", "-"))
+	if strings.Contains(name, ".") {
+		if isValue(name) {
+			return []string{name}
+		}
+		return nil
+	}
+	var keys []string
+	for _, key := range []string{name, "device-init." + name, "onboard." + name} {
+		if isValue(key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// parseKernelCmdline returns the configuration keys set by the fdo.*
+// parameters of a kernel command line. A parameter without a value sets a
+// key to true.
+func parseKernelCmdline(cmdline string) map[string]string {
+	values := map[string]string{}
+	for _, param := range splitKernelCmdline(cmdline) {
+		name, value, hasValue := strings.Cut(param, "=")
+		if !strings.HasPrefix(name, kernelCmdlinePrefix) {
+			continue
+		}
+		if !hasValue {
+			value = "true"
+		}
+		keys := kernelCmdlineKeys(strings.TrimPrefix(name, kernelCmdlinePrefix))
+		if len(keys) == 0 {
+			slog.Warn("Ignoring unknown kernel command line parameter", "parameter", name)
+			continue
+		}
+		for _, key := range keys {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+// mergeKernelCmdline merges the configuration keys set on the kernel command
+// line over the configuration files. The kernel command line is only read
+// when the kernel-cmdline option names its file, with the flag, the
+// environment or the configuration files.
+func mergeKernelCmdline() error {
+	kernelCmdlineFile = viper.GetString("kernel-cmdline")
+	if kernelCmdlineFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(kernelCmdlineFile)
+	if err != nil {
+		return fmt.Errorf("failed to read kernel command line: %w", err)
+	}
+
+	settings := map[string]any{}
+	for key, value := range parseKernelCmdline(string(data)) {
+		setConfigKey(settings, key, value)
+		configSources[key] = kernelCmdlineSource()
+	}
+	return viper.MergeConfigMap(settings)
+}
+
+func kernelCmdlineSource() string {
+	return "kernel command line " + kernelCmdlineFile
+}
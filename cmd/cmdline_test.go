@@ -0,0 +1,168 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func writeKernelCmdline(t *testing.T, cmdline string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "cmdline")
+	if err := os.WriteFile(path, []byte(cmdline+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSplitKernelCmdline(t *testing.T) {
+	tests := []struct {
+		cmdline string
+		want    []string
+	}{
+		{"BOOT_IMAGE=/vmlinuz ro  quiet\n", []string{"BOOT_IMAGE=/vmlinuz", "ro", "quiet"}},
+		{`fdo.device_info="rack 4 slot 2" fdo.kex=ECDH256`, []string{"fdo.device_info=rack 4 slot 2", "fdo.kex=ECDH256"}},
+		{"ro fdo.kex=ECDH256 -- fdo.blob=/init/cred", []string{"ro", "fdo.kex=ECDH256"}},
+		{`fdo.serial_number=""`, []string{"fdo.serial_number="}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := splitKernelCmdline(tt.cmdline); !slices.Equal(got, tt.want) {
+			t.Errorf("splitKernelCmdline(%q) = %q, want %q", tt.cmdline, got, tt.want)
+		}
+	}
+}
+
+func TestKernelCmdlineKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"blob", []string{"blob"}},
+		{"kex", []string{"onboard.kex"}},
+		{"insecure_tls", []string{"device-init.insecure-tls", "onboard.insecure-tls"}},
+		{"progress_file", []string{"progress-file"}},
+		{"onboard.insecure_tls", []string{"onboard.insecure-tls"}},
+		{"device_init.server_url", []string{"device-init.server-url"}},
+		{"onboard", nil},
+		{"kexx", nil},
+	}
+	for _, tt := range tests {
+		if got := kernelCmdlineKeys(tt.name); !slices.Equal(got, tt.want) {
+			t.Errorf("kernelCmdlineKeys(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestKernelCmdline_Precedence checks the precedence flag > env > kernel
+// command line > file > default.
+func TestKernelCmdline_Precedence(t *testing.T) {
+	cmdline := writeKernelCmdline(t, `BOOT_IMAGE=(hd0,gpt3)/vmlinuz root=UUID=1234 rw fdo.blob=/boot/device_credential `+
+		`fdo.kex=ECDH384 fdo.insecure_tls=1 fdo.onboard.max_attempts=3 fdo.once fdo.cipher=A256GCM fdo.timeout=10m `+
+		`fdo.unknown=1 -- fdo.resale=1`)
+	t.Setenv("FDO_CLIENT_ONBOARD_MAX_ATTEMPTS", "5")
+
+	yaml := `blob: config.bin
+key: ec256
+onboard:
+  kex: ECDH256
+  cipher: A192GCM
+  max-serviceinfo-size: 2000`
+	toml := `blob = "config.bin"
+key = "ec256"
+[onboard]
+kex = "ECDH256"
+cipher = "A192GCM"
+max-serviceinfo-size = 2000`
+
+	runTestBothFormats(t, "", onboardCmd, toml, yaml, false, "--kernel-cmdline", cmdline, "--cipher", "A128GCM")
+
+	o := capturedConfig.OnboardConfig
+	checks := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"Blob (cmdline over file)", capturedConfig.Blob, "/boot/device_credential"},
+		{"Key (file)", capturedConfig.Key, "ec256"},
+		{"Kex (cmdline over file)", o.Kex, "ECDH384"},
+		{"Cipher (flag over cmdline)", o.Cipher, "A128GCM"},
+		{"MaxAttempts (env over cmdline)", o.MaxAttempts, 5},
+		{"InsecureTLS (cmdline)", o.InsecureTLS, true},
+		{"Once (cmdline without value)", o.Once, true},
+		{"MaxServiceInfoSize (file)", o.MaxServiceInfoSize, 2000},
+		{"Resale (after --)", o.Resale, false},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+	if got, want := o.Timeout.String(), "10m0s"; got != want {
+		t.Errorf("Timeout = %s, want %s", got, want)
+	}
+}
+
+// TestKernelCmdline_OptIn checks that the kernel command line is only read
+// when its file is configured, with the flag, the environment or the
+// configuration file.
+func TestKernelCmdline_OptIn(t *testing.T) {
+	cmdline := writeKernelCmdline(t, "fdo.kex=ECDH384")
+	yaml := "blob: cred.bin\nkey: ec256\nonboard:\n  kex: ECDH256\n"
+
+	for _, tt := range []struct {
+		name string
+		yaml string
+		env  string
+		want string
+	}{
+		{"disabled by default", yaml, "", "ECDH256"},
+		{"environment", yaml, cmdline, "ECDH384"},
+		{"configuration file", "kernel-cmdline: " + cmdline + "\n" + yaml, "", "ECDH384"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("FDO_CLIENT_KERNEL_CMDLINE", tt.env)
+			if err := runTest(t, onboardCmd, tt.yaml, "yaml"); err != nil {
+				t.Fatal(err)
+			}
+			if got := capturedConfig.OnboardConfig.Kex; got != tt.want {
+				t.Errorf("kex = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKernelCmdline_Errors(t *testing.T) {
+	t.Run("missing file", func(t *testing.T) {
+		err := runCLI(t, onboardCmd, "onboard", "--blob", "cred.bin", "--key", "ec256", "--kex", "ECDH256",
+			"--kernel-cmdline", filepath.Join(t.TempDir(), "missing"))
+		if err == nil {
+			t.Fatal("expected missing kernel command line file to fail")
+		}
+	})
+
+	t.Run("invalid value", func(t *testing.T) {
+		cmdline := writeKernelCmdline(t, "fdo.kex=ECDH999")
+		err := runCLI(t, onboardCmd, "onboard", "--blob", "cred.bin", "--key", "ec256", "--kernel-cmdline", cmdline)
+		if err == nil {
+			t.Fatal("expected invalid kex from the kernel command line to fail validation")
+		}
+	})
+}
+
+func TestKernelCmdline_ConfigShow(t *testing.T) {
+	cmdline := writeKernelCmdline(t, "quiet fdo.tpm=/dev/tpmrm0 fdo.kex=ECDH256")
+	resetState(t)
+	report := runConfigShow(t, "--kernel-cmdline", cmdline)
+	source := "kernel command line " + cmdline
+	if got := findConfigValue(t, report, "tpm"); got.Value != "/dev/tpmrm0" || got.Source != source {
+		t.Errorf("tpm = %+v, want /dev/tpmrm0 from %s", got, source)
+	}
+	if got := findConfigValue(t, report, "onboard.kex"); got.Value != "ECDH256" || got.Source != source {
+		t.Errorf("onboard.kex = %+v, want ECDH256 from %s", got, source)
+	}
+}
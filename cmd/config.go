@@ -2,6 +2,11 @@ package cmd
 
 import (
 	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
 	"slices"
 	"strings"
 	"time"
@@ -23,6 +28,219 @@ func bindFlags(cmd *cobra.Command, prefix string) error {
 	return bindErr
 }
 
+// envPrefix is the prefix of the environment variables that set configuration
+// keys, for example FDO_CLIENT_ONBOARD_KEX for onboard.kex.
+const envPrefix = "FDO_CLIENT"
+
+var envKeyReplacer = strings.NewReplacer(".", "_", "-", "_")
+
+// envVar returns the environment variable that sets a configuration key.
+func envVar(key string) string {
+	return envPrefix + "_" + strings.ToUpper(envKeyReplacer.Replace(key))
+}
+
+// bindEnv makes every configuration key settable from the environment. Viper
+// gives values precedence in the order flag, environment, config file and
+// flag default. AutomaticEnv only covers the keys viper knows from a flag or a
+// file, so the keys without a flag are bound explicitly.
+func bindEnv() {
+	viper.SetEnvPrefix(envPrefix)
+	viper.SetEnvKeyReplacer(envKeyReplacer)
+	viper.AutomaticEnv()
+	for _, key := range configLeafKeys(reflect.TypeFor[fileConfig](), "") {
+		if err := viper.BindEnv(key); err != nil {
+			slog.Error("configuration error - environment binding failed", "key", key, "error", err)
+		}
+	}
+}
+
+// systemConfigDir is searched for a configuration when --config is not given,
+// after the user configuration directory.
+var systemConfigDir = "/etc/go-fdo-client"
+
+var configExts = []string{".yaml", ".yml", ".toml"}
+
+var (
+	// configFiles lists the configuration files merged by loadConfig in
+	// order of increasing precedence.
+	configFiles []string
+	// configSources maps each key set by a configuration file or the kernel
+	// command line to the source that set it last.
+	configSources map[string]string
+	// configProfile is the name of the profile applied by loadConfig.
+	configProfile string
+)
+
+// configDirs returns the directories searched for a configuration when
+// --config is not given, highest priority first.
+func configDirs() []string {
+	var dirs []string
+	userDir := os.Getenv("XDG_CONFIG_HOME")
+	if userDir == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			userDir = filepath.Join(home, ".config")
+		}
+	}
+	if userDir != "" {
+		dirs = append(dirs, filepath.Join(userDir, "go-fdo-client"))
+	}
+	return append(dirs, systemConfigDir)
+}
+
+// findConfig returns the first directory of configDirs that holds a config
+// file or a config.d directory, and the config file if there is one.
+func findConfig() (string, string) {
+	for _, dir := range configDirs() {
+		for _, ext := range configExts {
+			if path := filepath.Join(dir, "config"+ext); isFile(path) {
+				return dir, path
+			}
+		}
+		if info, err := os.Stat(filepath.Join(dir, "config.d")); err == nil && info.IsDir() {
+			return dir, ""
+		}
+	}
+	return "", ""
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// dropInFiles returns the configuration fragments in the config.d directory
+// below dir in lexical order.
+func dropInFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(dir, "config.d"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config drop-in directory: %w", err)
+	}
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || !slices.Contains(configExts, filepath.Ext(name)) {
+			continue
+		}
+		files = append(files, filepath.Join(dir, "config.d", name))
+	}
+	return files, nil
+}
+
+// loadConfig reads the configuration file, then merges the fragments of the
+// config.d directory next to it in lexical order, and finally the fdo.*
+// kernel command line parameters. Without --config or $FDO_CLIENT_CONFIG the
+// first of configDirs holding a config file or a config.d directory is used.
+// Any previously loaded configuration is replaced.
+func loadConfig() error {
+	path := configFile
+	if path == "" {
+		path = os.Getenv(envVar("config"))
+	}
+	var dir string
+	if path != "" {
+		dir = filepath.Dir(path)
+	} else {
+		dir, path = findConfig()
+	}
+
+	var files []string
+	if path != "" {
+		files = append(files, path)
+	}
+	if dir != "" {
+		dropIns, err := dropInFiles(dir)
+		if err != nil {
+			return err
+		}
+		files = append(files, dropIns...)
+	}
+
+	viper.SetConfigType("yaml")
+	if err := viper.ReadConfig(strings.NewReader("")); err != nil {
+		return err
+	}
+	configFiles, configSources = nil, map[string]string{}
+	for _, file := range files {
+		fragment := viper.New()
+		fragment.SetConfigFile(file)
+		if err := fragment.ReadInConfig(); err != nil {
+			return fmt.Errorf("failed to read config file: %w", err)
+		}
+		settings := fragment.AllSettings()
+		if err := viper.MergeConfigMap(settings); err != nil {
+			return fmt.Errorf("failed to merge config file %s: %w", file, err)
+		}
+		for _, key := range fragment.AllKeys() {
+			configSources[key] = file
+		}
+		configFiles = append(configFiles, file)
+	}
+	if err := mergeKernelCmdline(); err != nil {
+		return err
+	}
+	return applyProfile()
+}
+
+// applyProfile merges the profile selected by the profile key over the
+// configuration files. The profile may be selected on the kernel command
+// line, but does not override the values set there.
+func applyProfile() error {
+	configProfile = strings.ToLower(viper.GetString("profile"))
+	if configProfile == "" {
+		return nil
+	}
+	profileKey := "profiles." + configProfile
+	profile, ok := viper.Get(profileKey).(map[string]any)
+	if !ok {
+		available := slices.Sorted(maps.Keys(viper.GetStringMap("profiles")))
+		if len(available) == 0 {
+			return fmt.Errorf("profile %q not found, the configuration has no profiles", configProfile)
+		}
+		return fmt.Errorf("profile %q not found, available profiles [%s]", configProfile, strings.Join(available, ", "))
+	}
+
+	values := viper.New()
+	if err := values.MergeConfigMap(profile); err != nil {
+		return err
+	}
+	settings := map[string]any{}
+	for _, key := range values.AllKeys() {
+		if configSources[key] == kernelCmdlineSource() {
+			continue
+		}
+		setConfigKey(settings, key, values.Get(key))
+		configSources[key] = fmt.Sprintf("profile %s (%s)", configProfile, configSources[profileKey+"."+key])
+	}
+	return viper.MergeConfigMap(settings)
+}
+
+// setConfigKey sets the value of a dotted key in nested settings.
+func setConfigKey(settings map[string]any, key string, value any) {
+	section, rest, nested := strings.Cut(key, ".")
+	if !nested {
+		settings[key] = value
+		return
+	}
+	sectionSettings, _ := settings[section].(map[string]any)
+	if sectionSettings == nil {
+		sectionSettings = map[string]any{}
+		settings[section] = sectionSettings
+	}
+	setConfigKey(sectionSettings, rest, value)
+}
+
+// configKeys returns the keys set by the configuration files, or all keys
+// known to viper if effective is set, in sorted order.
+func configKeys(effective bool) []string {
+	if effective {
+		return slices.Sorted(slices.Values(viper.AllKeys()))
+	}
+	return slices.Sorted(maps.Keys(configSources))
+}
+
 var validKeys = []string{"ec256", "ec384", "rsa2048", "rsa3072"}
 
 func validateKey(key string) error {
@@ -38,15 +256,33 @@ type FDOClientConfig struct {
 	Blob  string `mapstructure:"blob"`
 	TPM   string `mapstructure:"tpm"`
 	Key   string `mapstructure:"key"`
+
+	ProgressFile  string `mapstructure:"progress-file"`
+	Profile       string `mapstructure:"profile"`
+	TraceFile     string `mapstructure:"trace-file"`
+	KernelCmdline string `mapstructure:"kernel-cmdline"`
+
+	LogFormat     string `mapstructure:"log-format"`
+	LogLevel      string `mapstructure:"log-level"`
+	LogFile       string `mapstructure:"log-file"`
+	LogMaxSize    int    `mapstructure:"log-max-size"`
+	LogMaxBackups int    `mapstructure:"log-max-backups"`
 }
 
 type DeviceInitConfig struct {
-	ServerURL     string `mapstructure:"server-url"`
-	KeyEnc        string `mapstructure:"key-enc"`
-	DeviceInfo    string `mapstructure:"device-info"`
-	DeviceInfoMac string `mapstructure:"device-info-mac"`
-	InsecureTLS   bool   `mapstructure:"insecure-tls"`
-	SerialNumber  string `mapstructure:"serial-number"`
+	ServerURL     string   `mapstructure:"server-url"`
+	KeyEnc        string   `mapstructure:"key-enc"`
+	DeviceInfo    string   `mapstructure:"device-info"`
+	DeviceInfoMac string   `mapstructure:"device-info-mac"`
+	InsecureTLS   bool     `mapstructure:"insecure-tls"`
+	SerialNumber  string   `mapstructure:"serial-number"`
+	TLSCAFile     string   `mapstructure:"tls-ca-file"`
+	TLSPinSPKI    []string `mapstructure:"tls-pin-spki"`
+	TLSServerName string   `mapstructure:"tls-server-name"`
+	TLSClientCert string   `mapstructure:"tls-client-cert"`
+	TLSClientKey  string   `mapstructure:"tls-client-key"`
+
+	TransportConfig `mapstructure:",squash"`
 }
 
 type OnboardConfig struct {
@@ -59,6 +295,62 @@ type OnboardConfig struct {
 	AllowCredentialReuse bool          `mapstructure:"allow-credential-reuse"`
 	Resale               bool          `mapstructure:"resale"`
 	TO2RetryDelay        time.Duration `mapstructure:"to2-retry-delay"`
+	MaxAttempts          int           `mapstructure:"max-attempts"`
+	Timeout              time.Duration `mapstructure:"timeout"`
+	Once                 bool          `mapstructure:"once"`
+	StartDelay           time.Duration `mapstructure:"start-delay"`
+	TO1FailureDelay      time.Duration `mapstructure:"to1-failure-delay"`
+	TO2FailureDelay      time.Duration `mapstructure:"to2-failure-delay"`
+	BackoffMultiplier    float64       `mapstructure:"backoff-multiplier"`
+	MaxDelay             time.Duration `mapstructure:"max-delay"`
+	TO1TLSCAFile         string        `mapstructure:"to1-tls-ca-file"`
+	TO1TLSPinSPKI        []string      `mapstructure:"to1-tls-pin-spki"`
+	TO1TLSServerName     string        `mapstructure:"to1-tls-server-name"`
+	TO2TLSCAFile         string        `mapstructure:"to2-tls-ca-file"`
+	TO2TLSPinSPKI        []string      `mapstructure:"to2-tls-pin-spki"`
+	TO2TLSServerName     string        `mapstructure:"to2-tls-server-name"`
+	TLSClientCert        string        `mapstructure:"tls-client-cert"`
+	TLSClientKey         string        `mapstructure:"tls-client-key"`
+	Faults               []FaultConfig `mapstructure:"faults"`
+
+	TransportConfig `mapstructure:",squash"`
+}
+
+// FaultConfig is a fault injected into the TO1 and TO2 messages that match
+// it, to test the retry behavior. Faults are only set in the configuration
+// file.
+type FaultConfig struct {
+	MsgType int           `mapstructure:"msg-type"`
+	URL     string        `mapstructure:"url"`
+	Action  string        `mapstructure:"action"`
+	Delay   time.Duration `mapstructure:"delay"`
+	Status  int           `mapstructure:"status"`
+	Count   int           `mapstructure:"count"`
+}
+
+// TransportConfig contains the connection options of the device-init and
+// onboard sections, applied to the DI, and to the TO1 and TO2 connections.
+type TransportConfig struct {
+	ConnectTimeout       time.Duration `mapstructure:"connect-timeout"`
+	TLSHandshakeTimeout  time.Duration `mapstructure:"tls-handshake-timeout"`
+	RequestTimeout       time.Duration `mapstructure:"request-timeout"`
+	Proxy                string        `mapstructure:"proxy"`
+	NoProxy              []string      `mapstructure:"no-proxy"`
+	ProxyCredentialsFile string        `mapstructure:"proxy-credentials-file"`
+	SourceAddress        string        `mapstructure:"source-address"`
+	Interface            string        `mapstructure:"interface"`
+	TLSMinVersion        string        `mapstructure:"tls-min-version"`
+	TLSCipherPolicy      string        `mapstructure:"tls-cipher-policy"`
+	Record               string        `mapstructure:"record"`
+	Replay               string        `mapstructure:"replay"`
+}
+
+// ProfileConfig is a named set of device-init and onboard options that
+// override the options of the configuration file when selected with
+// --profile. Options that are not set keep their shared values.
+type ProfileConfig struct {
+	DeviceInit DeviceInitConfig `mapstructure:"device-init"`
+	Onboard    OnboardConfig    `mapstructure:"onboard"`
 }
 
 type DeviceInitClientConfig struct {
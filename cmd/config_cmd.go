@@ -0,0 +1,340 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+var validConfigOutputs = []string{"text", "json"}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect and validate the client configuration",
+	Long: `
+Inspect the configuration assembled from the configuration files, environment
+variables and command line flags, check configuration files, or print their
+JSON Schema.
+
+Without --config the configuration is read from the first of
+$XDG_CONFIG_HOME/go-fdo-client and /etc/go-fdo-client that holds a
+config.yaml, config.yml or config.toml file or a config.d directory. The
+fragments in the config.d directory next to the configuration file are merged
+over it in lexical order, so that a base configuration can be layered with
+site specific overrides such as config.d/50-site.yaml. The fdo.* kernel
+command line parameters are merged over the configuration files.`,
+	Args: cobra.NoArgs,
+	// The configuration commands neither need a device credential nor a
+	// valid configuration
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return nil
+	},
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the merged configuration and the source of each value",
+	Long: `
+Print the values set by the configuration files and the fdo.* kernel command
+line parameters after merging, with the file that set each value. With
+--effective every configuration key is printed with the value in effect after
+applying environment variables, command line flags and defaults, and its
+source: a flag, an environment variable, the kernel command line, a
+configuration file or the default.`,
+	Example: `
+  # Show which drop-in file overrides a value:
+  go-fdo-client config show
+
+  # Show the configuration used by onboard, including FDO_CLIENT_* variables:
+  go-fdo-client config show --effective --output json`,
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		output, err := cmd.Flags().GetString("output")
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(validConfigOutputs, output) {
+			return fmt.Errorf("invalid output format: '%s', options [%s]", output, strings.Join(validConfigOutputs, ", "))
+		}
+
+		if err := loadConfig(); err != nil {
+			return err
+		}
+
+		// Make the device-init and onboard keys and their defaults known
+		if err := bindFlags(deviceInitCmd, "device-init"); err != nil {
+			return err
+		}
+		return bindFlags(onboardCmd, "onboard")
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		output, err := cmd.Flags().GetString("output")
+		if err != nil {
+			return err
+		}
+		effective, err := cmd.Flags().GetBool("effective")
+		if err != nil {
+			return err
+		}
+		return writeConfigReport(cmd.OutOrStdout(), newConfigReport(effective), output)
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate <file>...",
+	Short: "Check configuration files for unknown keys and invalid values",
+	Long: `
+Check configuration files without reading the device credential or accessing
+the network, for example in CI before baking them into an image. Multiple
+files are merged in the given order, like a configuration file and its
+config.d fragments.
+
+Each file is checked for keys that are not configuration keys, such as
+misspelled keys that would otherwise be ignored. The merged configuration is
+then checked like the commands check it: the global options always, and the
+device-init and onboard sections if they are present, also with each profile
+applied. The default working directory is only required to be an absolute
+path.
+
+The exit code is 0 when no problem was found and 1 otherwise.`,
+	Example: `
+  # Check a configuration and its drop-in fragments:
+  go-fdo-client config validate config.yaml config.d/*.yaml`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		problems := validateConfigFiles(args)
+		w := cmd.OutOrStdout()
+		for _, problem := range problems {
+			fmt.Fprintln(w, problem)
+		}
+		if len(problems) > 0 {
+			cmd.SilenceErrors = true
+			return &exitCodeError{code: 1, err: fmt.Errorf("%d configuration problems found", len(problems))}
+		}
+		fmt.Fprintf(w, "%s: OK\n", strings.Join(args, ", "))
+		return nil
+	},
+}
+
+var configSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema of the configuration file",
+	Long: `
+Print the JSON Schema (draft 2020-12) of the configuration file, for editors
+and CI tools to validate YAML and TOML configuration files against.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(configSchema())
+	},
+}
+
+func configCmdInit() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configShowCmd, configValidateCmd, configSchemaCmd)
+	configShowCmd.Flags().Bool("effective", false, "Print every key with the value in effect and its source")
+	configShowCmd.Flags().String("output", "text", "Output format [options: text, json]")
+}
+
+func init() {
+	configCmdInit()
+}
+
+// configValue is a configuration value and where it was set.
+type configValue struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// configReport is the merged configuration printed by config show.
+type configReport struct {
+	Files   []string      `json:"files"`
+	Profile string        `json:"profile,omitempty"`
+	Values  []configValue `json:"values"`
+}
+
+func newConfigReport(effective bool) *configReport {
+	report := &configReport{Files: configFiles, Profile: configProfile, Values: []configValue{}}
+	if report.Files == nil {
+		report.Files = []string{}
+	}
+	for _, key := range configKeys(effective) {
+		source := configSources[key]
+		if effective {
+			source = configSource(key)
+		}
+		report.Values = append(report.Values, configValue{
+			Key:    key,
+			Value:  fmt.Sprint(viper.Get(key)),
+			Source: source,
+		})
+	}
+	return report
+}
+
+// configSource returns where the effective value of a key was set, in order
+// of precedence.
+func configSource(key string) string {
+	if flag := configFlag(key); flag != nil && flag.Changed {
+		return "flag --" + key
+	}
+	if env := envVar(key); os.Getenv(env) != "" {
+		return "env " + env
+	}
+	if file, ok := configSources[key]; ok {
+		return file
+	}
+	return "default"
+}
+
+func writeConfigReport(w io.Writer, report *configReport, output string) error {
+	if output == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+
+	if len(report.Files) == 0 {
+		fmt.Fprintln(w, "Files:   none")
+	}
+	for _, file := range report.Files {
+		fmt.Fprintf(w, "File:    %s\n", file)
+	}
+	if report.Profile != "" {
+		fmt.Fprintf(w, "Profile: %s\n", report.Profile)
+	}
+	keyWidth, valueWidth := 0, 0
+	for _, value := range report.Values {
+		keyWidth = max(keyWidth, len(value.Key))
+		valueWidth = max(valueWidth, len(value.Value))
+	}
+	for _, value := range report.Values {
+		fmt.Fprintf(w, "%-*s  %-*s  %s\n", keyWidth, value.Key, valueWidth, value.Value, value.Source)
+	}
+	return nil
+}
+
+// validateConfigFiles merges the configuration files in order and returns the
+// problems found in them.
+func validateConfigFiles(files []string) []string {
+	var problems []string
+	fileType := reflect.TypeFor[fileConfig]()
+
+	// Start from the flag defaults, as the commands do
+	v := viper.New()
+	for _, key := range configLeafKeys(fileType, "") {
+		flag := configFlag(key)
+		if flag == nil {
+			continue
+		}
+		// The default of a list flag is printed as "[]"
+		if _, ok := flag.Value.(pflag.SliceValue); ok {
+			v.SetDefault(key, []string{})
+		} else {
+			v.SetDefault(key, flag.DefValue)
+		}
+	}
+
+	sections := map[string]bool{}
+	for _, file := range files {
+		fragment := viper.New()
+		fragment.SetConfigFile(file)
+		if err := fragment.ReadInConfig(); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", file, err))
+			continue
+		}
+		var unknown []string
+		for _, key := range fragment.AllKeys() {
+			if prefix := unknownConfigKey(fileType, key); prefix != "" && !slices.Contains(unknown, prefix) {
+				unknown = append(unknown, prefix)
+			}
+			unknown = append(unknown, unknownListKeys(fileType, key, fragment.Get(key))...)
+			section, _, _ := strings.Cut(key, ".")
+			sections[section] = true
+		}
+		slices.Sort(unknown)
+		for _, key := range unknown {
+			problems = append(problems, fmt.Sprintf("%s: unknown key %s", file, key))
+		}
+		if err := v.MergeConfigMap(fragment.AllSettings()); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", file, err))
+		}
+	}
+	if len(problems) > 0 {
+		return problems
+	}
+
+	var config fileConfig
+	if err := v.Unmarshal(&config); err != nil {
+		return []string{err.Error()}
+	}
+	if err := config.FDOClientConfig.validate(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if config.Profile != "" {
+		if _, ok := config.Profiles[strings.ToLower(config.Profile)]; !ok {
+			problems = append(problems, fmt.Sprintf("profile %q not found", config.Profile))
+		}
+	}
+	problems = append(problems, validateConfigSections(v, sections, "")...)
+
+	// Check each profile applied over the shared configuration
+	for _, name := range slices.Sorted(maps.Keys(config.Profiles)) {
+		profile := viper.New()
+		if err := profile.MergeConfigMap(v.AllSettings()); err != nil {
+			return append(problems, err.Error())
+		}
+		profileSettings, _ := v.Get("profiles." + name).(map[string]any)
+		if err := profile.MergeConfigMap(profileSettings); err != nil {
+			return append(problems, err.Error())
+		}
+		profileSections := maps.Clone(sections)
+		for section := range profileSettings {
+			profileSections[section] = true
+		}
+		problems = append(problems, validateConfigSections(profile, profileSections, "profile "+name+": ")...)
+	}
+	return problems
+}
+
+// validateConfigSections validates the device-init and onboard sections of a
+// configuration if they are present.
+func validateConfigSections(v *viper.Viper, sections map[string]bool, prefix string) []string {
+	var config fileConfig
+	if err := v.Unmarshal(&config); err != nil {
+		return []string{prefix + err.Error()}
+	}
+	var problems []string
+	if sections["device-init"] {
+		di := DeviceInitClientConfig{FDOClientConfig: config.FDOClientConfig, DeviceInit: config.DeviceInit}
+		if err := di.validate(); err != nil {
+			problems = append(problems, prefix+"device-init: "+err.Error())
+		}
+	}
+	if sections["onboard"] {
+		onboard := OnboardClientConfig{FDOClientConfig: config.FDOClientConfig, Onboard: config.Onboard}
+		if err := onboard.validateOptions(); err != nil {
+			problems = append(problems, prefix+"onboard: "+err.Error())
+		}
+		if dir := onboard.Onboard.DefaultWorkingDir; dir != "" && !filepath.IsAbs(dir) {
+			problems = append(problems, fmt.Sprintf("%sonboard: default-working-dir must be an absolute path, got: %s", prefix, dir))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,412 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// testConfigDirs creates the user and system configuration directories
+// searched without --config and returns them.
+func testConfigDirs(t *testing.T) (string, string) {
+	t.Helper()
+	resetState(t)
+	xdg := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	systemConfigDir = t.TempDir()
+	return filepath.Join(xdg, "go-fdo-client"), systemConfigDir
+}
+
+func writeConfigFile(t *testing.T, path, contents string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func executeOnboard(t *testing.T, args ...string) {
+	t.Helper()
+	stubRunE(t, onboardCmd)
+	rootCmd.SetArgs(append([]string{"onboard"}, args...))
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func runConfigShow(t *testing.T, args ...string) *configReport {
+	t.Helper()
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	t.Cleanup(func() { rootCmd.SetOut(nil) })
+	rootCmd.SetArgs(append([]string{"config", "show", "--output", "json"}, args...))
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("config show failed: %v", err)
+	}
+	var report configReport
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON output: %v\n%s", err, out.String())
+	}
+	return &report
+}
+
+func findConfigValue(t *testing.T, report *configReport, key string) configValue {
+	t.Helper()
+	for _, value := range report.Values {
+		if value.Key == key {
+			return value
+		}
+	}
+	t.Fatalf("key %s missing from %+v", key, report.Values)
+	return configValue{}
+}
+
+func TestConfig_Discovery(t *testing.T) {
+	t.Run("system", func(t *testing.T) {
+		_, system := testConfigDirs(t)
+		writeConfigFile(t, filepath.Join(system, "config.yaml"), "blob: system.bin\nkey: ec256\nonboard:\n  kex: ECDH256")
+		executeOnboard(t)
+		if got, want := capturedConfig.Blob, "system.bin"; got != want {
+			t.Errorf("Blob = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("user before system", func(t *testing.T) {
+		user, system := testConfigDirs(t)
+		writeConfigFile(t, filepath.Join(system, "config.yaml"), "blob: system.bin\nkey: ec256\nonboard:\n  kex: ECDH256")
+		writeConfigFile(t, filepath.Join(user, "config.toml"), "blob = \"user.bin\"\nkey = \"ec384\"\n[onboard]\nkex = \"ECDH384\"")
+		executeOnboard(t)
+		if got, want := capturedConfig.Blob, "user.bin"; got != want {
+			t.Errorf("Blob = %q, want %q", got, want)
+		}
+		if got, want := capturedConfig.OnboardConfig.Kex, "ECDH384"; got != want {
+			t.Errorf("Kex = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("--config skips discovery", func(t *testing.T) {
+		_, system := testConfigDirs(t)
+		writeConfigFile(t, filepath.Join(system, "config.yaml"), "blob: system.bin\nkey: ec256\nonboard:\n  kex: ECDH256")
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		writeConfigFile(t, path, "blob: cli.bin\nkey: ec256\nonboard:\n  kex: ECDH256")
+		executeOnboard(t, "--config", path)
+		if got, want := capturedConfig.Blob, "cli.bin"; got != want {
+			t.Errorf("Blob = %q, want %q", got, want)
+		}
+	})
+}
+
+func TestConfig_DropIns(t *testing.T) {
+	_, system := testConfigDirs(t)
+	writeConfigFile(t, filepath.Join(system, "config.yaml"), `blob: base.bin
+key: ec256
+onboard:
+  kex: ECDH256
+  cipher: A128GCM
+  max-attempts: 3`)
+	writeConfigFile(t, filepath.Join(system, "config.d", "20-site.toml"), "[onboard]\nkex = \"ECDH384\"\nmax-attempts = 5")
+	writeConfigFile(t, filepath.Join(system, "config.d", "10-image.yaml"), "key: ec384\nonboard:\n  kex: DHKEXid14\n  max-attempts: 4")
+	writeConfigFile(t, filepath.Join(system, "config.d", "30-ignored.txt"), "blob: ignored.bin")
+	writeConfigFile(t, filepath.Join(system, "config.d", ".40-hidden.yaml"), "blob: hidden.bin")
+
+	executeOnboard(t, "--cipher", "A256GCM")
+	o := capturedConfig.OnboardConfig
+	checks := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"Blob", capturedConfig.Blob, "base.bin"},
+		{"Key", capturedConfig.Key, "ec384"},
+		{"Kex", o.Kex, "ECDH384"},
+		{"Cipher", o.Cipher, "A256GCM"},
+		{"MaxAttempts", o.MaxAttempts, 5},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+
+	// Only a drop-in directory
+	_, system = testConfigDirs(t)
+	writeConfigFile(t, filepath.Join(system, "config.d", "10-only.yaml"), "blob: only.bin\nkey: ec256\nonboard:\n  kex: ECDH256")
+	executeOnboard(t)
+	if got, want := capturedConfig.Blob, "only.bin"; got != want {
+		t.Errorf("Blob = %q, want %q", got, want)
+	}
+}
+
+func TestConfig_DropInMalformed(t *testing.T) {
+	_, system := testConfigDirs(t)
+	writeConfigFile(t, filepath.Join(system, "config.yaml"), "blob: base.bin\nkey: ec256")
+	writeConfigFile(t, filepath.Join(system, "config.d", "10-bad.yaml"), "onboard: [unclosed")
+	stubRunE(t, onboardCmd)
+	rootCmd.SetArgs([]string{"onboard", "--kex", "ECDH256"})
+	if err := rootCmd.Execute(); err == nil {
+		t.Fatal("expected malformed drop-in to fail")
+	}
+}
+
+func TestConfigShow(t *testing.T) {
+	_, system := testConfigDirs(t)
+	base := filepath.Join(system, "config.yaml")
+	site := filepath.Join(system, "config.d", "50-site.yaml")
+	writeConfigFile(t, base, "blob: base.bin\nkey: ec256\nonboard:\n  kex: ECDH256\n  cipher: A192GCM")
+	writeConfigFile(t, site, "onboard:\n  kex: ECDH384")
+
+	t.Run("files", func(t *testing.T) {
+		report := runConfigShow(t)
+		if len(report.Files) != 2 || report.Files[0] != base || report.Files[1] != site {
+			t.Errorf("files = %v, want [%s %s]", report.Files, base, site)
+		}
+		if len(report.Values) != 4 {
+			t.Errorf("got %d values, want the 4 set by files: %+v", len(report.Values), report.Values)
+		}
+		if got := findConfigValue(t, report, "onboard.kex"); got.Value != "ECDH384" || got.Source != site {
+			t.Errorf("onboard.kex = %+v, want ECDH384 from %s", got, site)
+		}
+		if got := findConfigValue(t, report, "onboard.cipher"); got.Value != "A192GCM" || got.Source != base {
+			t.Errorf("onboard.cipher = %+v, want A192GCM from %s", got, base)
+		}
+	})
+
+	t.Run("effective", func(t *testing.T) {
+		t.Setenv("FDO_CLIENT_ONBOARD_CIPHER", "A256GCM")
+		report := runConfigShow(t, "--effective", "--blob", "cli.bin")
+		tests := []struct{ key, value, source string }{
+			{"blob", "cli.bin", "flag --blob"},
+			{"key", "ec256", base},
+			{"onboard.kex", "ECDH384", site},
+			{"onboard.cipher", "A256GCM", "env FDO_CLIENT_ONBOARD_CIPHER"},
+			{"onboard.max-serviceinfo-size", "1300", "default"},
+			{"device-init.key-enc", "x509", "default"},
+		}
+		for _, tt := range tests {
+			got := findConfigValue(t, report, tt.key)
+			if got.Value != tt.value || got.Source != tt.source {
+				t.Errorf("%s = %+v, want %s from %s", tt.key, got, tt.value, tt.source)
+			}
+		}
+	})
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []string
+	}{
+		{"valid YAML", map[string]string{"config.yaml": "tpm: /dev/tpmrm0\nkey: ec256\nonboard:\n  kex: ECDH256\n  max-delay: 10m\n  default-working-dir: /var/lib/fdo"}, nil},
+		{"valid TOML", map[string]string{"config.toml": "blob = \"cred.bin\"\nkey = \"ec384\"\n[device-init]\nserver-url = \"https://di.example.com:8038\""}, nil},
+		{"unknown keys", map[string]string{"config.yaml": "blob: cred.bin\nkey: ec256\nkex: ECDH256\nonbaord:\n  kex: ECDH256\n  cipher: A128GCM\nonboard:\n  kex: ECDH256\n  max-attemps: 3"},
+			[]string{"unknown key kex", "unknown key onbaord\n", "unknown key onboard.max-attemps"}},
+		{"unknown fault keys", map[string]string{"config.toml": "blob = \"cred.bin\"\nkey = \"ec256\"\n[onboard]\nkex = \"ECDH256\"\n[[onboard.faults]]\naction = \"drop\"\nmsgtype = 30"},
+			[]string{"unknown key onboard.faults[0].msgtype"}},
+		{"invalid fault", map[string]string{"config.yaml": "blob: cred.bin\nkey: ec256\nonboard:\n  kex: ECDH256\n  faults:\n    - action: http-error"}, []string{"onboard: faults[0]: the http-error action requires a status"}},
+		{"missing blob", map[string]string{"config.yaml": "key: ec256"}, []string{"either --blob or --tpm"}},
+		{"invalid onboard", map[string]string{"config.yaml": "blob: cred.bin\nkey: ec256\nonboard:\n  kex: ECDH256\n  cipher: BAD"}, []string{"onboard: invalid cipher suite: BAD"}},
+		{"relative working dir", map[string]string{"config.yaml": "blob: cred.bin\nkey: ec256\nonboard:\n  kex: ECDH256\n  default-working-dir: work"}, []string{"must be an absolute path"}},
+		{"invalid device-init", map[string]string{"config.yaml": "blob: cred.bin\nkey: ec256\ndevice-init:\n  server-url: not-a-url"}, []string{"device-init: invalid DI URL"}},
+		{"invalid type", map[string]string{"config.yaml": "blob: cred.bin\nkey: ec256\nonboard:\n  kex: ECDH256\n  max-attempts: many"}, []string{"max-attempts"}},
+		{"malformed", map[string]string{"config.yaml": "onboard: [unclosed"}, []string{"config.yaml: "}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			var files []string
+			for name, contents := range tt.files {
+				path := filepath.Join(dir, name)
+				writeConfigFile(t, path, contents)
+				files = append(files, path)
+			}
+			out, err := runCommand(t, append([]string{"config", "validate"}, files...)...)
+			if len(tt.want) == 0 {
+				if err != nil || !strings.Contains(out, ": OK") {
+					t.Fatalf("validate failed: %v\n%s", err, out)
+				}
+				return
+			}
+			if got := ExitCode(err); got != 1 {
+				t.Fatalf("exit code = %d, want 1\n%s", got, out)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("output does not contain %q:\n%s", want, out)
+				}
+			}
+		})
+	}
+}
+
+// TestConfigValidate_Fragments checks that files are merged in order before
+// the merged configuration is validated.
+func TestConfigValidate_Fragments(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "config.yaml")
+	site := filepath.Join(dir, "config.d", "50-site.toml")
+	writeConfigFile(t, base, "blob: cred.bin\nkey: ec256\nonboard:\n  kex: BAD")
+	writeConfigFile(t, site, "[onboard]\nkex = \"ECDH256\"")
+
+	if out, err := runCommand(t, "config", "validate", base, site); err != nil {
+		t.Fatalf("validate failed: %v\n%s", err, out)
+	}
+	if out, err := runCommand(t, "config", "validate", site, base); err == nil {
+		t.Fatalf("expected the later base file to override the fragment:\n%s", out)
+	}
+}
+
+func TestConfigSchema(t *testing.T) {
+	resetState(t)
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	t.Cleanup(func() { rootCmd.SetOut(nil) })
+	rootCmd.SetArgs([]string{"config", "schema"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("config schema failed: %v", err)
+	}
+
+	type property struct {
+		Type                 string              `json:"type"`
+		Enum                 []string            `json:"enum"`
+		Default              any                 `json:"default"`
+		Pattern              string              `json:"pattern"`
+		Description          string              `json:"description"`
+		AdditionalProperties any                 `json:"additionalProperties"`
+		Properties           map[string]property `json:"properties"`
+	}
+	var schema property
+	if err := json.Unmarshal(out.Bytes(), &schema); err != nil {
+		t.Fatalf("invalid JSON output: %v\n%s", err, out.String())
+	}
+	if schema.AdditionalProperties != false {
+		t.Errorf("schema allows unknown top-level keys")
+	}
+
+	onboard := schema.Properties["onboard"].Properties
+	if kex := onboard["kex"]; kex.Type != "string" || len(kex.Enum) != len(validKexSuites) || kex.Description == "" {
+		t.Errorf("onboard.kex = %+v", kex)
+	}
+	if cipher := onboard["cipher"]; cipher.Default != "A128GCM" {
+		t.Errorf("onboard.cipher default = %v, want A128GCM", cipher.Default)
+	}
+	if delay := onboard["max-delay"]; delay.Type != "string" || delay.Pattern == "" {
+		t.Errorf("onboard.max-delay = %+v, want duration string", delay)
+	}
+	if size := onboard["max-serviceinfo-size"]; size.Type != "integer" || size.Default != float64(1300) {
+		t.Errorf("onboard.max-serviceinfo-size = %+v", size)
+	}
+	if tpm := schema.Properties["tpm"]; tpm.Type != "string" {
+		t.Errorf("tpm = %+v", tpm)
+	}
+}
+
+// TestConfigKeysMatchFlags checks that every flag bound to the configuration
+// has a configuration key, so that config validate does not report it.
+func TestConfigKeysMatchFlags(t *testing.T) {
+	resetState(t)
+	fileType := reflect.TypeFor[fileConfig]()
+	for _, section := range []struct {
+		prefix string
+		cmd    *cobra.Command
+	}{
+		{"device-init.", deviceInitCmd},
+		{"onboard.", onboardCmd},
+	} {
+		section.cmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
+			if key := section.prefix + flag.Name; unknownConfigKey(fileType, key) != "" {
+				t.Errorf("flag --%s of %s has no configuration key %s", flag.Name, section.cmd.Name(), key)
+			}
+		})
+	}
+	for _, key := range []string{"blob", "tpm", "key", "debug", "progress-file", "profile"} {
+		if unknownConfigKey(fileType, key) != "" {
+			t.Errorf("global key %s is unknown", key)
+		}
+	}
+}
+
+func TestConfigShow_Profile(t *testing.T) {
+	resetState(t)
+	path := writeConfig(t, profilesYAML, "yaml")
+	report := runConfigShow(t, "--config", path, "--profile", "staging", "--effective")
+	if report.Profile != "staging" {
+		t.Errorf("profile = %q, want staging", report.Profile)
+	}
+	tests := []struct{ key, value, source string }{
+		{"onboard.max-attempts", "10", "profile staging (" + path + ")"},
+		{"device-init.server-url", "https://di.staging.example.com:8038", "profile staging (" + path + ")"},
+		{"onboard.kex", "ECDH256", path},
+		{"profile", "staging", "flag --profile"},
+	}
+	for _, tt := range tests {
+		got := findConfigValue(t, report, tt.key)
+		if got.Value != tt.value || got.Source != tt.source {
+			t.Errorf("%s = %+v, want %s from %s", tt.key, got, tt.value, tt.source)
+		}
+	}
+}
+
+func TestConfigValidate_Profiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfigFile(t, path, `blob: cred.bin
+key: ec256
+profile: qa
+onboard:
+  kex: ECDH256
+profiles:
+  staging:
+    onboard:
+      cipher: BAD
+  production:
+    blob: other.bin
+    device-init:
+      server-url: not-a-url`)
+
+	out, err := runCommand(t, "config", "validate", path)
+	if got := ExitCode(err); got != 1 {
+		t.Fatalf("exit code = %d, want 1\n%s", got, out)
+	}
+	for _, want := range []string{
+		"unknown key profiles.production.blob",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+
+	writeConfigFile(t, path, `blob: cred.bin
+key: ec256
+profile: qa
+onboard:
+  kex: ECDH256
+profiles:
+  staging:
+    onboard:
+      cipher: BAD
+  production:
+    device-init:
+      server-url: not-a-url`)
+	out, _ = runCommand(t, "config", "validate", path)
+	for _, want := range []string{
+		`profile "qa" not found`,
+		"profile production: device-init: invalid DI URL",
+		"profile staging: onboard: invalid cipher suite: BAD",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// fileConfig is the layout of a configuration file.
+type fileConfig struct {
+	FDOClientConfig `mapstructure:",squash"`
+	DeviceInit      DeviceInitConfig         `mapstructure:"device-init"`
+	Onboard         OnboardConfig            `mapstructure:"onboard"`
+	Profiles        map[string]ProfileConfig `mapstructure:"profiles"`
+}
+
+// configEnums lists the valid values of the configuration keys that take one
+// of a fixed set of values.
+func configEnums() map[string][]string {
+	return map[string][]string{
+		"key":                 validKeys,
+		"log-format":          validLogFormats,
+		"log-level":           validLogLevels,
+		"device-init.key-enc": validDiKeyEncs,
+		"onboard.kex":         validKexSuites,
+		"onboard.cipher":      validCipherSuites,
+
+		"device-init.tls-min-version":   validTLSVersions,
+		"device-init.tls-cipher-policy": validTLSCipherPolicies,
+		"onboard.tls-min-version":       validTLSVersions,
+		"onboard.tls-cipher-policy":     validTLSCipherPolicies,
+
+		"onboard.faults[].action": validFaultActions,
+	}
+}
+
+// configDescriptions describes the configuration keys without a flag.
+var configDescriptions = map[string]string{
+	"device-init.server-url": "DI server URL, overridden by the positional argument of device-init",
+	"onboard.faults":         "Faults injected into the TO1 and TO2 messages to test the retry behavior, never for production",
+}
+
+// configFlag returns the command line flag of a configuration key, or nil if
+// it has none.
+func configFlag(key string) *pflag.Flag {
+	section, name, nested := strings.Cut(key, ".")
+	switch {
+	case !nested:
+		return rootCmd.PersistentFlags().Lookup(key)
+	case section == "device-init":
+		return deviceInitCmd.Flags().Lookup(name)
+	case section == "onboard":
+		return onboardCmd.Flags().Lookup(name)
+	}
+	return nil
+}
+
+// mapstructureName returns the key of a struct field and whether the field is
+// squashed into its parent.
+func mapstructureName(field reflect.StructField) (string, bool) {
+	name, opts, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+	if opts == "squash" {
+		return "", true
+	}
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+	return name, false
+}
+
+// configKeyType returns the type of the value at a dotted configuration key
+// of t. Map values accept any key.
+func configKeyType(t reflect.Type, key string) (reflect.Type, bool) {
+	if key == "" {
+		return t, true
+	}
+	name, rest, _ := strings.Cut(key, ".")
+	switch t.Kind() {
+	case reflect.Map:
+		return configKeyType(t.Elem(), rest)
+	case reflect.Struct:
+		for i := range t.NumField() {
+			field := t.Field(i)
+			fieldName, squash := mapstructureName(field)
+			if squash {
+				if ft, ok := configKeyType(field.Type, key); ok {
+					return ft, true
+				}
+			} else if fieldName == name {
+				return configKeyType(field.Type, rest)
+			}
+		}
+	}
+	return nil, false
+}
+
+// unknownConfigKey returns the shortest prefix of a dotted key that is not a
+// configuration key of t, or "" if the key is known.
+func unknownConfigKey(t reflect.Type, key string) string {
+	parts := strings.Split(key, ".")
+	for i := range parts {
+		prefix := strings.Join(parts[:i+1], ".")
+		if _, ok := configKeyType(t, prefix); !ok {
+			return prefix
+		}
+	}
+	return ""
+}
+
+// unknownListKeys returns the keys of the entries of a list of structs at key,
+// such as onboard.faults[0].action, that are not fields of the struct.
+func unknownListKeys(t reflect.Type, key string, value any) []string {
+	kt, ok := configKeyType(t, key)
+	if !ok || kt.Kind() != reflect.Slice || kt.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	var entries []map[string]any
+	switch value := value.(type) {
+	case []map[string]any:
+		entries = value
+	case []any:
+		for _, entry := range value {
+			fields, _ := entry.(map[string]any)
+			entries = append(entries, fields)
+		}
+	}
+	var unknown []string
+	for i, fields := range entries {
+		for _, name := range slices.Sorted(maps.Keys(fields)) {
+			if _, ok := configKeyType(kt.Elem(), strings.ToLower(name)); !ok {
+				unknown = append(unknown, fmt.Sprintf("%s[%d].%s", key, i, name))
+			}
+		}
+	}
+	return unknown
+}
+
+// configLeafKeys returns the dotted keys of the values of t, not including
+// those below maps.
+func configLeafKeys(t reflect.Type, prefix string) []string {
+	var keys []string
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, squash := mapstructureName(field)
+		switch {
+		case squash:
+			keys = append(keys, configLeafKeys(field.Type, prefix)...)
+		case field.Type.Kind() == reflect.Struct:
+			keys = append(keys, configLeafKeys(field.Type, prefix+name+".")...)
+		case field.Type.Kind() != reflect.Map:
+			keys = append(keys, prefix+name)
+		}
+	}
+	return keys
+}
+
+// configSchema returns the JSON Schema of the configuration file.
+func configSchema() map[string]any {
+	schema := jsonSchema(reflect.TypeFor[fileConfig](), "")
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "go-fdo-client configuration"
+	return schema
+}
+
+// jsonSchema returns the JSON Schema of a configuration value of type t at
+// the dotted key.
+func jsonSchema(t reflect.Type, key string) map[string]any {
+	schema := map[string]any{}
+	switch {
+	case t == reflect.TypeFor[time.Duration]():
+		schema["type"] = "string"
+		schema["pattern"] = `^(0|([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+)$`
+	case t.Kind() == reflect.Struct:
+		properties := map[string]any{}
+		addStructProperties(properties, t, key)
+		schema["type"] = "object"
+		schema["properties"] = properties
+		schema["additionalProperties"] = false
+	case t.Kind() == reflect.Map:
+		schema["type"] = "object"
+		schema["additionalProperties"] = jsonSchema(t.Elem(), "")
+	case t.Kind() == reflect.Slice:
+		schema["type"] = "array"
+		schema["items"] = jsonSchema(t.Elem(), key+"[]")
+	case t.Kind() == reflect.String:
+		schema["type"] = "string"
+	case t.Kind() == reflect.Bool:
+		schema["type"] = "boolean"
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		schema["type"] = "integer"
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		schema["type"] = "number"
+	}
+
+	if values, ok := configEnums()[key]; ok {
+		schema["enum"] = values
+	}
+	if description, ok := configDescriptions[key]; ok {
+		schema["description"] = description
+	}
+	if flag := configFlag(key); key != "" && flag != nil {
+		schema["description"] = flag.Usage
+		if def, ok := schemaDefault(t, flag.DefValue); ok {
+			schema["default"] = def
+		}
+	}
+	return schema
+}
+
+func addStructProperties(properties map[string]any, t reflect.Type, key string) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, squash := mapstructureName(field)
+		if squash {
+			addStructProperties(properties, field.Type, key)
+			continue
+		}
+		fieldKey := name
+		if key != "" {
+			fieldKey = key + "." + name
+		}
+		properties[name] = jsonSchema(field.Type, fieldKey)
+	}
+}
+
+// schemaDefault converts the default value of a flag to the JSON type of t.
+// Empty and zero defaults are omitted.
+func schemaDefault(t reflect.Type, value string) (any, bool) {
+	switch {
+	case t == reflect.TypeFor[time.Duration]():
+		return value, value != "0s"
+	case t.Kind() == reflect.String:
+		return value, value != ""
+	case t.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		return b, err == nil && b
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		return n, err == nil && n != 0
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		return f, err == nil && f != 0
+	}
+	return nil, false
+}
@@ -38,6 +38,25 @@ const (
 	FDO_STATE_ERROR
 )
 
+func (s FdoDeviceState) String() string {
+	switch s {
+	case FDO_STATE_PC:
+		return "PC"
+	case FDO_STATE_PRE_DI:
+		return "PRE_DI"
+	case FDO_STATE_PRE_TO1:
+		return "PRE_TO1"
+	case FDO_STATE_IDLE:
+		return "IDLE"
+	case FDO_STATE_RESALE:
+		return "RESALE"
+	case FDO_STATE_ERROR:
+		return "ERROR"
+	default:
+		return fmt.Sprintf("UNKNOWN(%d)", int(s))
+	}
+}
+
 type fdoDeviceCredential struct {
 	DC    blob.DeviceCredential
 	State FdoDeviceState
@@ -60,19 +79,7 @@ func tpmCred() (hash.Hash, hash.Hash, crypto.Signer, func() error, error) {
 		_ = tpmc.Close()
 		return nil, nil, nil, nil, err
 	}
-	var key tpm.Key
-	switch rootConfig.Key {
-	case "ec256":
-		key, err = tpm.GenerateECKey(tpmc, elliptic.P256())
-	case "ec384":
-		key, err = tpm.GenerateECKey(tpmc, elliptic.P384())
-	case "rsa2048":
-		key, err = tpm.GenerateRSAKey(tpmc, 2048)
-	case "rsa3072":
-		key, err = tpm.GenerateRSAKey(tpmc, 3072)
-	default:
-		err = fmt.Errorf("unsupported key type: %s", rootConfig.Key)
-	}
+	key, err := tpmDeviceKey()
 	if err != nil {
 		_ = tpmc.Close()
 		return nil, nil, nil, nil, err
@@ -86,6 +93,22 @@ func tpmCred() (hash.Hash, hash.Hash, crypto.Signer, func() error, error) {
 	}, nil
 }
 
+// tpmDeviceKey returns the device key of the --key type in the TPM. The key
+// is derived from the TPM seed, so it is the same key every time.
+func tpmDeviceKey() (tpm.Key, error) {
+	switch rootConfig.Key {
+	case "ec256":
+		return tpm.GenerateECKey(tpmc, elliptic.P256())
+	case "ec384":
+		return tpm.GenerateECKey(tpmc, elliptic.P384())
+	case "rsa2048":
+		return tpm.GenerateRSAKey(tpmc, 2048)
+	case "rsa3072":
+		return tpm.GenerateRSAKey(tpmc, 3072)
+	}
+	return nil, fmt.Errorf("unsupported key type: %s", rootConfig.Key)
+}
+
 func readCred() (_ *fdo.DeviceCredential, hmacSha256, hmacSha384 hash.Hash, key crypto.Signer, cleanup func() error, _ error) {
 	if rootConfig.TPM != "" {
 		// DeviceCredential requires integrity, so it is stored as a file and
@@ -149,13 +172,36 @@ func loadDeviceStatus() (FdoDeviceState, error) {
 	return FDO_STATE_PRE_DI, nil
 }
 
+// readStoredCred reads the public part and state of the stored device
+// credential from the configured backend, without opening any device keys.
+func readStoredCred() (fdo.DeviceCredential, FdoDeviceState, error) {
+	if rootConfig.TPM != "" {
+		var dc fdoTpmDeviceCredential
+		if err := readTpmCred(&dc); err != nil {
+			return fdo.DeviceCredential{}, FDO_STATE_PC, err
+		}
+		return dc.DC.DeviceCredential, dc.State, nil
+	}
+
+	var dc fdoDeviceCredential
+	if err := readCredFile(&dc); err != nil {
+		return fdo.DeviceCredential{}, FDO_STATE_PC, err
+	}
+	return dc.DC.DeviceCredential, dc.State, nil
+}
+
 func readCredFile(v any) error {
-	blobData, err := os.ReadFile(filepath.Clean(rootConfig.Blob))
+	return readCredFileFrom(rootConfig.Blob, v)
+}
+
+// readCredFileFrom decodes a CBOR encoded credential file at path into v.
+func readCredFileFrom(path string, v any) error {
+	blobData, err := os.ReadFile(filepath.Clean(path))
 	if err != nil {
-		return fmt.Errorf("error reading blob credential %q: %w", rootConfig.Blob, err)
+		return fmt.Errorf("error reading blob credential %q: %w", path, err)
 	}
 	if err := cbor.Unmarshal(blobData, v); err != nil {
-		return fmt.Errorf("error parsing blob credential %q: %w", rootConfig.Blob, err)
+		return fmt.Errorf("error parsing blob credential %q: %w", path, err)
 	}
 	return nil
 }
@@ -181,8 +227,13 @@ func updateCred(newDC fdo.DeviceCredential, state FdoDeviceState) error {
 }
 
 func saveCred(dc any) error {
+	return saveCredTo(rootConfig.Blob, dc)
+}
+
+// saveCredTo atomically writes the CBOR encoded credential to path.
+func saveCredTo(path string, dc any) error {
 	// Encode device credential to temp file
-	tmpbase := filepath.Dir(rootConfig.Blob)
+	tmpbase := filepath.Dir(path)
 	tmp, err := os.CreateTemp(tmpbase, "fdo_cred_*")
 	if err != nil {
 		return fmt.Errorf("error creating temp file for device credential: %w", err)
@@ -199,8 +250,8 @@ func saveCred(dc any) error {
 	}
 
 	// Move temp file to given blob path (supports cross-filesystem moves)
-	if err := moveFile(tmp.Name(), rootConfig.Blob); err != nil {
-		return fmt.Errorf("error moving temp blob credential to %q: %w", rootConfig.Blob, err)
+	if err := moveFile(tmp.Name(), path); err != nil {
+		return fmt.Errorf("error moving temp blob credential to %q: %w", path, err)
 	}
 
 	return nil
@@ -0,0 +1,120 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+package cmd
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/fido-device-onboard/go-fdo"
+	"github.com/fido-device-onboard/go-fdo/blob"
+	"github.com/fido-device-onboard/go-fdo/cbor"
+	"github.com/fido-device-onboard/go-fdo/protocol"
+)
+
+// testRvInstruction CBOR encodes an RV variable value for test credentials.
+func testRvInstruction(t *testing.T, v protocol.RvVar, val any) protocol.RvInstruction {
+	t.Helper()
+	data, err := cbor.Marshal(val)
+	if err != nil {
+		t.Fatalf("encoding RV variable %d: %v", v, err)
+	}
+	return protocol.RvInstruction{Variable: v, Value: data}
+}
+
+// newTestBlobCred builds a blob device credential with an HTTP rendezvous
+// directive pointing at 127.0.0.1:8041.
+func newTestBlobCred(t *testing.T, state FdoDeviceState) fdoDeviceCredential {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		t.Fatal(err)
+	}
+	var guid protocol.GUID
+	if _, err := rand.Read(guid[:]); err != nil {
+		t.Fatal(err)
+	}
+	pkHash := sha256.Sum256([]byte("manufacturer key"))
+
+	return fdoDeviceCredential{
+		DC: blob.DeviceCredential{
+			Active: true,
+			DeviceCredential: fdo.DeviceCredential{
+				Version:    101,
+				DeviceInfo: "test-device",
+				GUID:       guid,
+				RvInfo: [][]protocol.RvInstruction{{
+					testRvInstruction(t, protocol.RVProtocol, protocol.RVProtHTTP),
+					testRvInstruction(t, protocol.RVIPAddress, net.IPv4(127, 0, 0, 1)),
+					testRvInstruction(t, protocol.RVDevPort, uint16(8041)),
+				}},
+				PublicKeyHash: protocol.Hash{Algorithm: protocol.Sha256Hash, Value: pkHash[:]},
+			},
+			HmacSecret: secret,
+			PrivateKey: blob.Pkcs8Key{Signer: key},
+		},
+		State: state,
+	}
+}
+
+// writeTestBlobCred stores dc in a temporary blob file and points the root
+// configuration at it.
+func writeTestBlobCred(t *testing.T, dc fdoDeviceCredential) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "cred.bin")
+	rootConfig = FDOClientConfig{Blob: path, Key: "ec256"}
+	if err := saveCred(dc); err != nil {
+		t.Fatalf("saving test credential: %v", err)
+	}
+	return path
+}
+
+func TestFdoDeviceStateString(t *testing.T) {
+	tests := []struct {
+		state FdoDeviceState
+		want  string
+	}{
+		{FDO_STATE_PRE_DI, "PRE_DI"},
+		{FDO_STATE_PRE_TO1, "PRE_TO1"},
+		{FDO_STATE_IDLE, "IDLE"},
+		{FDO_STATE_RESALE, "RESALE"},
+		{FDO_STATE_ERROR, "ERROR"},
+		{FdoDeviceState(42), "UNKNOWN(42)"},
+	}
+	for _, tt := range tests {
+		if got := tt.state.String(); got != tt.want {
+			t.Errorf("FdoDeviceState(%d).String() = %q, want %q", int(tt.state), got, tt.want)
+		}
+	}
+}
+
+func TestBlobCredRoundTrip(t *testing.T) {
+	want := newTestBlobCred(t, FDO_STATE_PRE_TO1)
+	writeTestBlobCred(t, want)
+
+	state, err := loadDeviceStatus()
+	if err != nil {
+		t.Fatalf("loadDeviceStatus: %v", err)
+	}
+	if state != FDO_STATE_PRE_TO1 {
+		t.Errorf("state = %v, want %v", state, FDO_STATE_PRE_TO1)
+	}
+
+	var got fdoDeviceCredential
+	if err := readCredFile(&got); err != nil {
+		t.Fatalf("readCredFile: %v", err)
+	}
+	if got.DC.GUID != want.DC.GUID {
+		t.Errorf("GUID = %x, want %x", got.DC.GUID, want.DC.GUID)
+	}
+}
@@ -0,0 +1,281 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fido-device-onboard/go-fdo-client/internal/sdnotify"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run FDO onboarding as a long-running systemd service",
+	Long: `
+Run FDO TO1 and TO2 onboarding as a long-running service and exit once the
+device has been onboarded (IDLE). The daemon accepts the same options as
+onboard, read from the onboard section of the configuration file.
+
+The daemon is meant to run as a systemd service of Type=notify. When
+$NOTIFY_SOCKET is set it reports READY=1 after startup, a STATUS= line with
+the current phase and rendezvous directive, RELOADING=1 while reloading and
+STOPPING=1 before it exits. With WatchdogSec= set, WATCHDOG=1 is sent at half
+the watchdog interval while onboarding makes progress: while it waits between
+attempts, and once after it starts a TO1 or TO2 exchange. An exchange that
+hangs for longer than WatchdogSec= makes systemd restart the service, so set
+it above the longest expected TO2 exchange.
+
+  SIGHUP   reload the configuration files once the current attempt ends, or
+           right away while waiting between attempts, and continue
+           onboarding; an invalid configuration is logged and the previous
+           one is kept
+  SIGTERM  stop onboarding and exit with status 0
+
+The onboarding progress is persisted (see --progress-file), so onboarding
+resumes with the next rendezvous directive after a reload or restart. The
+exit codes of bounded runs are the same as for onboard.`,
+	Example: `
+  # Run onboarding as a service using the configuration file:
+  go-fdo-client daemon --config /etc/go-fdo-client/config.yaml
+
+  # Minimal systemd unit:
+  #   [Service]
+  #   Type=notify
+  #   ExecStart=/usr/bin/go-fdo-client daemon --config /etc/go-fdo-client/config.yaml
+  #   ExecReload=/bin/kill -HUP $MAINPID
+  #   WatchdogSec=60`,
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadOnboardConfig(cmd)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		notifier, err := sdnotify.New()
+		if err != nil {
+			slog.Warn("Service manager notifications disabled", "error", err)
+		}
+		defer func() { _ = notifier.Close() }()
+
+		return onboardExitError(runDaemon(cmd.Context(), notifier))
+	},
+}
+
+func daemonCmdInit() {
+	rootCmd.AddCommand(daemonCmd)
+	addOnboardFlags(daemonCmd)
+}
+
+func init() {
+	daemonCmdInit()
+}
+
+// runDaemon onboards the device until it is IDLE. Onboarding restarts with a
+// reloaded configuration on SIGHUP and stops on SIGTERM or when ctx is done.
+func runDaemon(ctx context.Context, notifier *sdnotify.Notifier) error {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGHUP, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	notify := func(states ...string) {
+		if err := notifier.Notify(states...); err != nil {
+			slog.Warn("Failed to notify service manager", "error", err)
+		}
+	}
+	var loop retryLoopMonitor
+	hooks := onboardHooks{
+		status: func(s string) {
+			loop.progressed()
+			notify(sdnotify.Status(s))
+		},
+		idle: loop.setIdle,
+	}
+
+	if err := startWatchdog(ctx, notifier, &loop); err != nil {
+		return err
+	}
+	notify(sdnotify.Ready, sdnotify.Status("Checking device state"))
+
+	for {
+		attemptCtx, cancelAttempt := context.WithCancel(ctx)
+		loop.start(cancelAttempt)
+		done := make(chan error, 1)
+		go func() { done <- onboardDevice(attemptCtx, hooks) }()
+
+		var err error
+		var sig os.Signal
+		reload := false
+	wait:
+		for {
+			select {
+			case err = <-done:
+				break wait
+			case s := <-sigs:
+				if s == syscall.SIGHUP {
+					if !reload {
+						slog.Info("Reloading configuration once the current attempt ends")
+					}
+					reload = true
+					loop.requestReload()
+					continue
+				}
+				sig = s
+				cancelAttempt()
+				err = <-done
+				break wait
+			}
+		}
+		stopped := attemptCtx.Err() != nil
+		cancelAttempt()
+
+		switch {
+		case sig != nil || ctx.Err() != nil:
+			slog.Info("Stopping onboarding")
+			notify(sdnotify.Stopping, sdnotify.Status("Stopped before onboarding completed"))
+			return nil
+		case reload && stopped:
+			slog.Info("Reloading configuration")
+			notify(sdnotify.Reloading, sdnotify.Status("Reloading configuration"))
+			if err := reloadConfig(); err != nil {
+				slog.Error("Keeping previous configuration", "error", err)
+			}
+			notify(sdnotify.Ready)
+		case err != nil:
+			notify(sdnotify.Stopping, sdnotify.Status("Onboarding failed: "+err.Error()))
+			return err
+		default:
+			notify(sdnotify.Stopping, sdnotify.Status("Device onboarded (IDLE)"))
+			return nil
+		}
+	}
+}
+
+// retryLoopMonitor follows the retry loop of the daemon. It stops onboarding
+// for a reload only while the loop is idle, so that a reload never
+// interrupts a TO1 or TO2 exchange, and it lets the watchdog know whether the
+// loop makes progress.
+type retryLoopMonitor struct {
+	mu       sync.Mutex
+	cancel   context.CancelFunc // stops the current onboarding run
+	idle     bool               // the loop is between attempts or waits
+	reload   bool               // a reload waits for the loop to be idle
+	progress bool               // progress was reported since the last keep-alive
+}
+
+// start resets the monitor for an onboarding run stopped by cancel.
+func (m *retryLoopMonitor) start(cancel context.CancelFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cancel, m.idle, m.reload, m.progress = cancel, false, false, true
+}
+
+// requestReload stops the onboarding run now if the loop is idle, or else
+// once it becomes idle.
+func (m *retryLoopMonitor) requestReload() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reload = true
+	if m.idle {
+		m.cancel()
+	}
+}
+
+// setIdle is the idle hook of the retry loop.
+func (m *retryLoopMonitor) setIdle(idle bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.idle, m.progress = idle, true
+	if idle && m.reload {
+		m.cancel()
+	}
+}
+
+// progressed records progress of the loop, such as a new phase.
+func (m *retryLoopMonitor) progressed() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.progress = true
+}
+
+// alive reports whether the loop made progress since the last call or is
+// idle, and so is not stuck in an exchange.
+func (m *retryLoopMonitor) alive() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	alive := m.idle || m.progress
+	m.progress = false
+	return alive
+}
+
+// startWatchdog sends keep-alive notifications at half the watchdog interval
+// configured by systemd until ctx is done, as long as the retry loop is
+// alive. A TO1 or TO2 exchange that hangs for longer than the interval makes
+// systemd restart the service.
+func startWatchdog(ctx context.Context, notifier *sdnotify.Notifier, loop *retryLoopMonitor) error {
+	interval, err := sdnotify.WatchdogInterval()
+	if err != nil || interval == 0 || notifier == nil {
+		return err
+	}
+	slog.Debug("Service manager watchdog enabled", "interval", interval)
+	go func() {
+		ticker := time.NewTicker(interval / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if !loop.alive() {
+					slog.Debug("Skipping watchdog keep-alive, no onboarding progress")
+					continue
+				}
+				if err := notifier.Notify(sdnotify.Watchdog); err != nil {
+					slog.Warn("Failed to notify service manager", "error", err)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// reloadConfig re-reads the configuration files and replaces the global and
+// onboard configuration if the new one is valid. Command line flags keep
+// precedence over the file.
+func reloadConfig() error {
+	if err := loadConfig(); err != nil {
+		return err
+	}
+
+	var root FDOClientConfig
+	if err := viper.Unmarshal(&root); err != nil {
+		return err
+	}
+	if err := root.validate(); err != nil {
+		return err
+	}
+	var onboard OnboardClientConfig
+	if err := viper.Unmarshal(&onboard); err != nil {
+		return fmt.Errorf("failed to unmarshal onboard config: %w", err)
+	}
+	if err := onboard.validate(); err != nil {
+		return err
+	}
+	// Reopens the log file, so that it can be rotated externally as well
+	if err := configureLogging(root); err != nil {
+		return err
+	}
+
+	rootConfig, onboardConfig = root, onboard
+	return nil
+}
@@ -0,0 +1,242 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+//go:build unix
+
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/fido-device-onboard/go-fdo/protocol"
+)
+
+// listenNotifySocket points NOTIFY_SOCKET at a local datagram socket and
+// returns a function that waits for the next notification containing want.
+func listenNotifySocket(t *testing.T) func(want string) string {
+	t.Helper()
+	// Socket paths are limited to about 100 bytes, t.TempDir may be longer
+	dir, err := os.MkdirTemp("", "notify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	addr := &net.UnixAddr{Name: filepath.Join(dir, "notify.sock"), Net: "unixgram"}
+	conn, err := net.ListenUnixgram("unixgram", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", addr.Name)
+
+	return func(want string) string {
+		t.Helper()
+		buf := make([]byte, 4096)
+		for {
+			_ = conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+			n, err := conn.Read(buf)
+			if err != nil {
+				t.Fatalf("no notification containing %q: %v", want, err)
+			}
+			if msg := string(buf[:n]); strings.Contains(msg, want) {
+				return msg
+			}
+		}
+	}
+}
+
+// startDaemon runs the daemon command in the background and returns a
+// channel receiving its result.
+func startDaemon(t *testing.T, args ...string) <-chan error {
+	t.Helper()
+	resetState(t)
+	t.Cleanup(func() { onboardConfig = OnboardClientConfig{} })
+	rootCmd.SetArgs(append([]string{"daemon"}, args...))
+	result := make(chan error, 1)
+	go func() { result <- rootCmd.Execute() }()
+	return result
+}
+
+func waitDaemon(t *testing.T, result <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(30 * time.Second):
+		t.Fatal("daemon did not exit")
+		return nil
+	}
+}
+
+func writeDaemonConfig(t *testing.T, path, blob string, maxAttempts int) {
+	t.Helper()
+	config := "blob: " + blob + "\nkey: ec256\nonboard:\n  kex: ECDH256\n"
+	if maxAttempts > 0 {
+		config += fmt.Sprintf("  max-attempts: %d\n", maxAttempts)
+	}
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDaemon_ExitsWhenIdle(t *testing.T) {
+	next := listenNotifySocket(t)
+	path := writeTestBlobCred(t, newTestBlobCred(t, FDO_STATE_IDLE))
+
+	result := startDaemon(t, "--blob", path, "--key", "ec256", "--kex", "ECDH256")
+	if err := waitDaemon(t, result); err != nil {
+		t.Fatalf("daemon failed: %v", err)
+	}
+	next("READY=1")
+	if msg := next("STOPPING=1"); !strings.Contains(msg, "STATUS=Device onboarded (IDLE)") {
+		t.Errorf("stop notification = %q, want IDLE status", msg)
+	}
+}
+
+// TestDaemon_ReloadOnSIGHUP checks that the daemon reports its phase and
+// directive, and that SIGHUP interrupts the retry delay and applies the
+// reloaded configuration.
+func TestDaemon_ReloadOnSIGHUP(t *testing.T) {
+	next := listenNotifySocket(t)
+	dc := newTestBlobCred(t, FDO_STATE_PRE_TO1)
+	dc.DC.RvInfo = unreachableRvInfo(t, 1, 3600)
+	blob := writeTestBlobCred(t, dc)
+	config := filepath.Join(t.TempDir(), "config.yaml")
+	writeDaemonConfig(t, config, blob, 0)
+
+	result := startDaemon(t, "--config", config)
+	next("READY=1")
+	next("STATUS=TO1 with directive 0 (attempt 1)")
+	next("STATUS=Waiting")
+
+	// Bound the reloaded run to a single attempt so that the daemon exits
+	writeDaemonConfig(t, config, blob, 1)
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	next("RELOADING=1")
+	next("READY=1")
+	next("STATUS=TO1 with directive 0 (attempt 2)")
+	next("STOPPING=1")
+
+	if got := ExitCode(waitDaemon(t, result)); got != onboardExitMaxAttempts {
+		t.Errorf("exit code = %d, want %d", got, onboardExitMaxAttempts)
+	}
+}
+
+// TestDaemon_ReloadWaitsForAttempt checks that SIGHUP does not interrupt a
+// TO1 exchange and that the reload is applied once the attempt ends.
+func TestDaemon_ReloadWaitsForAttempt(t *testing.T) {
+	next := listenNotifySocket(t)
+	arrived, release := make(chan struct{}), make(chan struct{})
+	var interrupted atomic.Bool
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			close(arrived)
+			select {
+			case <-release:
+			case <-r.Context().Done():
+				interrupted.Store(true)
+			}
+		}
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	dc := newTestBlobCred(t, FDO_STATE_PRE_TO1)
+	dc.DC.RvInfo = [][]protocol.RvInstruction{
+		testRvDirective(t, srv.URL, protocol.RVProtHTTP, testRvInstruction(t, protocol.RVDelaysec, uint32(3600))),
+	}
+	blob := writeTestBlobCred(t, dc)
+	config := filepath.Join(t.TempDir(), "config.yaml")
+	writeDaemonConfig(t, config, blob, 0)
+
+	result := startDaemon(t, "--config", config)
+	next("READY=1")
+	<-arrived
+	writeDaemonConfig(t, config, blob, 1)
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if interrupted.Load() {
+		t.Fatal("SIGHUP interrupted the TO1 exchange")
+	}
+	close(release)
+	next("RELOADING=1")
+	next("STATUS=TO1 with directive 0 (attempt 2)")
+	next("STOPPING=1")
+
+	if got := ExitCode(waitDaemon(t, result)); got != onboardExitMaxAttempts {
+		t.Errorf("exit code = %d, want %d", got, onboardExitMaxAttempts)
+	}
+	if interrupted.Load() {
+		t.Error("SIGHUP interrupted the TO1 exchange")
+	}
+}
+
+func TestRetryLoopMonitor(t *testing.T) {
+	canceled := false
+	var m retryLoopMonitor
+	m.start(func() { canceled = true })
+
+	if !m.alive() {
+		t.Error("not alive after the start of a run")
+	}
+	if m.alive() {
+		t.Error("alive without progress during an exchange")
+	}
+	m.progressed()
+	if !m.alive() {
+		t.Error("not alive after a status report")
+	}
+
+	m.requestReload()
+	if canceled {
+		t.Fatal("reload stopped an exchange")
+	}
+	m.setIdle(true)
+	if !canceled {
+		t.Fatal("reload not applied between attempts")
+	}
+	if !m.alive() || !m.alive() {
+		t.Error("not alive while waiting between attempts")
+	}
+
+	canceled = false
+	m.start(func() { canceled = true })
+	m.setIdle(true)
+	m.requestReload()
+	if !canceled {
+		t.Error("reload not applied while waiting")
+	}
+}
+
+func TestDaemon_StopOnSIGTERM(t *testing.T) {
+	next := listenNotifySocket(t)
+	dc := newTestBlobCred(t, FDO_STATE_PRE_TO1)
+	dc.DC.RvInfo = unreachableRvInfo(t, 1, 3600)
+	path := writeTestBlobCred(t, dc)
+
+	result := startDaemon(t, "--blob", path, "--key", "ec256", "--kex", "ECDH256")
+	next("READY=1")
+	next("STATUS=Waiting")
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	next("STOPPING=1")
+	if err := waitDaemon(t, result); err != nil {
+		t.Errorf("daemon stopped with error: %v", err)
+	}
+}
@@ -23,7 +23,6 @@ import (
 	"strings"
 
 	"github.com/fido-device-onboard/go-fdo"
-	"github.com/fido-device-onboard/go-fdo-client/internal/tls"
 	"github.com/fido-device-onboard/go-fdo-client/internal/tpm_utils"
 	"github.com/fido-device-onboard/go-fdo/blob"
 	"github.com/fido-device-onboard/go-fdo/cbor"
@@ -60,6 +59,9 @@ At least one of --blob or --tpm is required to store device credentials.`,
 		if err != nil {
 			return err
 		}
+		if err := checkProxyFlag(cmd); err != nil {
+			return err
+		}
 
 		if len(args) > 0 {
 			viper.Set("device-init.server-url", args[0])
@@ -72,11 +74,10 @@ At least one of --blob or --tpm is required to store device credentials.`,
 		return diConf.validate()
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
-
-		if rootConfig.Debug {
-			level.Set(slog.LevelDebug)
+		// A replayed DI writes a credential with a fresh device key
+		if diConf.DeviceInit.Replay != "" && fileExists(diConf.Blob) {
+			return fmt.Errorf("--replay requires a --blob that does not exist yet, the replayed credential would overwrite %s", diConf.Blob)
 		}
-
 		if rootConfig.TPM != "" {
 			var err error
 			tpmc, err = tpm_utils.TpmOpen(rootConfig.TPM)
@@ -112,6 +113,12 @@ func deviceInitCmdInit() {
 	deviceInitCmd.Flags().String("device-info-mac", "", "Mac-address's iface e.g. eth0 for device credentials")
 	deviceInitCmd.Flags().Bool("insecure-tls", false, "Skip TLS certificate verification")
 	deviceInitCmd.Flags().String("serial-number", "", "Serial number for device credentials, if not specified, it'll be gathered from the system")
+	deviceInitCmd.Flags().String("tls-ca-file", "", "PEM bundle of the CA certificates trusted for the DI server instead of the system roots")
+	deviceInitCmd.Flags().StringSlice("tls-pin-spki", nil, "Base64 SHA-256 hash of a public key of which one must be in the DI server's certificate chain (repeatable)")
+	deviceInitCmd.Flags().String("tls-server-name", "", "Name the DI server certificate is verified against instead of the URL host")
+	deviceInitCmd.Flags().String("tls-client-cert", "", "PEM certificate chain presented to a DI server or proxy requesting a client certificate")
+	deviceInitCmd.Flags().String("tls-client-key", "", "PEM private key of --tls-client-cert, or \"tpm\" for the device key in the TPM")
+	addTransportFlags(deviceInitCmd)
 }
 
 func init() {
@@ -216,7 +223,11 @@ func doDI() (err error) { //nolint:gocyclo
 	}
 	slog.Debug("Starting Device Initialization", "Serial Number", diConf.DeviceInit.SerialNumber, "Device Info", deviceInfo)
 
-	cred, err := fdo.DI(context.TODO(), tls.TlsTransport(diConf.DeviceInit.ServerURL, nil, diConf.DeviceInit.InsecureTLS), custom.DeviceMfgInfo{
+	transport, err := diTransport(key)
+	if err != nil {
+		return err
+	}
+	cred, err := fdo.DI(context.TODO(), transport, custom.DeviceMfgInfo{
 		KeyType:      keyType,
 		KeyEncoding:  keyEncoding,
 		SerialNumber: diConf.DeviceInit.SerialNumber,
@@ -227,6 +238,7 @@ func doDI() (err error) { //nolint:gocyclo
 		HmacSha384: hmacSha384,
 		Key:        key,
 	})
+	messageSession.warnRecordErr()
 	if err != nil {
 		return err
 	}
@@ -295,6 +307,19 @@ func (d *DeviceInitClientConfig) validate() error {
 		return fmt.Errorf("invalid DI key encoding: %s", d.DeviceInit.KeyEnc)
 	}
 
+	if err := validateTLSOptions(d.DeviceInit.tlsOptions()); err != nil {
+		return err
+	}
+	if err := validateClientCert(d.DeviceInit.TLSClientCert, d.DeviceInit.TLSClientKey, d.TPM != ""); err != nil {
+		return err
+	}
+	if err := d.DeviceInit.TransportConfig.validate(); err != nil {
+		return err
+	}
+	if d.DeviceInit.Replay != "" && d.TPM != "" {
+		return fmt.Errorf("--replay cannot be used with --tpm, the replayed credential would replace the one in the TPM: replay into a scratch --blob instead")
+	}
+
 	return nil
 }
 
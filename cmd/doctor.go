@@ -0,0 +1,555 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+package cmd
+
+import (
+	"context"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	fdotls "github.com/fido-device-onboard/go-fdo-client/internal/tls"
+	"github.com/fido-device-onboard/go-fdo-client/internal/tpm_utils"
+	"github.com/fido-device-onboard/go-fdo/protocol"
+	"github.com/google/go-tpm/tpm2"
+	"github.com/spf13/cobra"
+)
+
+// Exit code returned by the doctor command when a check failed.
+const doctorExitFailed = 40
+
+// Result of a doctor check that does not block onboarding but deserves a look.
+const doctorWarn = "warn"
+
+var validDoctorOutputs = []string{"text", "json"}
+
+// minimumClockTime is a time the system clock of a device running this client
+// cannot be before.
+var minimumClockTime = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// doctorNow returns the system time checked by doctor.
+var doctorNow = time.Now
+
+// kexKeyTypes maps each key exchange suite to the device key type of matching
+// strength.
+var kexKeyTypes = map[string]string{
+	"ECDH256":     "ec256",
+	"ECDH384":     "ec384",
+	"DHKEXid14":   "rsa2048",
+	"ASYMKEX2048": "rsa2048",
+	"DHKEXid15":   "rsa3072",
+	"ASYMKEX3072": "rsa3072",
+}
+
+// doctorReport is the itemized result of the doctor checks.
+type doctorReport struct {
+	Passed bool          `json:"passed"`
+	Checks []verifyCheck `json:"checks"`
+}
+
+func (r *doctorReport) add(name, result, detail string) {
+	r.Checks = append(r.Checks, verifyCheck{Name: name, Result: result, Detail: detail})
+}
+
+func (r *doctorReport) check(name string, err error, detail string) {
+	if err != nil {
+		r.add(name, verifyFail, err.Error())
+		return
+	}
+	r.add(name, verifyPass, detail)
+}
+
+func (r *doctorReport) failed() int {
+	n := 0
+	for _, check := range r.Checks {
+		if check.Result == verifyFail {
+			n++
+		}
+	}
+	return n
+}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the device for common causes of onboarding failures",
+	Long: `
+Run a series of checks to find common causes of onboarding failures before
+escalating them, and print a pass/fail report. Onboarding is not attempted and
+the device credential is not modified.
+
+  config       the --key, --kex and --cipher options are valid
+  crypto       the key exchange, cipher and device key are of matching
+               strength and --key matches a blob device key
+  tpm          the TPM can be opened (--tpm only)
+  tpm-nv       the TPM NV index holding the device credential exists
+  credential   the device credential can be read and decoded
+  working-dir  the default working directory is writable
+  tls          the TO1 and TO2 CA bundles can be read and the SPKI pins are
+               valid
+  proxy        the --proxy option or the proxy environment variables are
+               valid, and which rendezvous URLs use them
+  dns          each rendezvous host name resolves
+  connect      each rendezvous URL accepts TCP connections and TLS handshakes
+               verified with the TO1 TLS options
+  clock        the system time is plausible and within the validity of the
+               certificates of the TLS rendezvous servers
+
+Warnings do not fail the report. With --offline the dns and connect checks
+are skipped and the clock is not compared to server certificates.
+
+The exit code is 0 when no check failed and 40 otherwise.`,
+	Example: `
+  # Check a device using the onboarding configuration:
+  go-fdo-client doctor --config /etc/go-fdo-client/config.yaml
+
+  # Check without network access and print the report as JSON:
+  go-fdo-client doctor --tpm /dev/tpmrm0 --key ec256 --kex ECDH256 --offline --output json`,
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		output, err := cmd.Flags().GetString("output")
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(validDoctorOutputs, output) {
+			return fmt.Errorf("invalid output format: '%s', options [%s]", output, strings.Join(validDoctorOutputs, ", "))
+		}
+
+		// Invalid options are reported by the config check
+		return readOnboardConfig(cmd)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		output, err := cmd.Flags().GetString("output")
+		if err != nil {
+			return err
+		}
+		offline, err := cmd.Flags().GetBool("offline")
+		if err != nil {
+			return err
+		}
+		timeout, err := cmd.Flags().GetDuration("probe-timeout")
+		if err != nil {
+			return err
+		}
+		if timeout <= 0 {
+			return fmt.Errorf("probe-timeout must be positive")
+		}
+
+		report := runDoctor(cmd.Context(), offline, timeout)
+		if err := writeDoctorReport(cmd.OutOrStdout(), report, output); err != nil {
+			return err
+		}
+
+		// The report has already been printed
+		if !report.Passed {
+			cmd.SilenceErrors = true
+			return &exitCodeError{code: doctorExitFailed}
+		}
+		return nil
+	},
+}
+
+func doctorCmdInit() {
+	rootCmd.AddCommand(doctorCmd)
+	addOnboardFlags(doctorCmd)
+	doctorCmd.Flags().String("output", "text", "Output format [options: text, json]")
+	doctorCmd.Flags().Bool("offline", false, "Skip the checks that need network access")
+	doctorCmd.Flags().Duration("probe-timeout", 5*time.Second, "Timeout for each network check")
+}
+
+func init() {
+	doctorCmdInit()
+}
+
+// runDoctor runs all checks in order. Checks that depend on a failed one are
+// skipped.
+func runDoctor(ctx context.Context, offline bool, timeout time.Duration) *doctorReport {
+	report := &doctorReport{Checks: []verifyCheck{}}
+
+	configErr := onboardConfig.validateOptions()
+	report.check("config", configErr, fmt.Sprintf("key %s, kex %s, cipher %s",
+		rootConfig.Key, onboardConfig.Onboard.Kex, onboardConfig.Onboard.Cipher))
+
+	credOK, tpmOpen := true, false
+	if rootConfig.TPM != "" {
+		var err error
+		tpmc, err = tpm_utils.TpmOpen(rootConfig.TPM)
+		report.check("tpm", err, rootConfig.TPM)
+		if err == nil {
+			tpmOpen = true
+			defer tpmc.Close()
+			if tpm_utils.TpmNVGetSize(tpmc, tpm2.TPMHandle(FDO_CRED_NV_IDX)) == 0 {
+				report.add("tpm-nv", verifyFail, fmt.Sprintf("NV index 0x%08X is not defined, run device-init", FDO_CRED_NV_IDX))
+				credOK = false
+			} else {
+				report.add("tpm-nv", verifyPass, fmt.Sprintf("NV index 0x%08X", FDO_CRED_NV_IDX))
+			}
+		} else {
+			report.add("tpm-nv", verifySkip, "TPM could not be opened")
+			credOK = false
+		}
+	}
+
+	var rvURLs []*url.URL
+	if credOK {
+		rvURLs = doctorCredential(report, configErr == nil)
+	} else {
+		report.add("crypto", verifySkip, "no device credential")
+		report.add("credential", verifySkip, "TPM NV index is not usable")
+	}
+
+	workingDir := onboardConfig.Onboard.DefaultWorkingDir
+	if workingDir == "" {
+		workingDir, _ = os.Getwd()
+	}
+	report.check("working-dir", validateWorkingDir(workingDir), workingDir)
+
+	// The client certificate of the TPM device key is used by the probes
+	var deviceKey crypto.Signer
+	if onboardConfig.Onboard.TLSClientKey == tpmClientKey && tpmOpen {
+		if key, err := tpmDeviceKey(); err == nil {
+			defer func() { _ = key.Close() }()
+			deviceKey = key
+		}
+	}
+	report.check("tls", loadOnboardTransport(deviceKey), fmt.Sprintf("TO1 %s; TO2 %s",
+		describeTLSOptions(onboardConfig.Onboard.to1TLSOptions()),
+		describeTLSOptions(onboardConfig.Onboard.to2TLSOptions())))
+
+	proxied := doctorProxy(report, rvURLs)
+
+	var certs []doctorCert
+	switch {
+	case offline:
+		report.add("dns", verifySkip, "--offline")
+		report.add("connect", verifySkip, "--offline")
+	case len(rvURLs) == 0:
+		report.add("dns", verifySkip, "no rendezvous URLs")
+		report.add("connect", verifySkip, "no rendezvous URLs")
+	default:
+		certs = doctorNetwork(ctx, report, rvURLs, proxied, timeout)
+	}
+
+	doctorClock(report, certs)
+
+	report.Passed = report.failed() == 0
+	return report
+}
+
+// doctorCredential checks the stored credential and the compatibility of the
+// configured crypto options with it. It returns the rendezvous URLs.
+func doctorCredential(report *doctorReport, configOK bool) []*url.URL {
+	state, err := loadDeviceStatus()
+	if err == nil && state == FDO_STATE_PRE_DI {
+		err = errors.New("no device credential found, run device-init")
+	}
+	if err != nil {
+		report.add("crypto", verifySkip, "no device credential")
+		report.add("credential", verifyFail, err.Error())
+		return nil
+	}
+	dc, _, err := readStoredCred()
+	if err != nil {
+		report.add("crypto", verifySkip, "no device credential")
+		report.add("credential", verifyFail, err.Error())
+		return nil
+	}
+
+	if configOK {
+		result, detail := doctorCrypto()
+		report.add("crypto", result, detail)
+	} else {
+		report.add("crypto", verifySkip, "invalid configuration")
+	}
+
+	detail := fmt.Sprintf("GUID %x, state %s", dc.GUID[:], state)
+	switch state {
+	case FDO_STATE_PRE_TO1:
+		report.add("credential", verifyPass, detail)
+	case FDO_STATE_IDLE, FDO_STATE_RESALE:
+		report.add("credential", doctorWarn, detail+", onboarding already completed")
+	default:
+		report.add("credential", verifyFail, detail)
+	}
+
+	var urls []*url.URL
+	for _, directive := range protocol.ParseDeviceRvInfo(dc.RvInfo) {
+		urls = append(urls, directive.URLs...)
+	}
+	return urls
+}
+
+// doctorCrypto checks that the key exchange suite, the cipher suite and the
+// device key are of matching strength. Mismatches are allowed by the protocol
+// but often rejected by owner servers.
+func doctorCrypto() (string, string) {
+	kex, cipher := onboardConfig.Onboard.Kex, onboardConfig.Onboard.Cipher
+	if rootConfig.TPM == "" {
+		var cred fdoDeviceCredential
+		if err := readCredFile(&cred); err == nil && cred.DC.PrivateKey.IsValid() {
+			if err := matchKeyType(cred.DC.PrivateKey.Public(), rootConfig.Key); err != nil {
+				return verifyFail, err.Error()
+			}
+		}
+	}
+
+	var warnings []string
+	if want := kexKeyTypes[kex]; want != rootConfig.Key {
+		warnings = append(warnings, fmt.Sprintf("key exchange %s is meant for %s device keys, not %s", kex, want, rootConfig.Key))
+	}
+	strong := kex == "ECDH384" || kex == "DHKEXid15" || kex == "ASYMKEX3072"
+	if strong && strings.Contains(cipher, "128") {
+		warnings = append(warnings, fmt.Sprintf("cipher %s is weaker than key exchange %s", cipher, kex))
+	}
+	if len(warnings) > 0 {
+		return doctorWarn, strings.Join(warnings, "; ")
+	}
+	return verifyPass, fmt.Sprintf("%s key with %s and %s", rootConfig.Key, kex, cipher)
+}
+
+// doctorProxy checks the --proxy option or the proxy environment used by the
+// HTTP transport and returns the rendezvous URLs that are sent through a
+// proxy.
+func doctorProxy(report *doctorReport, rvURLs []*url.URL) map[string]bool {
+	var detail string
+	switch proxy := onboardConfig.Onboard.Proxy; proxy {
+	case directProxy:
+		report.add("proxy", verifyPass, "proxies disabled with --proxy direct")
+		return nil
+	case "":
+		var vars []string
+		for _, name := range []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy"} {
+			value := os.Getenv(name)
+			if value == "" {
+				continue
+			}
+			u, err := url.Parse(value)
+			if err != nil || u.Host == "" {
+				report.add("proxy", verifyFail, fmt.Sprintf("%s is not a valid proxy URL", name))
+				return nil
+			}
+			vars = append(vars, name+"="+u.Redacted())
+		}
+		if len(vars) == 0 {
+			report.add("proxy", verifyPass, "no proxy configured")
+			return nil
+		}
+		detail = strings.Join(vars, ", ")
+		if noProxy := getenvAny("NO_PROXY", "no_proxy"); noProxy != "" {
+			detail += ", NO_PROXY=" + noProxy
+		}
+	default:
+		u, err := url.Parse(proxy)
+		if err != nil || u.Host == "" {
+			report.add("proxy", verifyFail, "--proxy is not a valid proxy URL")
+			return nil
+		}
+		detail = "--proxy " + u.Redacted()
+		if noProxy := onboardConfig.Onboard.NoProxy; len(noProxy) > 0 {
+			detail += ", --no-proxy " + strings.Join(noProxy, ",")
+		}
+	}
+
+	proxied := map[string]bool{}
+	var via []string
+	for _, u := range rvURLs {
+		if proxyURL(u) != "" {
+			proxied[u.String()] = true
+			via = append(via, u.Host)
+		}
+	}
+	if len(via) > 0 {
+		detail += "; via proxy: " + strings.Join(via, " ")
+	} else {
+		detail += "; no rendezvous URL uses the proxy"
+	}
+	report.add("proxy", verifyPass, detail)
+	return proxied
+}
+
+// proxyFromEnvironment selects the proxy of a request from the proxy
+// environment variables like the HTTP transport. It is replaced in tests since
+// net/http reads the environment only once.
+var proxyFromEnvironment = http.ProxyFromEnvironment
+
+// proxyURL returns the proxy that the onboard connections use for u, the
+// --proxy option or the proxy that net/http.ProxyFromEnvironment selects, or
+// "" for a direct connection.
+func proxyURL(u *url.URL) string {
+	// CoAP is not proxied
+	if strings.HasPrefix(u.Scheme, "coap") {
+		return ""
+	}
+	switch proxy := onboardConfig.Onboard.Proxy; proxy {
+	case directProxy:
+		return ""
+	case "":
+	default:
+		if fdotls.NoProxy(u, onboardConfig.Onboard.NoProxy) {
+			return ""
+		}
+		return proxy
+	}
+
+	// TLS rendezvous URLs are sent as HTTPS requests
+	req := &http.Request{URL: &url.URL{Scheme: u.Scheme, Host: u.Host}}
+	if u.Scheme == "tls" {
+		req.URL.Scheme = "https"
+	}
+	proxy, err := proxyFromEnvironment(req)
+	if err != nil || proxy == nil {
+		return ""
+	}
+	return proxy.String()
+}
+
+func getenvAny(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// doctorCert is the validity of a TLS rendezvous server certificate.
+type doctorCert struct {
+	url       string
+	notBefore time.Time
+	notAfter  time.Time
+}
+
+// doctorNetwork resolves and connects to every rendezvous URL and returns the
+// validity of the TLS server certificates.
+func doctorNetwork(ctx context.Context, report *doctorReport, rvURLs []*url.URL, proxied map[string]bool, timeout time.Duration) []doctorCert {
+	conf := to1TLSConfig
+	if conf == nil {
+		conf = &tls.Config{InsecureSkipVerify: onboardConfig.Onboard.InsecureTLS} //nolint:gosec
+	}
+
+	var certs []doctorCert
+	for _, u := range rvURLs {
+		rawURL := u.String()
+		probe := probeURL(ctx, rawURL, timeout, conf)
+		if net.ParseIP(u.Hostname()) == nil {
+			if dnsErr, ok := strings.CutPrefix(probe.Error, dnsLookupFailed+": "); ok {
+				// The proxy resolves the host names of proxied URLs
+				if proxied[rawURL] {
+					report.add("dns", doctorWarn, fmt.Sprintf("%s: %s (resolved by the proxy)", u.Hostname(), dnsErr))
+				} else {
+					report.add("dns", verifyFail, fmt.Sprintf("%s: %s", u.Hostname(), dnsErr))
+				}
+				continue
+			}
+			if len(probe.Addresses) > 0 {
+				report.add("dns", verifyPass, fmt.Sprintf("%s: %s", u.Hostname(), strings.Join(probe.Addresses, " ")))
+			}
+		}
+
+		switch {
+		case probe.Error != "" && proxied[rawURL]:
+			report.add("connect", doctorWarn, fmt.Sprintf("%s: %s (requests go through the proxy)", rawURL, probe.Error))
+		case probe.Error != "":
+			report.add("connect", verifyFail, fmt.Sprintf("%s: %s", rawURL, probe.Error))
+		default:
+			detail := fmt.Sprintf("%s: tcp", rawURL)
+			if probe.CoAP {
+				detail = fmt.Sprintf("%s: coap", rawURL)
+			}
+			if probe.TLS != "" {
+				detail += ", " + probe.TLS
+			}
+			report.add("connect", verifyPass, fmt.Sprintf("%s, %dms", detail, probe.LatencyMs))
+		}
+
+		if u.Scheme == "https" || u.Scheme == "tls" {
+			if cert, err := serverCertificate(ctx, u, timeout); err == nil {
+				certs = append(certs, doctorCert{url: rawURL, notBefore: cert.NotBefore, notAfter: cert.NotAfter})
+			} else {
+				slog.Debug("Failed to read server certificate", "url", rawURL, "error", err)
+			}
+		}
+	}
+	return certs
+}
+
+// serverCertificate returns the leaf certificate of a TLS server without
+// verifying it, only its validity period is used.
+func serverCertificate(ctx context.Context, u *url.URL, timeout time.Duration) (*x509.Certificate, error) {
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: timeout},
+		Config: &tls.Config{
+			ServerName:         u.Hostname(),
+			InsecureSkipVerify: true, //nolint:gosec
+		},
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	conn, err := dialer.DialContext(ctx, "tcp", u.Host)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = conn.Close() }()
+	certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, errors.New("no server certificate")
+	}
+	return certs[0], nil
+}
+
+// doctorClock checks that the system time is plausible and within the
+// validity of the TLS server certificates, which fail to verify otherwise.
+func doctorClock(report *doctorReport, certs []doctorCert) {
+	now := doctorNow().UTC()
+	if now.Before(minimumClockTime) {
+		report.add("clock", verifyFail, fmt.Sprintf("system time %s is before %s, the clock is not set",
+			now.Format(time.RFC3339), minimumClockTime.Format(time.DateOnly)))
+		return
+	}
+	for _, cert := range certs {
+		if now.Before(cert.notBefore) || now.After(cert.notAfter) {
+			report.add("clock", verifyFail, fmt.Sprintf("system time %s is outside the validity of the certificate of %s (%s to %s): the clock or the certificate is wrong",
+				now.Format(time.RFC3339), cert.url, cert.notBefore.Format(time.RFC3339), cert.notAfter.Format(time.RFC3339)))
+			return
+		}
+	}
+	detail := "system time " + now.Format(time.RFC3339)
+	if len(certs) > 0 {
+		detail += fmt.Sprintf(", within the validity of %d server certificates", len(certs))
+	}
+	report.add("clock", verifyPass, detail)
+}
+
+func writeDoctorReport(w io.Writer, report *doctorReport, output string) error {
+	if output == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+
+	for _, check := range report.Checks {
+		fmt.Fprintf(w, "%-4s  %-11s", strings.ToUpper(check.Result), check.Name)
+		if check.Detail != "" {
+			fmt.Fprintf(w, "  %s", check.Detail)
+		}
+		fmt.Fprintln(w)
+	}
+	if n := report.failed(); n > 0 {
+		fmt.Fprintf(w, "%d checks FAILED\n", n)
+	} else {
+		fmt.Fprintln(w, "No problems found")
+	}
+	return nil
+}
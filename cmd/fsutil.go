@@ -4,6 +4,7 @@
 package cmd
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"log/slog"
@@ -120,3 +121,64 @@ func copyAndRemove(src, dst string) error {
 
 	return nil
 }
+
+// secureRemove overwrites the contents of a regular file with zeros, syncs
+// it to disk and then removes it, so that secrets do not linger in the
+// filesystem blocks that held them. Symlinks are refused, as with moveFile.
+//
+// This is best effort: journaling and copy-on-write filesystems or SSD wear
+// leveling may still retain copies of the original data.
+func secureRemove(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		return fmt.Errorf("refusing to remove symlink %q", path)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%q is not a regular file", path)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("error opening %q for overwrite: %w", path, err)
+	}
+	defer f.Close()
+
+	zeros := bytes.NewReader(make([]byte, info.Size()))
+	if _, err := io.Copy(f, zeros); err != nil {
+		return fmt.Errorf("error overwriting %q: %w", path, err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("error syncing %q: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("error closing %q: %w", path, err)
+	}
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("error removing %q: %w", path, err)
+	}
+	return nil
+}
+
+// writeFileAtomic writes data to a temp file next to path and moves it into
+// place, so that readers never see a partially written file.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".fdo_tmp_*")
+	if err != nil {
+		return fmt.Errorf("error creating temp file: %w", err)
+	}
+	defer func() { _ = tmp.Close() }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("error writing temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("error closing temp file: %w", err)
+	}
+	return moveFile(tmp.Name(), path)
+}
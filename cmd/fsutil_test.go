@@ -205,3 +205,28 @@ func TestMoveFileLargeFile(t *testing.T) {
 		t.Errorf("Permissions not preserved: got %o, want 0640", info.Mode().Perm())
 	}
 }
+
+// TestSecureRemove verifies that secureRemove deletes regular files and
+// refuses to follow symlinks.
+func TestSecureRemove(t *testing.T) {
+	tmpDir := t.TempDir()
+	target := filepath.Join(tmpDir, "secret.bin")
+	if err := os.WriteFile(target, []byte("secret data"), 0600); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	link := filepath.Join(tmpDir, "link.bin")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+	if err := secureRemove(link); err == nil {
+		t.Error("secureRemove should refuse symlinks")
+	}
+
+	if err := secureRemove(target); err != nil {
+		t.Fatalf("secureRemove failed: %v", err)
+	}
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Errorf("file still exists after secureRemove: %v", err)
+	}
+}
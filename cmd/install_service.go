@@ -0,0 +1,341 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+var (
+	validServiceModes        = []string{"onboard", "daemon"}
+	validRestartPolicies     = []string{"no", "on-failure", "on-abnormal", "on-abort", "always"}
+	defaultServiceWorkingDir = defaultStateDir
+)
+
+// serviceUnit describes the systemd service written by install-service.
+type serviceUnit struct {
+	Name       string        // unit name, e.g. go-fdo-client.service
+	Mode       string        // command run by the unit, onboard or daemon
+	Binary     string        // absolute path of the client executable
+	Restart    string        // Restart= policy
+	RestartSec time.Duration // RestartSec= delay
+	WorkingDir string        // working directory of the service and FSIMs
+	StateDir   bool          // whether systemd creates WorkingDir as StateDirectory=
+	Credential string        // blob file or TPM device the unit requires
+	CredArgs   []string      // options locating the configuration and the device credential
+}
+
+// serviceCredentialFlags are the only options install-service passes to the
+// unit besides --config; all others are read from the configuration file.
+var serviceCredentialFlags = []string{"config", "blob", "tpm", "progress-file"}
+
+var installServiceCmd = &cobra.Command{
+	Use:   "install-service",
+	Short: "Write a systemd unit that onboards the device at boot",
+	Long: `
+Write a systemd service that onboards the device once it is online. The
+service reads its options from the configuration file on every start, so
+that changes to the file, its config.d fragments and, for the daemon, a
+reload (SIGHUP) take effect without installing the service again:
+
+  <root>/etc/systemd/system/<unit>
+      Ordering and dependencies: the unit starts after network-online.target
+      and time-sync.target, as TO1 and TO2 need the network and a correct
+      clock to validate certificates.
+  <root>/etc/systemd/system/<unit>.d/10-onboard.conf
+      The device specific part: the command line with the configuration file
+      and the device credential, the working directory, the restart policy
+      and the conditions.
+
+A configuration file is required: --config, $FDO_CLIENT_CONFIG or the file
+found in the configuration directories. Only the --blob, --tpm and
+--progress-file options are accepted on the command line as well, other
+options must be set in the configuration file. The absolute paths of these
+files are written to the unit, without --root for files below it.
+
+The unit only starts when the device credential (--blob or --tpm path) exists
+and is skipped once the device has been onboarded: its ExecCondition= runs
+"status --pending", which fails once the credential is IDLE. With --mode
+onboard the unit is Type=oneshot, with --mode daemon it runs the daemon
+command as Type=notify.
+
+Unless default-working-dir is set in the configuration, the service runs in
+/var/lib/go-fdo-client, created by systemd as its StateDirectory=. Relative
+credential and progress file paths are made absolute.
+
+Nothing outside --root is modified and systemctl is not run. Run
+install-service again after changing the credential or the working
+directory.`,
+	Example: `
+  # Install a one-shot unit for a blob credential:
+  go-fdo-client install-service --config /etc/go-fdo-client/config.yaml --blob /var/lib/go-fdo-client/cred.bin
+  systemctl daemon-reload && systemctl enable go-fdo-client.service
+
+  # Write a daemon unit into an image build root:
+  go-fdo-client install-service --config ./rootfs/etc/go-fdo-client/config.yaml --mode daemon --root ./rootfs`,
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		var flagErr error
+		cmd.InheritedFlags().VisitAll(func(flag *pflag.Flag) {
+			if flag.Changed && flagErr == nil && !slices.Contains(serviceCredentialFlags, flag.Name) {
+				flagErr = fmt.Errorf("--%s is not passed to the service, set it in the configuration file", flag.Name)
+			}
+		})
+		if flagErr != nil {
+			return flagErr
+		}
+		// The defaults of the onboard options the service reads
+		if err := bindFlags(onboardCmd, "onboard"); err != nil {
+			return err
+		}
+		if err := viper.Unmarshal(&onboardConfig); err != nil {
+			return fmt.Errorf("failed to unmarshal onboard config: %w", err)
+		}
+		// The working directory is checked on the target, not on this host
+		return onboardConfig.validateOptions()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		root, err := cmd.Flags().GetString("root")
+		if err != nil {
+			return err
+		}
+		unit, err := newServiceUnit(cmd)
+		if err != nil {
+			return err
+		}
+
+		unitDir := filepath.Join(root, "etc", "systemd", "system")
+		files := []struct {
+			path    string
+			content string
+		}{
+			{filepath.Join(unitDir, unit.Name), unit.unitFile()},
+			{filepath.Join(unitDir, unit.Name+".d", "10-onboard.conf"), unit.dropIn()},
+		}
+		for _, f := range files {
+			if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+				return fmt.Errorf("error creating unit directory: %w", err)
+			}
+			if err := os.WriteFile(f.path, []byte(f.content), 0o644); err != nil { //nolint:gosec
+				return fmt.Errorf("error writing unit file: %w", err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Wrote %s\n", f.path)
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "Enable the service with:\n  systemctl daemon-reload\n  systemctl enable %s\n", unit.Name)
+		return nil
+	},
+}
+
+func installServiceCmdInit() {
+	rootCmd.AddCommand(installServiceCmd)
+	installServiceCmd.Flags().String("root", "/", "Write the unit files below this directory")
+	installServiceCmd.Flags().String("unit", "go-fdo-client.service", "Name of the systemd service unit")
+	installServiceCmd.Flags().String("mode", "onboard", "Command run by the unit [options: onboard, daemon]")
+	installServiceCmd.Flags().String("restart", "on-failure", "Restart= policy of the unit [options: no, on-failure, on-abnormal, on-abort, always]")
+	installServiceCmd.Flags().Duration("restart-sec", 30*time.Second, "Delay before the unit is restarted")
+	installServiceCmd.Flags().String("binary", "", "Path of the go-fdo-client executable (default: this executable)")
+}
+
+func init() {
+	installServiceCmdInit()
+}
+
+// newServiceUnit builds the unit from the command flags and the effective
+// configuration.
+func newServiceUnit(cmd *cobra.Command) (*serviceUnit, error) {
+	unit := &serviceUnit{}
+	var err error
+	if unit.Name, err = cmd.Flags().GetString("unit"); err != nil {
+		return nil, err
+	}
+	if unit.Mode, err = cmd.Flags().GetString("mode"); err != nil {
+		return nil, err
+	}
+	if unit.Restart, err = cmd.Flags().GetString("restart"); err != nil {
+		return nil, err
+	}
+	if unit.RestartSec, err = cmd.Flags().GetDuration("restart-sec"); err != nil {
+		return nil, err
+	}
+	if unit.Binary, err = cmd.Flags().GetString("binary"); err != nil {
+		return nil, err
+	}
+
+	if !strings.HasSuffix(unit.Name, ".service") || strings.ContainsRune(unit.Name, '/') {
+		return nil, fmt.Errorf("invalid unit name: '%s', must be a file name ending in .service", unit.Name)
+	}
+	if !slices.Contains(validServiceModes, unit.Mode) {
+		return nil, fmt.Errorf("invalid mode: '%s', options [%s]", unit.Mode, strings.Join(validServiceModes, ", "))
+	}
+	if !slices.Contains(validRestartPolicies, unit.Restart) {
+		return nil, fmt.Errorf("invalid restart policy: '%s', options [%s]", unit.Restart, strings.Join(validRestartPolicies, ", "))
+	}
+	if unit.Mode == "onboard" && unit.Restart == "always" {
+		return nil, fmt.Errorf("restart policy 'always' is not supported by one-shot units, use --mode daemon")
+	}
+	if unit.RestartSec < 0 {
+		return nil, fmt.Errorf("restart-sec must not be negative")
+	}
+	if onboardConfig.Onboard.Resale {
+		return nil, fmt.Errorf("resale cannot be installed as a service: the unit is skipped once the device is IDLE")
+	}
+
+	if unit.Binary == "" {
+		if unit.Binary, err = os.Executable(); err != nil {
+			return nil, fmt.Errorf("failed to determine the executable path, use --binary: %w", err)
+		}
+	}
+	if !filepath.IsAbs(unit.Binary) {
+		return nil, fmt.Errorf("binary must be an absolute path, got: %s", unit.Binary)
+	}
+
+	root, err := cmd.Flags().GetString("root")
+	if err != nil {
+		return nil, err
+	}
+	config, err := serviceConfigFile(root)
+	if err != nil {
+		return nil, err
+	}
+	unit.CredArgs = append(unit.CredArgs, "--config="+config)
+
+	if rootConfig.TPM != "" {
+		unit.Credential = rootConfig.TPM
+		unit.CredArgs = append(unit.CredArgs, "--tpm="+rootConfig.TPM)
+	} else {
+		if unit.Credential, err = targetPath(root, rootConfig.Blob); err != nil {
+			return nil, err
+		}
+		unit.CredArgs = append(unit.CredArgs, "--blob="+unit.Credential)
+	}
+	if rootConfig.ProgressFile != "" {
+		path, err := targetPath(root, rootConfig.ProgressFile)
+		if err != nil {
+			return nil, err
+		}
+		unit.CredArgs = append(unit.CredArgs, "--progress-file="+path)
+	}
+
+	unit.WorkingDir = onboardConfig.Onboard.DefaultWorkingDir
+	if unit.WorkingDir == "" {
+		unit.WorkingDir, unit.StateDir = defaultServiceWorkingDir, true
+	}
+	if !filepath.IsAbs(unit.WorkingDir) {
+		return nil, fmt.Errorf("default-working-dir must be an absolute path, got: %s", unit.WorkingDir)
+	}
+
+	return unit, nil
+}
+
+// serviceConfigFile returns the absolute path of the configuration file read
+// by the service, relative to the root of the target system.
+func serviceConfigFile(root string) (string, error) {
+	path := configFile
+	if path == "" {
+		path = os.Getenv(envVar("config"))
+	}
+	if path == "" {
+		_, path = findConfig()
+	}
+	if path == "" {
+		return "", fmt.Errorf("install-service requires a configuration file holding the onboard options, which the service reads on every start: use --config")
+	}
+	return targetPath(root, path)
+}
+
+// targetPath returns the absolute path of a file on the target system: paths
+// below root are made relative to it.
+func targetPath(root, path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if root, err = filepath.Abs(root); err != nil {
+		return "", err
+	}
+	if rel, err := filepath.Rel(root, path); err == nil && filepath.IsLocal(rel) {
+		path = string(filepath.Separator) + rel
+	}
+	return path, nil
+}
+
+// unitFile returns the service unit with the parts that do not depend on the
+// configuration.
+func (u *serviceUnit) unitFile() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Generated by go-fdo-client install-service.\n")
+	fmt.Fprintf(&b, "# Device specific settings are in %s.d/10-onboard.conf.\n", u.Name)
+	fmt.Fprintf(&b, "[Unit]\n")
+	fmt.Fprintf(&b, "Description=FIDO Device Onboard (FDO) client onboarding\n")
+	fmt.Fprintf(&b, "Documentation=man:go-fdo-client-%s(1) https://github.com/fido-device-onboard/go-fdo-client\n", u.Mode)
+	fmt.Fprintf(&b, "Wants=network-online.target\n")
+	fmt.Fprintf(&b, "After=network-online.target time-sync.target\n")
+	fmt.Fprintf(&b, "\n[Service]\n")
+	if u.Mode == "daemon" {
+		fmt.Fprintf(&b, "Type=notify\n")
+	} else {
+		fmt.Fprintf(&b, "Type=oneshot\n")
+		// onboard retries until it succeeds or reaches its configured bounds
+		fmt.Fprintf(&b, "TimeoutStartSec=infinity\n")
+	}
+	fmt.Fprintf(&b, "ExecStart=%s %s\n", systemdArg(u.Binary), u.Mode)
+	fmt.Fprintf(&b, "\n[Install]\n")
+	fmt.Fprintf(&b, "WantedBy=multi-user.target\n")
+	return b.String()
+}
+
+// dropIn returns the drop-in with the settings derived from the
+// configuration.
+func (u *serviceUnit) dropIn() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Generated by go-fdo-client install-service, the options are read from the configuration file.\n")
+	fmt.Fprintf(&b, "[Unit]\n")
+	if filepath.IsAbs(u.Credential) {
+		fmt.Fprintf(&b, "ConditionPathExists=%s\n", systemdArg(u.Credential))
+	}
+	fmt.Fprintf(&b, "\n[Service]\n")
+	fmt.Fprintf(&b, "# Skip the unit once the device has been onboarded (IDLE)\n")
+	fmt.Fprintf(&b, "ExecCondition=%s status --pending %s\n", systemdArg(u.Binary), systemdArgs(u.CredArgs))
+	fmt.Fprintf(&b, "ExecStart=\n")
+	fmt.Fprintf(&b, "ExecStart=%s %s %s\n", systemdArg(u.Binary), u.Mode, systemdArgs(u.CredArgs))
+	if u.StateDir {
+		fmt.Fprintf(&b, "StateDirectory=%s\n", filepath.Base(u.WorkingDir))
+	}
+	fmt.Fprintf(&b, "WorkingDirectory=%s\n", systemdArg(u.WorkingDir))
+	fmt.Fprintf(&b, "Restart=%s\n", u.Restart)
+	if u.RestartSec%time.Second == 0 {
+		fmt.Fprintf(&b, "RestartSec=%d\n", int64(u.RestartSec/time.Second))
+	} else {
+		fmt.Fprintf(&b, "RestartSec=%dms\n", u.RestartSec.Milliseconds())
+	}
+	return b.String()
+}
+
+func systemdArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = systemdArg(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// systemdArg escapes an argument of a unit file command line. Specifiers and
+// variables are escaped, arguments with white space or quotes are quoted.
+func systemdArg(arg string) string {
+	arg = strings.NewReplacer("%", "%%", "$", "$$").Replace(arg)
+	if arg != "" && !strings.ContainsAny(arg, " \t\n\"'\\;") {
+		return arg
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`).Replace(arg) + `"`
+}
@@ -4,16 +4,116 @@
 package cmd
 
 import (
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"slices"
+	"strings"
 
+	"github.com/fido-device-onboard/go-fdo-client/internal/journal"
+	"github.com/fido-device-onboard/go-fdo-client/internal/logfile"
 	"hermannm.dev/devlog"
 )
 
 var level slog.LevelVar
 
+// logCloser closes the log file or journal connection of the logger set by
+// configureLogging, if any.
+var logCloser io.Closer
+
+var (
+	validLogFormats = []string{"text", "json", "journald"}
+	validLogLevels  = []string{"debug", "info", "warn", "error"}
+)
+
 func init() {
 	slog.SetDefault(slog.New(devlog.NewHandler(os.Stdout, &devlog.Options{
 		Level: &level,
 	})))
 }
+
+// validateLogging validates the logging options of the global configuration.
+func (f *FDOClientConfig) validateLogging() error {
+	if f.LogFormat != "" && !slices.Contains(validLogFormats, f.LogFormat) {
+		return fmt.Errorf("invalid --log-format: '%s' [options: %s]", f.LogFormat, strings.Join(validLogFormats, ", "))
+	}
+	if f.LogLevel != "" && !slices.Contains(validLogLevels, f.LogLevel) {
+		return fmt.Errorf("invalid --log-level: '%s' [options: %s]", f.LogLevel, strings.Join(validLogLevels, ", "))
+	}
+	if f.LogFormat == "journald" && f.LogFile != "" {
+		return fmt.Errorf("--log-file cannot be used with --log-format journald")
+	}
+	if f.LogMaxSize < 0 || f.LogMaxBackups < 0 {
+		return fmt.Errorf("--log-max-size and --log-max-backups must not be negative")
+	}
+	return nil
+}
+
+// logLevel returns the level of the --log-level and --debug options.
+func (f *FDOClientConfig) logLevel() slog.Level {
+	if f.Debug {
+		return slog.LevelDebug
+	}
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(f.LogLevel)); err != nil {
+		return slog.LevelInfo
+	}
+	return l
+}
+
+// configureLogging replaces the default logger with one writing in the
+// format and to the destination of the configuration: the log file if set,
+// the journal with --log-format journald or when the output of the process
+// goes to the journal, and standard output otherwise. The previous logger is
+// kept if the new one cannot be set up.
+func configureLogging(f FDOClientConfig) error {
+	if err := f.validateLogging(); err != nil {
+		return err
+	}
+
+	format := f.LogFormat
+	if format == "" {
+		format = "text"
+		if f.LogFile == "" && journal.Connected() {
+			format = "journald"
+		}
+	}
+
+	var out io.Writer = os.Stdout
+	var closer io.Closer
+	if f.LogFile != "" {
+		file, err := logfile.Open(f.LogFile, int64(f.LogMaxSize)<<20, f.LogMaxBackups)
+		if err != nil {
+			return err
+		}
+		out, closer = file, file
+	}
+
+	var handler slog.Handler
+	switch format {
+	case "json":
+		handler = slog.NewJSONHandler(out, &slog.HandlerOptions{Level: &level})
+	case "journald":
+		h, err := journal.NewHandler("go-fdo-client", &slog.HandlerOptions{Level: &level})
+		if err != nil && f.LogFormat == "" {
+			// Output still reaches the journal through standard output
+			handler = devlog.NewHandler(out, &devlog.Options{Level: &level})
+			break
+		}
+		if err != nil {
+			return err
+		}
+		handler, closer = h, h
+	default:
+		handler = devlog.NewHandler(out, &devlog.Options{Level: &level})
+	}
+
+	level.Set(f.logLevel())
+	slog.SetDefault(slog.New(handler))
+	if logCloser != nil {
+		_ = logCloser.Close()
+	}
+	logCloser = closer
+	return nil
+}
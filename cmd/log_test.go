@@ -0,0 +1,137 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fido-device-onboard/go-fdo"
+	"github.com/fido-device-onboard/go-fdo/protocol"
+)
+
+// readJSONLog returns the records of a JSON log file.
+func readJSONLog(t *testing.T, path string) []map[string]any {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	var records []map[string]any
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var record map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("invalid JSON log line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+func TestConfigureLogging(t *testing.T) {
+	t.Cleanup(func() { _ = configureLogging(FDOClientConfig{}) })
+	path := filepath.Join(t.TempDir(), "client.log")
+
+	if err := configureLogging(FDOClientConfig{LogFormat: "json", LogLevel: "warn", LogFile: path}); err != nil {
+		t.Fatal(err)
+	}
+	slog.Info("not logged")
+	slog.Warn("logged", "guid", "0123")
+	if err := configureLogging(FDOClientConfig{LogFormat: "json", LogLevel: "warn", LogFile: path, Debug: true}); err != nil {
+		t.Fatal(err)
+	}
+	slog.Debug("logged with --debug")
+
+	records := readJSONLog(t, path)
+	if len(records) != 2 || records[0]["msg"] != "logged" || records[0]["level"] != "WARN" || records[0]["guid"] != "0123" ||
+		records[1]["msg"] != "logged with --debug" {
+		t.Errorf("records = %v", records)
+	}
+}
+
+func TestConfigureLogging_Invalid(t *testing.T) {
+	t.Cleanup(func() { _ = configureLogging(FDOClientConfig{}) })
+	for _, tt := range []struct {
+		name   string
+		config FDOClientConfig
+		err    string
+	}{
+		{"format", FDOClientConfig{LogFormat: "xml"}, "invalid --log-format"},
+		{"level", FDOClientConfig{LogLevel: "verbose"}, "invalid --log-level"},
+		{"journald file", FDOClientConfig{LogFormat: "journald", LogFile: "client.log"}, "cannot be used with --log-format journald"},
+		{"size", FDOClientConfig{LogMaxSize: -1}, "must not be negative"},
+		{"file", FDOClientConfig{LogFile: filepath.Join(t.TempDir(), "missing", "client.log")}, "failed to open log file"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := configureLogging(tt.config); err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+// TestOnboardLogAttributes checks that the records of the retry loop carry
+// the GUID, directive and phase.
+func TestOnboardLogAttributes(t *testing.T) {
+	resetState(t)
+	path := filepath.Join(t.TempDir(), "client.log")
+	if err := configureLogging(FDOClientConfig{LogFormat: "json", LogFile: path}); err != nil {
+		t.Fatal(err)
+	}
+
+	dc := newTestBlobCred(t, FDO_STATE_PRE_TO1)
+	conf := fdo.TO2Config{Cred: dc.DC.DeviceCredential, Key: dc.DC.PrivateKey.Signer}
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rvInfo := unreachableRvInfo(t, 2, 0)
+	rvInfo[1] = append(rvInfo[1], testRvInstruction(t, protocol.RVBypass, nil))
+	onboardConfig = OnboardClientConfig{Onboard: OnboardConfig{DefaultWorkingDir: cwd, MaxAttempts: 2}}
+	t.Cleanup(func() { onboardConfig = OnboardClientConfig{} })
+
+	policy, _ := newTestRetryPolicy(onboardConfig.Onboard)
+	if _, err := transferOwnership(context.Background(), rvInfo, conf, policy, &onboardProgress{}); !errors.Is(err, errMaxAttempts) {
+		t.Fatalf("transferOwnership error = %v, want %v", err, errMaxAttempts)
+	}
+
+	guid := hex.EncodeToString(conf.Cred.GUID[:])
+	want := map[string]struct {
+		directive float64
+		phase     string
+	}{
+		"TO1 failed": {0, "TO1"},
+		"TO2 failed": {1, "TO2"},
+	}
+	for _, record := range readJSONLog(t, path) {
+		if record["guid"] != guid {
+			t.Errorf("record %v without the GUID", record)
+		}
+		msg, _ := record["msg"].(string)
+		w, ok := want[msg]
+		if !ok {
+			continue
+		}
+		delete(want, msg)
+		if record["directive"] != w.directive || record["phase"] != w.phase || record["url"] == nil || record["error"] == nil {
+			t.Errorf("record %v, want directive %v and phase %s", record, w.directive, w.phase)
+		}
+	}
+	if len(want) > 0 {
+		t.Errorf("missing records %v", want)
+	}
+}
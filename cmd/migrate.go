@@ -0,0 +1,165 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+package cmd
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/fido-device-onboard/go-fdo-client/internal/tpm_utils"
+	"github.com/spf13/cobra"
+)
+
+// Errors returned when a credential cannot be moved between storage backends.
+var (
+	errBlobToTpm = errors.New("a blob credential cannot be imported into a TPM: " +
+		"the TPM derives its own device key and HMAC key, so the key and HMAC secret " +
+		"recorded by the manufacturer cannot be loaded into it; " +
+		"re-initialize the device with device-init --tpm instead")
+	errTpmToBlob = errors.New("a TPM credential cannot be imported into a blob: " +
+		"the device key and HMAC key are generated inside the TPM and are not exportable")
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export <file>",
+	Short: "Export the device credential to a file",
+	Long: `Export the stored device credential to a file in its native CBOR encoding.
+
+A blob credential export contains the device private key and HMAC secret and
+must be protected accordingly. A TPM credential export only contains the
+public credential; the keys stay in the TPM, so it can only be imported back
+into the TPM that created it.`,
+	Example: `  # Back up the credential stored in a TPM:
+  go-fdo-client export --tpm /dev/tpmrm0 tpm-cred.bin`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		force, err := cmd.Flags().GetBool("force")
+		if err != nil {
+			return err
+		}
+		out := args[0]
+		if !force && fileExists(out) {
+			return fmt.Errorf("%q already exists (use --force to overwrite)", out)
+		}
+
+		var dc any
+		if rootConfig.TPM != "" {
+			tpmc, err = tpm_utils.TpmOpen(rootConfig.TPM)
+			if err != nil {
+				return err
+			}
+			defer tpmc.Close()
+			var tpmCred fdoTpmDeviceCredential
+			if err := readTpmCred(&tpmCred); err != nil {
+				return fmt.Errorf("failed to read credential from TPM: %w", err)
+			}
+			dc = tpmCred
+		} else {
+			var fileCred fdoDeviceCredential
+			if err := readCredFile(&fileCred); err != nil {
+				return fmt.Errorf("failed to read credential from file: %w", err)
+			}
+			dc = fileCred
+		}
+
+		if err := saveCredTo(out, dc); err != nil {
+			return err
+		}
+		slog.Info("Device credential exported", "file", out)
+		return nil
+	},
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import a device credential from a file",
+	Long: `Import a device credential previously written by export into the configured
+storage backend (--blob or --tpm).
+
+Only credentials whose keys can live in the destination are accepted:
+  blob credential  ->  --blob   supported
+  TPM credential   ->  --tpm    supported on the TPM that created it
+  blob credential  ->  --tpm    refused, the TPM cannot load external keys
+  TPM credential   ->  --blob   refused, TPM keys are not exportable
+
+Importing into a TPM requires --key to match the key type used at device-init.
+An existing credential in the destination is only replaced with --force.`,
+	Example: `  # Restore a TPM credential backup:
+  go-fdo-client import --tpm /dev/tpmrm0 --key ec256 tpm-cred.bin
+
+  # Move a blob credential to a new location:
+  go-fdo-client import --blob /var/lib/fdo/cred.bin /tmp/cred.bin`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		force, err := cmd.Flags().GetBool("force")
+		if err != nil {
+			return err
+		}
+
+		// Identify the credential type before touching the destination
+		var blobCred fdoDeviceCredential
+		var tpmCred fdoTpmDeviceCredential
+		isBlob := readCredFileFrom(args[0], &blobCred) == nil
+		if !isBlob {
+			if err := readCredFileFrom(args[0], &tpmCred); err != nil {
+				return fmt.Errorf("%q is neither a blob nor a TPM device credential: %w", args[0], err)
+			}
+		}
+
+		if rootConfig.TPM == "" {
+			if !isBlob {
+				return errTpmToBlob
+			}
+			if !force && fileExists(rootConfig.Blob) {
+				return fmt.Errorf("blob credential %q already exists (use --force to replace it)", rootConfig.Blob)
+			}
+			if err := saveCred(blobCred); err != nil {
+				return err
+			}
+			slog.Info("Device credential imported", "blob", rootConfig.Blob,
+				"guid", hex.EncodeToString(blobCred.DC.GUID[:]), "state", blobCred.State)
+			return nil
+		}
+
+		if isBlob {
+			return errBlobToTpm
+		}
+		if rootConfig.Key == "" {
+			return fmt.Errorf("--key is required to import a TPM credential (via CLI flag or config file)")
+		}
+		if err := validateKey(rootConfig.Key); err != nil {
+			return err
+		}
+
+		tpmc, err = tpm_utils.TpmOpen(rootConfig.TPM)
+		if err != nil {
+			return err
+		}
+		defer tpmc.Close()
+
+		if !force && credentialStored() {
+			return fmt.Errorf("TPM NV index 0x%08X already holds a credential (use --force to replace it)", FDO_CRED_NV_IDX)
+		}
+		slog.Warn("TPM credentials only work on the TPM that created them, the device keys are derived from it")
+		if err := saveTpmCred(tpmCred); err != nil {
+			return err
+		}
+		slog.Info("Device credential imported", "tpm", rootConfig.TPM,
+			"guid", hex.EncodeToString(tpmCred.DC.GUID[:]), "state", tpmCred.State)
+		return nil
+	},
+}
+
+func migrateCmdInit() {
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(importCmd)
+	exportCmd.Flags().Bool("force", false, "Overwrite an existing output file")
+	importCmd.Flags().Bool("force", false, "Replace an existing device credential")
+}
+
+func init() {
+	migrateCmdInit()
+}
@@ -0,0 +1,80 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/fido-device-onboard/go-fdo/tpm"
+)
+
+func runCmd(t *testing.T, args ...string) error {
+	t.Helper()
+	resetState(t)
+	rootCmd.SetArgs(args)
+	return rootCmd.Execute()
+}
+
+func TestExportImport_Blob(t *testing.T) {
+	want := newTestBlobCred(t, FDO_STATE_PRE_TO1)
+	src := writeTestBlobCred(t, want)
+	exported := filepath.Join(t.TempDir(), "export.bin")
+
+	if err := runCmd(t, "export", "--blob", src, exported); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	if err := runCmd(t, "export", "--blob", src, exported); err == nil {
+		t.Fatal("export should refuse to overwrite an existing file without --force")
+	}
+
+	dst := filepath.Join(t.TempDir(), "imported.bin")
+	if err := runCmd(t, "import", "--blob", dst, exported); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+
+	var got fdoDeviceCredential
+	if err := readCredFileFrom(dst, &got); err != nil {
+		t.Fatalf("reading imported credential: %v", err)
+	}
+	if got.DC.GUID != want.DC.GUID || got.State != want.State || !bytes.Equal(got.DC.HmacSecret, want.DC.HmacSecret) {
+		t.Error("imported credential does not match the exported one")
+	}
+
+	if err := runCmd(t, "import", "--blob", dst, exported); err == nil {
+		t.Fatal("import should refuse to replace an existing credential without --force")
+	}
+	if err := runCmd(t, "import", "--blob", dst, "--force", exported); err != nil {
+		t.Fatalf("import --force: %v", err)
+	}
+}
+
+func TestImport_RefusesCrossBackend(t *testing.T) {
+	blobCred := newTestBlobCred(t, FDO_STATE_PRE_TO1)
+	blobFile := writeTestBlobCred(t, blobCred)
+
+	tpmFile := filepath.Join(t.TempDir(), "tpm.bin")
+	if err := saveCredTo(tpmFile, fdoTpmDeviceCredential{
+		DC:    tpm.DeviceCredential{DeviceCredential: blobCred.DC.DeviceCredential, DeviceKey: tpm.FdoDeviceKey},
+		State: FDO_STATE_PRE_TO1,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := runCmd(t, "import", "--tpm", "/dev/nonexistent-tpm", "--key", "ec256", blobFile)
+	if !errors.Is(err, errBlobToTpm) {
+		t.Errorf("blob to TPM import error = %v, want %v", err, errBlobToTpm)
+	}
+
+	dst := filepath.Join(t.TempDir(), "cred.bin")
+	err = runCmd(t, "import", "--blob", dst, tpmFile)
+	if !errors.Is(err, errTpmToBlob) {
+		t.Errorf("TPM to blob import error = %v, want %v", err, errTpmToBlob)
+	}
+	if fileExists(dst) {
+		t.Error("refused import created the destination credential")
+	}
+}
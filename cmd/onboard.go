@@ -6,6 +6,7 @@ package cmd
 import (
 	"bytes"
 	"context"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io/fs"
@@ -22,7 +23,7 @@ import (
 	"time"
 
 	"github.com/fido-device-onboard/go-fdo"
-	"github.com/fido-device-onboard/go-fdo-client/internal/tls"
+	"github.com/fido-device-onboard/go-fdo-client/internal/coap"
 	"github.com/fido-device-onboard/go-fdo-client/internal/tpm_utils"
 	"github.com/fido-device-onboard/go-fdo/cose"
 	"github.com/fido-device-onboard/go-fdo/fsim"
@@ -30,6 +31,7 @@ import (
 	"github.com/fido-device-onboard/go-fdo/protocol"
 	"github.com/fido-device-onboard/go-fdo/serviceinfo"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
@@ -52,13 +54,36 @@ var validKexSuites = []string{
 	"DHKEXid14", "DHKEXid15", "ASYMKEX2048", "ASYMKEX3072", "ECDH256", "ECDH384",
 }
 
+// Exit codes returned by the onboard command when a bounded run gives up.
+const (
+	onboardExitMaxAttempts = 30
+	onboardExitTimeout     = 31
+	onboardExitOnce        = 32
+)
+
+// Errors returned by transferOwnership when a bounded run gives up.
+var (
+	errMaxAttempts = errors.New("maximum number of onboarding attempts reached")
+	errOnce        = errors.New("all rendezvous directives tried once without success")
+)
+
 var onboardCmd = &cobra.Command{
 	Use:   "onboard",
 	Short: "Run FDO TO1 and TO2 onboarding",
 	Long: `
 Run FDO TO1 and TO2 onboarding to transfer device ownership to the owner server.
 The device must have been initialized (device-init) before running onboard.
-At least one of --blob or --tpm is required to access device credentials.`,
+At least one of --blob or --tpm is required to access device credentials.
+
+By default onboard retries until it succeeds or is interrupted. An attempt is
+one try of a rendezvous directive: TO1 (unless bypassed) followed by TO2 with
+each owner URL found. The retries can be bounded, each bound has its own exit
+code:
+  0   onboarding completed
+  1   onboarding failed or could not be started
+  30  --max-attempts attempts failed
+  31  --timeout expired
+  32  --once tried every directive once without success`,
 	Example: `
   # Using CLI arguments:
   go-fdo-client onboard --key ec256 --kex ECDH256 --blob cred.bin
@@ -67,74 +92,133 @@ At least one of --blob or --tpm is required to access device credentials.`,
   go-fdo-client onboard --config config.yaml
 
   # Mix CLI and config (CLI takes precedence):
-  go-fdo-client onboard --config config.yaml --cipher A256GCM`,
-	PreRunE: func(cmd *cobra.Command, args []string) error {
-		err := bindFlags(cmd, "onboard")
-		if err != nil {
-			return err
-		}
-
-		if err := viper.Unmarshal(&onboardConfig); err != nil {
-			return fmt.Errorf("failed to unmarshal onboard config: %w", err)
-		}
+  go-fdo-client onboard --config config.yaml --cipher A256GCM
 
-		return onboardConfig.validate()
+  # Give up after trying each directive once or after 10 minutes:
+  go-fdo-client onboard --config config.yaml --once --timeout 10m`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadOnboardConfig(cmd)
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
-
-		if rootConfig.Debug {
-			level.Set(slog.LevelDebug)
-		}
-
-		if rootConfig.TPM != "" {
-			var err error
-			tpmc, err = tpm_utils.TpmOpen(rootConfig.TPM)
-			if err != nil {
-				return err
-			}
-			defer tpmc.Close()
-		}
-
-		deviceStatus, err := loadDeviceStatus()
-		if err != nil {
-			return fmt.Errorf("load device status failed: %w", err)
-		}
-
-		printDeviceStatus(deviceStatus)
-
-		if deviceStatus == FDO_STATE_PRE_TO1 || (deviceStatus == FDO_STATE_IDLE && onboardConfig.Onboard.Resale) {
-			return doOnboard()
-		} else if deviceStatus == FDO_STATE_IDLE {
-			slog.Info("FDO in Idle State. Device Onboarding already completed")
-		} else if deviceStatus == FDO_STATE_PRE_DI {
-			return fmt.Errorf("device has not been properly initialized: run device-init first")
-		} else {
-			return fmt.Errorf("device state is invalid: %v", deviceStatus)
-		}
-
-		return nil
+		return onboardExitError(onboardDevice(clientContext, onboardHooks{}))
 	},
 }
 
 func onboardCmdInit() {
 	rootCmd.AddCommand(onboardCmd)
+	addOnboardFlags(onboardCmd)
+}
 
-	onboardCmd.Flags().Bool("allow-credential-reuse", false, "Allow credential reuse protocol during onboarding")
-	onboardCmd.Flags().String("cipher", "A128GCM", "Name of cipher suite to use for encryption (see usage)")
-	onboardCmd.Flags().Bool("enable-interop-test", false, "Enable FIDO Alliance interop test module (fsim.Interop)")
-	onboardCmd.Flags().String("kex", "", "Name of cipher suite to use for key exchange (see usage)")
-	onboardCmd.Flags().Bool("insecure-tls", false, "Skip TLS certificate verification")
-	onboardCmd.Flags().Int("max-serviceinfo-size", serviceinfo.DefaultMTU, "Maximum service info size to receive")
-	onboardCmd.Flags().Bool("resale", false, "Perform resale")
-	onboardCmd.Flags().Duration("to2-retry-delay", 0, "Delay between failed TO2 attempts when trying multiple Owner URLs from same RV directive (0=disabled)")
-	onboardCmd.Flags().String("default-working-dir", "", "Default working directory for all FSIMs (fdo.command, fdo.download, fdo.upload, fdo.wget) (default: current working directory)")
+// addOnboardFlags registers the flags of the onboard section of the
+// configuration on cmd.
+func addOnboardFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("allow-credential-reuse", false, "Allow credential reuse protocol during onboarding")
+	cmd.Flags().String("cipher", "A128GCM", "Name of cipher suite to use for encryption (see usage)")
+	cmd.Flags().Bool("enable-interop-test", false, "Enable FIDO Alliance interop test module (fsim.Interop)")
+	cmd.Flags().String("kex", "", "Name of cipher suite to use for key exchange (see usage)")
+	cmd.Flags().Bool("insecure-tls", false, "Skip TLS certificate verification")
+	cmd.Flags().Int("max-serviceinfo-size", serviceinfo.DefaultMTU, "Maximum service info size to receive")
+	cmd.Flags().Bool("resale", false, "Perform resale")
+	cmd.Flags().Duration("to2-retry-delay", 0, "Delay between failed TO2 attempts when trying multiple Owner URLs from same RV directive (0=disabled)")
+	cmd.Flags().String("default-working-dir", "", "Default working directory for all FSIMs (fdo.command, fdo.download, fdo.upload, fdo.wget) (default: current working directory)")
+	cmd.Flags().Int("max-attempts", 0, "Give up after this many failed rendezvous directive attempts (0=unlimited)")
+	cmd.Flags().Duration("timeout", 0, "Give up when onboarding has not completed within this duration (0=unlimited)")
+	cmd.Flags().Bool("once", false, "Give up after trying every rendezvous directive once")
+	cmd.Flags().Duration("start-delay", 0, "Wait a random duration of up to this long before the first attempt (0=disabled)")
+	cmd.Flags().Duration("to1-failure-delay", 0, "Delay after a directive whose TO1 failed, unless the directive sets a delay (0=RV defaults)")
+	cmd.Flags().Duration("to2-failure-delay", 0, "Delay after a directive whose TO2 attempts failed, unless the directive sets a delay (0=RV defaults)")
+	cmd.Flags().Float64("backoff-multiplier", 1, "Multiply directive delays by this factor for each failed pass over all directives")
+	cmd.Flags().Duration("max-delay", time.Hour, "Cap of directive delays grown by --backoff-multiplier")
+	cmd.Flags().String("to1-tls-ca-file", "", "PEM bundle of the CA certificates trusted for rendezvous servers instead of the system roots")
+	cmd.Flags().StringSlice("to1-tls-pin-spki", nil, "Base64 SHA-256 hash of a public key of which one must be in the rendezvous server's certificate chain (repeatable)")
+	cmd.Flags().String("to1-tls-server-name", "", "Name rendezvous server certificates are verified against instead of the URL host")
+	cmd.Flags().String("to2-tls-ca-file", "", "PEM bundle of the CA certificates trusted for owner servers instead of the system roots")
+	cmd.Flags().StringSlice("to2-tls-pin-spki", nil, "Base64 SHA-256 hash of a public key of which one must be in the owner server's certificate chain (repeatable)")
+	cmd.Flags().String("to2-tls-server-name", "", "Name owner server certificates are verified against instead of the URL host")
+	cmd.Flags().String("tls-client-cert", "", "PEM certificate chain presented to rendezvous and owner servers or proxies requesting a client certificate")
+	cmd.Flags().String("tls-client-key", "", "PEM private key of --tls-client-cert, or \"tpm\" for the device key in the TPM")
+	addTransportFlags(cmd)
 }
 
 func init() {
 	onboardCmdInit()
 }
 
-func doOnboard() error {
+// loadOnboardConfig binds the flags of cmd to the onboard section of the
+// configuration and loads and validates it.
+func loadOnboardConfig(cmd *cobra.Command) error {
+	err := bindFlags(cmd, "onboard")
+	if err != nil {
+		return err
+	}
+	if err := checkProxyFlag(cmd); err != nil {
+		return err
+	}
+
+	if err := viper.Unmarshal(&onboardConfig); err != nil {
+		return fmt.Errorf("failed to unmarshal onboard config: %w", err)
+	}
+
+	return onboardConfig.validate()
+}
+
+// readOnboardConfig binds the onboard flags of cmd to the onboard section of
+// the configuration and loads it without validating it, for the commands
+// that inspect the onboarding of the device without running it.
+func readOnboardConfig(cmd *cobra.Command) error {
+	var bindErr error
+	cmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
+		if onboardCmd.Flags().Lookup(flag.Name) == nil {
+			return
+		}
+		if err := viper.BindPFlag("onboard."+flag.Name, flag); err != nil {
+			bindErr = err
+		}
+	})
+	if bindErr != nil {
+		return bindErr
+	}
+	if err := viper.Unmarshal(&onboardConfig); err != nil {
+		return fmt.Errorf("failed to unmarshal onboard config: %w", err)
+	}
+	return nil
+}
+
+// onboardDevice onboards the device if its state calls for it. The device is
+// onboarded once it is IDLE, unless resale is enabled.
+func onboardDevice(ctx context.Context, hooks onboardHooks) error {
+	if rootConfig.TPM != "" {
+		var err error
+		tpmc, err = tpm_utils.TpmOpen(rootConfig.TPM)
+		if err != nil {
+			return err
+		}
+		defer tpmc.Close()
+	}
+
+	deviceStatus, err := loadDeviceStatus()
+	if err != nil {
+		return fmt.Errorf("load device status failed: %w", err)
+	}
+
+	printDeviceStatus(deviceStatus)
+
+	if deviceStatus == FDO_STATE_PRE_TO1 || (deviceStatus == FDO_STATE_IDLE && onboardConfig.Onboard.Resale) {
+		return doOnboard(ctx, hooks)
+	} else if deviceStatus == FDO_STATE_IDLE {
+		slog.Info("FDO in Idle State. Device Onboarding already completed")
+	} else if deviceStatus == FDO_STATE_PRE_DI {
+		return fmt.Errorf("device has not been properly initialized: run device-init first")
+	} else {
+		return fmt.Errorf("device state is invalid: %v", deviceStatus)
+	}
+
+	return nil
+}
+
+// doOnboard runs TO1 and TO2 until the device is onboarded or ctx is done,
+// calling hooks as the retry loop progresses.
+func doOnboard(ctx context.Context, hooks onboardHooks) error {
 	// Read device credential blob to configure client for TO1/TO2
 	dc, hmacSha256, hmacSha384, privateKey, cleanup, err := readCred()
 	if err == nil && cleanup != nil {
@@ -162,7 +246,28 @@ func doOnboard() error {
 		slog.Warn("Setting serviceinfo.Devmod.Device", "error", err, "default", deviceName)
 	}
 
-	newDC, err := transferOwnership(clientContext, dc.RvInfo, fdo.TO2Config{
+	if onboardConfig.Onboard.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, onboardConfig.Onboard.Timeout)
+		defer cancel()
+	}
+
+	session, err := openSession(&onboardConfig.Onboard.TransportConfig)
+	if err != nil {
+		return err
+	}
+	defer session.warnRecordErr()
+	// A replayed session does not connect to the servers
+	if !session.replaying() {
+		if err := loadOnboardTransport(privateKey); err != nil {
+			return err
+		}
+	}
+
+	log := slog.With("guid", hex.EncodeToString(dc.GUID[:]))
+	progress := loadProgress(dc.GUID)
+	progress.hooks = hooks
+	newDC, err := transferOwnership(ctx, dc.RvInfo, fdo.TO2Config{
 		Cred:       *dc,
 		HmacSha256: hmacSha256,
 		HmacSha384: hmacSha384,
@@ -179,80 +284,216 @@ func doOnboard() error {
 		CipherSuite:               kexCipherSuiteID,
 		AllowCredentialReuse:      onboardConfig.Onboard.AllowCredentialReuse,
 		MaxServiceInfoSizeReceive: uint16(onboardConfig.Onboard.MaxServiceInfoSize),
-	})
+	}, newRetryPolicy(onboardConfig.Onboard), progress)
 	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("onboarding did not complete within %s: %w", onboardConfig.Onboard.Timeout, context.DeadlineExceeded)
+		}
 		if errors.Is(err, context.Canceled) {
-			slog.Info("Onboarding canceled by user")
+			log.Info("Onboarding canceled by user")
 		}
 		return err
 	}
 	if newDC == nil {
-		slog.Info("Credential not updated (Credential Reuse Protocol)")
+		log.Info("Credential not updated (Credential Reuse Protocol)")
+		progress.recordCompleted(dc.GUID)
 		return nil
 	}
 
 	// Store new credential
-	slog.Info("FIDO Device Onboard Complete")
-	return updateCred(*newDC, FDO_STATE_IDLE)
+	log.Info("FIDO Device Onboard Complete")
+	if err := updateCred(*newDC, FDO_STATE_IDLE); err != nil {
+		return err
+	}
+	progress.recordCompleted(newDC.GUID)
+	return nil
+}
+
+// onboardExitError attaches the exit code of a bounded onboarding run that
+// gave up to err.
+func onboardExitError(err error) error {
+	switch {
+	case errors.Is(err, errMaxAttempts):
+		return &exitCodeError{code: onboardExitMaxAttempts, err: err}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &exitCodeError{code: onboardExitTimeout, err: err}
+	case errors.Is(err, errOnce):
+		return &exitCodeError{code: onboardExitOnce, err: err}
+	default:
+		return err
+	}
+}
+
+// defaultLastDirectiveDelay is applied after the last rendezvous directive
+// when it does not configure a delay.
+const defaultLastDirectiveDelay = 120 * time.Second
+
+// directiveDelay returns the delay, before jitter, applied after all attempts
+// for a directive have failed.
+func directiveDelay(directive *protocol.RvDirective, isLastDirective bool) time.Duration {
+	if directive.Delay == 0 && isLastDirective {
+		return defaultLastDirectiveDelay
+	}
+	return directive.Delay
 }
 
 // addJitter adds ±25% randomization to a delay duration as per FDO spec v1.1 section 3.7.
+// The result saturates at the longest duration instead of overflowing.
 func addJitter(delay time.Duration) time.Duration {
 	jitterPercent := 0.25 * (2*rand.Float64() - 1) // Random from -0.25 to +0.25 (±25%)
-	jitter := float64(delay) * jitterPercent
-	return delay + time.Duration(jitter)
+	jittered := float64(delay) * (1 + jitterPercent)
+	if jittered >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(jittered)
 }
 
 // applyDelay waits for the specified duration with context cancellation support.
-func applyDelay(ctx context.Context, delay time.Duration) error {
+func applyDelay(ctx context.Context, clock retryClock, delay time.Duration) error {
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
-	case <-time.After(delay):
+	case <-clock.After(delay):
 		return nil
 	}
 }
 
-// getOwnerURLs performs TO1 protocol to discover Owner URLs or uses RV bypass.
-// Returns: owner URLs, TO1 response (needed for TO2)
-func getOwnerURLs(ctx context.Context, directive *protocol.RvDirective, conf fdo.TO2Config) ([]string, *cose.Sign1[protocol.To1d, []byte]) {
+// retryClock abstracts waiting so that the retry loop can be tested without
+// sleeping.
+type retryClock interface {
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// retryPhase is the protocol that failed for a rendezvous directive.
+type retryPhase int
+
+const (
+	retryPhaseTO1 retryPhase = iota
+	retryPhaseTO2
+)
+
+func (p retryPhase) String() string {
+	if p == retryPhaseTO2 {
+		return "TO2"
+	}
+	return "TO1"
+}
+
+// retryPolicy computes the delays of the onboarding retry loop. With all
+// options at their zero value it only applies the directive delays of
+// directiveDelay.
+type retryPolicy struct {
+	StartDelay      time.Duration // upper bound of the random delay before the first attempt
+	TO1FailureDelay time.Duration // delay after a failed TO1, unless the directive sets RVDelaySec
+	TO2FailureDelay time.Duration // delay after a failed TO2, unless the directive sets RVDelaySec
+	Multiplier      float64       // growth of the delay for each failed pass over all directives
+	MaxDelay        time.Duration // cap of the grown delay, never below the directive delay (0=no cap)
+
+	clock  retryClock
+	jitter func(time.Duration) time.Duration
+	random func() float64
+}
+
+func newRetryPolicy(o OnboardConfig) *retryPolicy {
+	return &retryPolicy{
+		StartDelay:      o.StartDelay,
+		TO1FailureDelay: o.TO1FailureDelay,
+		TO2FailureDelay: o.TO2FailureDelay,
+		Multiplier:      o.BackoffMultiplier,
+		MaxDelay:        o.MaxDelay,
+		clock:           systemClock{},
+		jitter:          addJitter,
+		random:          rand.Float64,
+	}
+}
+
+// initialDelay returns a random delay of up to StartDelay, spreading the
+// first attempts of devices that come online at the same time.
+func (p *retryPolicy) initialDelay() time.Duration {
+	if p.StartDelay <= 0 {
+		return 0
+	}
+	return time.Duration(p.random() * float64(p.StartDelay))
+}
+
+// delay returns the delay, before jitter, after a failed attempt of a
+// directive during the given pass over all directives, counting from 0.
+func (p *retryPolicy) delay(directive *protocol.RvDirective, isLastDirective bool, phase retryPhase, pass int) time.Duration {
+	// RVDelaySec from the voucher always takes precedence over the policy
+	base := directive.Delay
+	if base == 0 {
+		switch {
+		case phase == retryPhaseTO1 && p.TO1FailureDelay > 0:
+			base = p.TO1FailureDelay
+		case phase == retryPhaseTO2 && p.TO2FailureDelay > 0:
+			base = p.TO2FailureDelay
+		default:
+			base = directiveDelay(directive, isLastDirective)
+		}
+	}
+	if base == 0 || p.Multiplier <= 1 || pass == 0 {
+		return base
+	}
+
+	limit := time.Duration(math.MaxInt64)
+	if p.MaxDelay > 0 {
+		limit = max(p.MaxDelay, base)
+	}
+	grown := float64(base) * math.Pow(p.Multiplier, float64(pass))
+	if grown >= float64(limit) {
+		return limit
+	}
+	return time.Duration(grown)
+}
+
+// getOwnerURLs performs TO1 protocol to discover Owner URLs or uses RV bypass,
+// logging to log.
+// Returns: owner URLs, TO1 response (needed for TO2), last TO1 error
+func getOwnerURLs(ctx context.Context, log *slog.Logger, directive *protocol.RvDirective, conf fdo.TO2Config) ([]string, *cose.Sign1[protocol.To1d, []byte], error) {
 	var to1d *cose.Sign1[protocol.To1d, []byte]
 	var ownerURLs []string
 
 	// RV bypass: Use Owner URLs directly from directive, skipping TO1
 	if directive.Bypass {
-		slog.Info("RV bypass enabled, skipping TO1 protocol")
+		log.Info("RV bypass enabled, skipping TO1 protocol")
 		for _, url := range directive.URLs {
 			ownerURLs = append(ownerURLs, url.String())
-			slog.Info("Using Owner URL from bypass directive", "url", url.String())
+			log.Info("Using Owner URL from bypass directive", "url", url.String())
 		}
-		return ownerURLs, nil
+		return ownerURLs, nil, nil
 	}
 
 	// Normal flow: Contact Rendezvous server via TO1 to discover Owner address
-	slog.Info("Attempting TO1 protocol")
+	log = log.With("phase", retryPhaseTO1.String())
+	log.Info("Attempting TO1 protocol")
+	var to1Err error
 	for _, url := range directive.URLs {
 		var err error
-		to1d, err = fdo.TO1(ctx, tls.TlsTransport(url.String(), nil, onboardConfig.Onboard.InsecureTLS), conf.Cred, conf.Key, nil)
+		to1d, err = fdo.TO1(ctx, to1Transport(url.String()), conf.Cred, conf.Key, nil)
 		if err != nil {
-			slog.Error("TO1 failed", "base URL", url.String(), "error", err)
+			log.Error("TO1 failed", "url", url.String(), "error", err)
+			to1Err = fmt.Errorf("TO1 with %s failed: %w", url, err)
 			continue
 		}
-		slog.Info("TO1 succeeded", "base URL", url.String())
+		log.Info("TO1 succeeded", "url", url.String())
 		break
 	}
 
 	// Check if all TO1 attempts failed
 	// Note: Empty URLs is valid (delay-only directive), individual failures already logged in loop
 	if to1d == nil {
-		slog.Info("All TO1 attempts failed for this directive")
-		return nil, nil // Return empty URLs - will skip TO2
+		log.Info("All TO1 attempts failed for this directive")
+		return nil, nil, to1Err // Return empty URLs - will skip TO2
 	}
 
 	// TO1 succeeded - extract TO2 URLs from response
 	for _, to2Addr := range to1d.Payload.Val.RV {
 		if to2Addr.DNSAddress == nil && to2Addr.IPAddress == nil {
-			slog.Error("Both IP and DNS can't be null")
+			log.Error("Both IP and DNS can't be null")
 			continue
 		}
 
@@ -262,8 +503,10 @@ func getOwnerURLs(ctx context.Context, directive *protocol.RvDirective, conf fdo
 			scheme, port = "http://", "80"
 		case protocol.HTTPSTransport:
 			scheme, port = "https://", "443"
+		case protocol.CoAPTransport:
+			scheme, port = "coap://", coap.DefaultPort
 		default:
-			slog.Error("Unsupported transport protocol", "transport protocol", to2Addr.TransportProtocol)
+			log.Error("Unsupported transport protocol", "transport_protocol", to2Addr.TransportProtocol)
 			continue
 		}
 		if to2Addr.Port != 0 {
@@ -276,7 +519,7 @@ func getOwnerURLs(ctx context.Context, directive *protocol.RvDirective, conf fdo
 				host := *to2Addr.DNSAddress
 				ownerURLs = append(ownerURLs, scheme+net.JoinHostPort(host, port))
 			} else {
-				slog.Warn("DNS address is not resolvable", "dns", *to2Addr.DNSAddress)
+				log.Warn("DNS address is not resolvable", "host", *to2Addr.DNSAddress)
 			}
 		}
 
@@ -286,7 +529,7 @@ func getOwnerURLs(ctx context.Context, directive *protocol.RvDirective, conf fdo
 				host := to2Addr.IPAddress.String()
 				ownerURLs = append(ownerURLs, scheme+net.JoinHostPort(host, port))
 			} else {
-				slog.Warn("IP address is not valid", "ip", to2Addr.IPAddress.String())
+				log.Warn("IP address is not valid", "host", to2Addr.IPAddress.String())
 			}
 		}
 	}
@@ -294,70 +537,115 @@ func getOwnerURLs(ctx context.Context, directive *protocol.RvDirective, conf fdo
 	// Check if TO1 succeeded but returned no valid TO2 addresses
 	// This is unexpected but valid (manufacturer may have configured device oddly)
 	if len(ownerURLs) == 0 {
-		slog.Info("TO1 succeeded but no valid TO2 addresses found")
+		log.Info("TO1 succeeded but no valid TO2 addresses found")
+		return nil, to1d, errors.New("TO1 succeeded but returned no valid TO2 addresses")
 	}
 
-	return ownerURLs, to1d
+	return ownerURLs, to1d, nil
 }
 
-func transferOwnership(ctx context.Context, rvInfo [][]protocol.RvInstruction, conf fdo.TO2Config) (*fdo.DeviceCredential, error) { //nolint:gocyclo
+func transferOwnership(ctx context.Context, rvInfo [][]protocol.RvInstruction, conf fdo.TO2Config, policy *retryPolicy, progress *onboardProgress) (*fdo.DeviceCredential, error) { //nolint:gocyclo
 	directives := protocol.ParseDeviceRvInfo(rvInfo)
 
 	if len(directives) == 0 {
 		return nil, errors.New("no rendezvous information found that's usable for the device")
 	}
 
-	// Infinite retry loop - continues until onboarding succeeds or context canceled
-	for {
-		for i, directive := range directives {
+	log := slog.With("guid", hex.EncodeToString(conf.Cred.GUID[:]))
+	if delay := policy.initialDelay(); delay > 0 {
+		log.Info("Applying randomized start delay", "delay", delay)
+		progress.report("Waiting %s before the first attempt", delay)
+		if err := progress.wait(ctx, policy.clock, delay); err != nil {
+			return nil, err
+		}
+	}
+
+	// Retry loop - continues until onboarding succeeds, the context is canceled
+	// or the configured number of attempts or passes is exhausted. A restarted
+	// client resumes after the directive of the last recorded attempt.
+	pass, start := progress.resumePoint(len(directives))
+	attempt := 0
+	for ; ; pass++ {
+		for i := start; i < len(directives); i++ {
+			directive := directives[i]
+			progress.setIdle(false)
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
 			isLastDirective := (i == len(directives)-1)
+			attempt++
+			dlog := log.With("directive", i)
+			dlog.Info("Trying rendezvous directive", "attempt", attempt, "total_attempts", progress.Attempts+1)
 
 			// Step 1: Get Owner URLs (via TO1 or RV bypass)
-			ownerURLs, to1d := getOwnerURLs(ctx, &directive, conf)
+			if !directive.Bypass {
+				progress.report("TO1 with directive %d (attempt %d)", i, progress.Attempts+1)
+			}
+			ownerURLs, to1d, attemptErr := getOwnerURLs(ctx, dlog, &directive, conf)
+			if replayEnded(attemptErr) {
+				return nil, attemptErr
+			}
 
 			// Step 2: Attempt TO2 with each Owner URL
 			// Note: If TO1 failed, ownerURLs is empty and loop is skipped
+			to2log := dlog.With("phase", retryPhaseTO2.String())
 			if len(ownerURLs) > 0 {
-				slog.Info("Attempting TO2 protocol")
+				to2log.Info("Attempting TO2 protocol")
 			}
 			for j, baseURL := range ownerURLs {
 				isLastURL := (j == len(ownerURLs)-1)
-				newDC, err := transferOwnership2(ctx, tls.TlsTransport(baseURL, nil, onboardConfig.Onboard.InsecureTLS), to1d, conf)
+				progress.report("TO2 with %s from directive %d (attempt %d)", baseURL, i, progress.Attempts+1)
+				newDC, err := transferOwnership2(ctx, to2Transport(baseURL), to1d, conf)
 				if newDC != nil {
-					slog.Info("TO2 succeeded", "base URL", baseURL)
+					to2log.Info("TO2 succeeded", "url", baseURL)
 					return newDC, nil
 				}
-				slog.Error("TO2 failed", "base URL", baseURL, "error", err)
+				to2log.Error("TO2 failed", "url", baseURL, "error", err)
+				attemptErr = fmt.Errorf("TO2 with %s failed: %w", baseURL, err)
+				if replayEnded(err) {
+					return nil, attemptErr
+				}
 
 				// Apply configurable delay between Owner URLs within a directive
 				// (not spec-compliant, but prevents hammering the same server via different URLs)
 				if !isLastURL && onboardConfig.Onboard.TO2RetryDelay > 0 {
-					slog.Info("Applying TO2 retry delay", "delay", onboardConfig.Onboard.TO2RetryDelay)
-					if err := applyDelay(ctx, onboardConfig.Onboard.TO2RetryDelay); err != nil {
+					to2log.Info("Applying TO2 retry delay", "delay", onboardConfig.Onboard.TO2RetryDelay)
+					if err := progress.wait(ctx, policy.clock, onboardConfig.Onboard.TO2RetryDelay); err != nil {
 						return nil, err
 					}
 				}
 			}
 
+			progress.recordAttempt(pass, i, attemptErr)
+			progress.setIdle(true)
+
+			// Give up without waiting once the configured bounds are reached
+			if maxAttempts := onboardConfig.Onboard.MaxAttempts; maxAttempts > 0 && attempt >= maxAttempts {
+				log.Info("Giving up onboarding", "attempts", attempt)
+				return nil, errMaxAttempts
+			}
+			if onboardConfig.Onboard.Once && attempt >= len(directives) {
+				log.Info("Giving up onboarding after a single pass", "attempts", attempt)
+				return nil, errOnce
+			}
+
 			// Step 3: Apply delay after directive attempts (TO1 failed or all TO2 URLs failed)
 			// IMPORTANT: Delay applies even with zero URLs (allows RVDelaySec-only directives)
-			if directive.Delay != 0 {
-				// Use configured delay from directive
-				delay := addJitter(directive.Delay)
-				slog.Info("Applying directive delay", "delay", delay)
-				if err := applyDelay(ctx, delay); err != nil {
-					return nil, err
-				}
-			} else if isLastDirective {
-				// Last directive with no configured delay - apply default
-				delay := addJitter(120 * time.Second)
-				slog.Info("Applying default delay for last directive", "delay", delay)
-				if err := applyDelay(ctx, delay); err != nil {
+			// A directive with no delay continues to the next directive
+			phase := retryPhaseTO1
+			if len(ownerURLs) > 0 {
+				phase = retryPhaseTO2
+			}
+			if delay := policy.delay(&directive, isLastDirective, phase, pass); delay != 0 {
+				delay = policy.jitter(delay)
+				dlog.Info("Applying retry delay", "delay", delay, "phase", phase.String(), "pass", pass)
+				progress.report("Waiting %s after %s with directive %d failed (%d failed attempts)", delay.Round(time.Second), phase, i, progress.Attempts)
+				if err := progress.wait(ctx, policy.clock, delay); err != nil {
 					return nil, err
 				}
 			}
-			// Non-last directive with no delay - continue to next directive
 		}
+		start = 0
 	}
 }
 
@@ -544,22 +832,37 @@ func (o *OnboardClientConfig) validate() error {
 		}
 		o.Onboard.DefaultWorkingDir = cwd
 	}
+	if err := validateWorkingDir(o.Onboard.DefaultWorkingDir); err != nil {
+		return err
+	}
+
+	return o.validateOptions()
+}
+
+// validateWorkingDir checks that the default working directory is an
+// absolute path to an existing, writable directory.
+func validateWorkingDir(dir string) error {
 	// Validate default working directory is an absolute path
-	if !filepath.IsAbs(o.Onboard.DefaultWorkingDir) {
-		return fmt.Errorf("default-working-dir must be an absolute path, got: %s", o.Onboard.DefaultWorkingDir)
+	if !filepath.IsAbs(dir) {
+		return fmt.Errorf("default-working-dir must be an absolute path, got: %s", dir)
 	}
 	// Validate directory exists and is writable
-	if !fileExists(o.Onboard.DefaultWorkingDir) {
-		return fmt.Errorf("invalid default working directory: %s", o.Onboard.DefaultWorkingDir)
+	if !fileExists(dir) {
+		return fmt.Errorf("invalid default working directory: %s", dir)
 	}
 	// Test writability using CreateTemp
-	tempFile, err := os.CreateTemp(o.Onboard.DefaultWorkingDir, ".fdo.test_*")
+	tempFile, err := os.CreateTemp(dir, ".fdo.test_*")
 	if err != nil {
 		return fmt.Errorf("default working directory is not writable: %w", err)
 	}
 	tempFile.Close()
 	os.Remove(tempFile.Name())
+	return nil
+}
 
+// validateOptions validates the onboard options that do not depend on the
+// host the configuration is validated on.
+func (o *OnboardClientConfig) validateOptions() error {
 	if o.Key == "" {
 		return fmt.Errorf("--key is required (via CLI flag or config file)")
 	}
@@ -580,10 +883,42 @@ func (o *OnboardClientConfig) validate() error {
 			o.Onboard.Kex, strings.Join(validKexSuites, ", "))
 	}
 
+	if o.Onboard.MaxAttempts < 0 {
+		return fmt.Errorf("max-attempts must not be negative")
+	}
+	if o.Onboard.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative")
+	}
+	if o.Onboard.StartDelay < 0 || o.Onboard.TO1FailureDelay < 0 || o.Onboard.TO2FailureDelay < 0 || o.Onboard.MaxDelay < 0 {
+		return fmt.Errorf("start-delay, to1-failure-delay, to2-failure-delay and max-delay must not be negative")
+	}
+	if o.Onboard.BackoffMultiplier < 1 {
+		return fmt.Errorf("backoff-multiplier must be at least 1, got: %g", o.Onboard.BackoffMultiplier)
+	}
+	if o.Onboard.BackoffMultiplier > 1 && o.Onboard.MaxDelay == 0 {
+		return fmt.Errorf("max-delay is required with a backoff-multiplier above 1")
+	}
+
 	if o.Onboard.MaxServiceInfoSize < 0 || o.Onboard.MaxServiceInfoSize > math.MaxUint16 {
 		return fmt.Errorf("max-serviceinfo-size must be between 0 and %d", math.MaxUint16)
 	}
 
+	if err := validateTLSOptions(o.Onboard.to1TLSOptions()); err != nil {
+		return fmt.Errorf("TO1: %w", err)
+	}
+	if err := validateTLSOptions(o.Onboard.to2TLSOptions()); err != nil {
+		return fmt.Errorf("TO2: %w", err)
+	}
+	if err := validateClientCert(o.Onboard.TLSClientCert, o.Onboard.TLSClientKey, o.TPM != ""); err != nil {
+		return err
+	}
+	if err := o.Onboard.TransportConfig.validate(); err != nil {
+		return err
+	}
+	if _, err := o.Onboard.faults(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
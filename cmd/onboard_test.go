@@ -4,12 +4,30 @@
 package cmd
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"math"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/fido-device-onboard/go-fdo"
+	"github.com/fido-device-onboard/go-fdo-client/internal/chaos"
+	"github.com/fido-device-onboard/go-fdo-client/internal/coap/coaptest"
+	"github.com/fido-device-onboard/go-fdo/cbor"
+	"github.com/fido-device-onboard/go-fdo/cose"
 	"github.com/fido-device-onboard/go-fdo/fsim"
+	"github.com/fido-device-onboard/go-fdo/protocol"
 )
 
 // TestFSIMsEnabledByDefault verifies that all standard FSIMs are enabled
@@ -375,3 +393,385 @@ func TestCommandModuleAlwaysEnabled(t *testing.T) {
 		})
 	}
 }
+
+// unreachableRvInfo returns n rendezvous directives pointing at a closed port
+// with the given delay in seconds.
+func unreachableRvInfo(t *testing.T, n int, delaySecs uint32) [][]protocol.RvInstruction {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedURL := "http://" + l.Addr().String()
+	_ = l.Close()
+
+	var rvInfo [][]protocol.RvInstruction
+	for range n {
+		var extra []protocol.RvInstruction
+		if delaySecs > 0 {
+			extra = append(extra, testRvInstruction(t, protocol.RVDelaysec, delaySecs))
+		}
+		rvInfo = append(rvInfo, testRvDirective(t, closedURL, protocol.RVProtHTTP, extra...))
+	}
+	return rvInfo
+}
+
+// TestTransferOwnershipBounds verifies that bounded onboarding runs give up
+// with their own error and exit code instead of retrying forever.
+func TestTransferOwnershipBounds(t *testing.T) {
+	dc := newTestBlobCred(t, FDO_STATE_PRE_TO1)
+	conf := fdo.TO2Config{Cred: dc.DC.DeviceCredential, Key: dc.DC.PrivateKey.Signer}
+
+	tests := []struct {
+		name     string
+		config   OnboardConfig
+		rvInfo   [][]protocol.RvInstruction
+		timeout  time.Duration
+		wantErr  error
+		wantCode int
+	}{
+		{
+			name:     "max attempts",
+			config:   OnboardConfig{MaxAttempts: 2},
+			rvInfo:   unreachableRvInfo(t, 3, 0),
+			wantErr:  errMaxAttempts,
+			wantCode: onboardExitMaxAttempts,
+		},
+		{
+			name:     "once",
+			config:   OnboardConfig{Once: true},
+			rvInfo:   unreachableRvInfo(t, 3, 0),
+			wantErr:  errOnce,
+			wantCode: onboardExitOnce,
+		},
+		{
+			name:     "timeout",
+			rvInfo:   unreachableRvInfo(t, 1, 60),
+			timeout:  100 * time.Millisecond,
+			wantErr:  context.DeadlineExceeded,
+			wantCode: onboardExitTimeout,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			onboardConfig = OnboardClientConfig{Onboard: tt.config}
+			t.Cleanup(func() { onboardConfig = OnboardClientConfig{} })
+
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+
+			done := make(chan error, 1)
+			go func() {
+				_, err := transferOwnership(ctx, tt.rvInfo, conf, newRetryPolicy(tt.config), &onboardProgress{})
+				done <- err
+			}()
+			select {
+			case err := <-done:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("transferOwnership error = %v, want %v", err, tt.wantErr)
+				}
+				if got := ExitCode(onboardExitError(err)); got != tt.wantCode {
+					t.Errorf("exit code = %d, want %d", got, tt.wantCode)
+				}
+			case <-time.After(10 * time.Second):
+				t.Fatal("transferOwnership did not give up")
+			}
+		})
+	}
+}
+
+// fakeClock records requested delays and fires immediately.
+type fakeClock struct {
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+func newTestRetryPolicy(o OnboardConfig) (*retryPolicy, *fakeClock) {
+	clock := &fakeClock{}
+	policy := newRetryPolicy(o)
+	policy.clock = clock
+	policy.jitter = func(d time.Duration) time.Duration { return d }
+	policy.random = func() float64 { return 0.5 }
+	return policy, clock
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	rvDelay := &protocol.RvDirective{Delay: 30 * time.Second}
+	noDelay := &protocol.RvDirective{}
+
+	tests := []struct {
+		name      string
+		config    OnboardConfig
+		directive *protocol.RvDirective
+		isLast    bool
+		phase     retryPhase
+		pass      int
+		want      time.Duration
+	}{
+		{"defaults, not last", OnboardConfig{}, noDelay, false, retryPhaseTO1, 0, 0},
+		{"defaults, last", OnboardConfig{}, noDelay, true, retryPhaseTO1, 0, defaultLastDirectiveDelay},
+		{"defaults, RVDelaySec", OnboardConfig{}, rvDelay, false, retryPhaseTO1, 5, 30 * time.Second},
+		{"TO1 failure delay", OnboardConfig{TO1FailureDelay: 10 * time.Second, TO2FailureDelay: time.Minute}, noDelay, false, retryPhaseTO1, 0, 10 * time.Second},
+		{"TO2 failure delay", OnboardConfig{TO1FailureDelay: 10 * time.Second, TO2FailureDelay: time.Minute}, noDelay, true, retryPhaseTO2, 0, time.Minute},
+		{"RVDelaySec wins over phase delay", OnboardConfig{TO1FailureDelay: 10 * time.Second}, rvDelay, false, retryPhaseTO1, 0, 30 * time.Second},
+		{"backoff", OnboardConfig{BackoffMultiplier: 2}, rvDelay, false, retryPhaseTO1, 2, 120 * time.Second},
+		{"backoff capped", OnboardConfig{BackoffMultiplier: 2, MaxDelay: 100 * time.Second}, rvDelay, false, retryPhaseTO1, 2, 100 * time.Second},
+		{"cap below RVDelaySec", OnboardConfig{BackoffMultiplier: 2, MaxDelay: 10 * time.Second}, rvDelay, false, retryPhaseTO1, 3, 30 * time.Second},
+		{"backoff without cap does not overflow", OnboardConfig{BackoffMultiplier: 10}, rvDelay, false, retryPhaseTO1, 1000, time.Duration(math.MaxInt64)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, _ := newTestRetryPolicy(tt.config)
+			if got := policy.delay(tt.directive, tt.isLast, tt.phase, tt.pass); got != tt.want {
+				t.Errorf("delay = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddJitter(t *testing.T) {
+	for range 100 {
+		if got := addJitter(time.Minute); got < 45*time.Second || got > 75*time.Second {
+			t.Fatalf("addJitter(1m) = %v, want within ±25%%", got)
+		}
+		if got := addJitter(math.MaxInt64); got < math.MaxInt64*3/4 {
+			t.Fatalf("addJitter(max) = %v, want no overflow", got)
+		}
+	}
+}
+
+func TestRetryPolicyInitialDelay(t *testing.T) {
+	policy, _ := newTestRetryPolicy(OnboardConfig{})
+	if got := policy.initialDelay(); got != 0 {
+		t.Errorf("initialDelay without start-delay = %v, want 0", got)
+	}
+	policy, _ = newTestRetryPolicy(OnboardConfig{StartDelay: time.Minute})
+	if got, want := policy.initialDelay(), 30*time.Second; got != want {
+		t.Errorf("initialDelay = %v, want %v", got, want)
+	}
+}
+
+// TestTransferOwnershipRetryPolicy walks the retry loop with a fake clock and
+// checks the sequence of delays it waits for.
+func TestTransferOwnershipRetryPolicy(t *testing.T) {
+	dc := newTestBlobCred(t, FDO_STATE_PRE_TO1)
+	conf := fdo.TO2Config{Cred: dc.DC.DeviceCredential, Key: dc.DC.PrivateKey.Signer}
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Directive 0 sets RVDelaySec=30 and fails TO1, directive 1 is a bypass
+	// directive that fails TO2
+	rvInfo := unreachableRvInfo(t, 2, 0)
+	rvInfo[0] = append(rvInfo[0], testRvInstruction(t, protocol.RVDelaysec, uint32(30)))
+	rvInfo[1] = append(rvInfo[1], testRvInstruction(t, protocol.RVBypass, nil))
+
+	config := OnboardConfig{
+		DefaultWorkingDir: cwd,
+		MaxAttempts:       6,
+		StartDelay:        10 * time.Second,
+		TO1FailureDelay:   5 * time.Second,
+		TO2FailureDelay:   10 * time.Second,
+		BackoffMultiplier: 2,
+		MaxDelay:          50 * time.Second,
+	}
+	onboardConfig = OnboardClientConfig{Onboard: config}
+	t.Cleanup(func() { onboardConfig = OnboardClientConfig{} })
+
+	policy, clock := newTestRetryPolicy(config)
+	if _, err := transferOwnership(context.Background(), rvInfo, conf, policy, &onboardProgress{}); !errors.Is(err, errMaxAttempts) {
+		t.Fatalf("transferOwnership error = %v, want %v", err, errMaxAttempts)
+	}
+
+	want := []time.Duration{
+		5 * time.Second,                    // random start delay
+		30 * time.Second, 10 * time.Second, // pass 0: RVDelaySec, TO2 failure delay
+		50 * time.Second, 20 * time.Second, // pass 1: capped backoff, backoff
+		50 * time.Second, // pass 2: capped; gives up after the last attempt without waiting
+	}
+	if !slices.Equal(clock.sleeps, want) {
+		t.Errorf("delays = %v, want %v", clock.sleeps, want)
+	}
+}
+
+// TestTransferOwnershipFaults runs the retry loop against injected faults
+// instead of unreachable servers.
+func TestTransferOwnershipFaults(t *testing.T) {
+	dc := newTestBlobCred(t, FDO_STATE_PRE_TO1)
+	conf := fdo.TO2Config{Cred: dc.DC.DeviceCredential, Key: dc.DC.PrivateKey.Signer}
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		rvInfo   [][]protocol.RvInstruction
+		config   OnboardConfig
+		wantHits []int
+		want     []time.Duration
+		wantErr  string
+	}{
+		{
+			name: "TO1 HTTP error honors RVDelaySec",
+			rvInfo: [][]protocol.RvInstruction{
+				testRvDirective(t, "http://192.0.2.1:8041", protocol.RVProtHTTP,
+					testRvInstruction(t, protocol.RVDelaysec, uint32(30))),
+			},
+			config: OnboardConfig{
+				MaxAttempts: 2,
+				Faults: []FaultConfig{
+					{MsgType: 30, URL: "192.0.2.1", Action: "http-error", Status: 500},
+				},
+			},
+			wantHits: []int{2},
+			want:     []time.Duration{30 * time.Second},
+			wantErr:  "500 Internal Server Error",
+		},
+		{
+			name: "TO2 retry delay between owner URLs",
+			rvInfo: [][]protocol.RvInstruction{
+				testRvDirective(t, "http://192.0.2.2:8043", protocol.RVProtHTTP,
+					testRvInstruction(t, protocol.RVDns, "owner.example"),
+					testRvInstruction(t, protocol.RVBypass, nil)),
+			},
+			config: OnboardConfig{
+				MaxAttempts:     2,
+				TO2RetryDelay:   3 * time.Second,
+				TO2FailureDelay: 10 * time.Second,
+				Faults: []FaultConfig{
+					{URL: "owner.example", Action: "dns-error"},
+					{MsgType: 60, URL: "192.0.2.2", Action: "http-error", Status: 503},
+				},
+			},
+			wantHits: []int{2, 2},
+			want: []time.Duration{
+				3 * time.Second, 10 * time.Second, // pass 0: TO2 retry delay, TO2 failure delay
+				3 * time.Second, // pass 1: gives up after the last attempt without waiting
+			},
+			wantErr: "503 Service Unavailable",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.DefaultWorkingDir = cwd
+			onboardConfig = OnboardClientConfig{Onboard: tt.config}
+			t.Cleanup(func() {
+				onboardConfig = OnboardClientConfig{}
+				faultInjector = nil
+			})
+			faults, err := tt.config.faults()
+			if err != nil {
+				t.Fatal(err)
+			}
+			faultInjector = chaos.New(faults)
+
+			policy, clock := newTestRetryPolicy(tt.config)
+			progress := &onboardProgress{}
+			if _, err := transferOwnership(context.Background(), tt.rvInfo, conf, policy, progress); !errors.Is(err, errMaxAttempts) {
+				t.Fatalf("transferOwnership error = %v, want %v", err, errMaxAttempts)
+			}
+			if got := faultInjector.Hits(); !slices.Equal(got, tt.wantHits) {
+				t.Errorf("fault hits = %v, want %v", got, tt.wantHits)
+			}
+			if !slices.Equal(clock.sleeps, tt.want) {
+				t.Errorf("delays = %v, want %v", clock.sleeps, tt.want)
+			}
+			if !strings.Contains(progress.LastError, tt.wantErr) {
+				t.Errorf("last error = %q, want %q", progress.LastError, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestTransferOwnership_CoAP runs TO1 over CoAP against a stand-in
+// rendezvous server redirecting to a CoAP owner server, which fails TO2.
+func TestTransferOwnership_CoAP(t *testing.T) {
+	dc := newTestBlobCred(t, FDO_STATE_PRE_TO1)
+	conf := fdo.TO2Config{Cred: dc.DC.DeviceCredential, Key: dc.DC.PrivateKey.Signer}
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ownerKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var port atomic.Uint32 // set once the server listens
+	var msgTypes []uint8
+	server := coaptest.NewServer(func(req *coaptest.Request) *coaptest.Response {
+		msgTypes = append(msgTypes, req.MsgType)
+		var body any
+		switch req.MsgType {
+		case protocol.TO1HelloRVMsgType:
+			var hello struct {
+				GUID    protocol.GUID
+				SigInfo cbor.RawBytes
+			}
+			if err := cbor.Unmarshal(req.Body, &hello); err != nil {
+				t.Errorf("TO1.HelloRV: %v", err)
+			}
+			body = struct {
+				Nonce   protocol.Nonce
+				SigInfo cbor.RawBytes
+			}{SigInfo: hello.SigInfo}
+		case protocol.TO1ProveToRVMsgType:
+			ip := net.IPv4(127, 0, 0, 1)
+			redirect := cose.Sign1[protocol.To1d, []byte]{Payload: cbor.NewByteWrap(protocol.To1d{
+				RV:       []protocol.RvTO2Addr{{IPAddress: &ip, Port: uint16(port.Load()), TransportProtocol: protocol.CoAPTransport}},
+				To0dHash: protocol.Hash{Algorithm: protocol.Sha256Hash, Value: make([]byte, 32)},
+			})}
+			if err := redirect.Sign(ownerKey, nil, nil, nil); err != nil {
+				t.Errorf("signing TO1.RVRedirect: %v", err)
+			}
+			body = redirect.Tag()
+		default:
+			body = protocol.ErrorMessage{Code: protocol.ResourceNotFound, PrevMsgType: req.MsgType, ErrString: "unknown voucher"}
+			data, _ := cbor.Marshal(body)
+			return &coaptest.Response{MsgType: protocol.ErrorMsgType, Body: data}
+		}
+		data, err := cbor.Marshal(body)
+		if err != nil {
+			t.Errorf("encoding response to message %d: %v", req.MsgType, err)
+		}
+		return &coaptest.Response{MsgType: req.MsgType + 1, Body: data}
+	})
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, _ := strconv.ParseUint(u.Port(), 10, 16)
+	port.Store(uint32(p))
+
+	config := OnboardConfig{DefaultWorkingDir: cwd, MaxAttempts: 1}
+	onboardConfig = OnboardClientConfig{Onboard: config}
+	t.Cleanup(func() { onboardConfig = OnboardClientConfig{} })
+
+	rvInfo := [][]protocol.RvInstruction{testRvDirective(t, server.URL, protocol.RVProtCoapUDP)}
+	progress := &onboardProgress{}
+	if _, err := transferOwnership(context.Background(), rvInfo, conf, newRetryPolicy(config), progress); !errors.Is(err, errMaxAttempts) {
+		t.Fatalf("transferOwnership error = %v, want %v", err, errMaxAttempts)
+	}
+	server.Close()
+
+	if want := []uint8{30, 32, 60}; !slices.Equal(msgTypes, want) {
+		t.Errorf("server received messages %v, want %v", msgTypes, want)
+	}
+	if want := "TO2 with " + server.URL + " failed"; !strings.Contains(progress.LastError, want) || !strings.Contains(progress.LastError, "unknown voucher") {
+		t.Errorf("last error = %q, want %q with the owner's error message", progress.LastError, want)
+	}
+}
@@ -4,32 +4,107 @@
 package cmd
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
 	"fmt"
-	"log/slog"
+	"io"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 
+	"github.com/fido-device-onboard/go-fdo"
 	"github.com/fido-device-onboard/go-fdo-client/internal/tpm_utils"
+	"github.com/fido-device-onboard/go-fdo/cbor"
+	"github.com/fido-device-onboard/go-fdo/cbor/cdn"
+	"github.com/fido-device-onboard/go-fdo/protocol"
+	"github.com/fido-device-onboard/go-fdo/tpm"
 	"github.com/spf13/cobra"
+	"go.yaml.in/yaml/v3"
 )
 
+const redacted = "<redacted>"
+
+var validPrintOutputs = []string{"text", "json", "yaml", "cbor-diag"}
+
+// credentialReport is the stable, printable schema of a stored device
+// credential. Secrets are only populated when explicitly requested.
+type credentialReport struct {
+	State         string              `json:"state" yaml:"state"`
+	Storage       string              `json:"storage" yaml:"storage"`
+	Version       uint16              `json:"version" yaml:"version"`
+	DeviceInfo    string              `json:"device_info" yaml:"device_info"`
+	GUID          string              `json:"guid" yaml:"guid"`
+	PublicKeyHash hashReport          `json:"public_key_hash" yaml:"public_key_hash"`
+	RvInfo        []rvDirectiveReport `json:"rv_info" yaml:"rv_info"`
+	DeviceKey     deviceKeyReport     `json:"device_key" yaml:"device_key"`
+	HmacSecret    string              `json:"hmac_secret,omitempty" yaml:"hmac_secret,omitempty"`
+	Progress      *onboardProgress    `json:"onboarding_progress,omitempty" yaml:"onboarding_progress,omitempty"`
+}
+
+type hashReport struct {
+	Algorithm string `json:"algorithm" yaml:"algorithm"`
+	Value     string `json:"value" yaml:"value"`
+}
+
+type deviceKeyReport struct {
+	Type       string  `json:"type" yaml:"type"`
+	Handle     *uint32 `json:"handle,omitempty" yaml:"handle,omitempty"`
+	PrivateKey string  `json:"private_key,omitempty" yaml:"private_key,omitempty"`
+}
+
+type rvDirectiveReport struct {
+	URLs           []string    `json:"urls" yaml:"urls"`
+	Bypass         bool        `json:"bypass" yaml:"bypass"`
+	DelaySeconds   int64       `json:"delay_seconds" yaml:"delay_seconds"`
+	EthIface       *uint8      `json:"eth_iface,omitempty" yaml:"eth_iface,omitempty"`
+	WlanIface      *uint8      `json:"wlan_iface,omitempty" yaml:"wlan_iface,omitempty"`
+	WlanSSID       string      `json:"wlan_ssid,omitempty" yaml:"wlan_ssid,omitempty"`
+	WlanPass       string      `json:"wlan_password,omitempty" yaml:"wlan_password,omitempty"`
+	ExtMechanism   string      `json:"ext_mechanism,omitempty" yaml:"ext_mechanism,omitempty"`
+	ServerCertHash *hashReport `json:"server_cert_hash,omitempty" yaml:"server_cert_hash,omitempty"`
+	ServerCAHash   *hashReport `json:"server_ca_hash,omitempty" yaml:"server_ca_hash,omitempty"`
+}
+
 var printCmd = &cobra.Command{
 	Use:   "print",
 	Short: "Print device credentials",
 	Long: `Print the contents of the device's credential store.
 
 The device credentials are read from either a file (--blob) or a TPM device (--tpm)
-and printed to standard output.`,
+and printed to standard output in a stable schema containing the GUID, device
+info, manufacturer public key hash, decoded rendezvous directives, device
+state and persisted onboarding progress. The HMAC secret, private key and Wi-Fi passwords are redacted unless
+--show-secrets is given.`,
 	Example: `  # Print credentials from a blob file:
   go-fdo-client print --blob cred.bin
 
-  # Print credentials from a TPM:
-  go-fdo-client print --tpm /dev/tpmrm0`,
+  # Print credentials from a TPM as JSON:
+  go-fdo-client print --tpm /dev/tpmrm0 --output json
+
+  # Print the stored CBOR in diagnostic notation, including secrets:
+  go-fdo-client print --blob cred.bin --output cbor-diag --show-secrets`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if rootConfig.Debug {
-			level.Set(slog.LevelDebug)
+		output, err := cmd.Flags().GetString("output")
+		if err != nil {
+			return err
 		}
+		if !slices.Contains(validPrintOutputs, output) {
+			return fmt.Errorf("invalid output format: '%s', options [%s]", output, strings.Join(validPrintOutputs, ", "))
+		}
+		showSecrets, err := cmd.Flags().GetBool("show-secrets")
+		if err != nil {
+			return err
+		}
+
+		var report *credentialReport
+		var stored any
 		if rootConfig.TPM != "" {
-			var err error
 			tpmc, err = tpm_utils.TpmOpen(rootConfig.TPM)
 			if err != nil {
 				return err
@@ -39,7 +114,7 @@ and printed to standard output.`,
 			if err = readTpmCred(&tpmCred); err != nil {
 				return fmt.Errorf("failed to read credential from TPM: %w", err)
 			}
-			fmt.Printf("%+v\n", tpmCred)
+			report, stored = newTpmCredentialReport(tpmCred, showSecrets), tpmCred
 		} else {
 			if !isValidPath(rootConfig.Blob) {
 				return fmt.Errorf("invalid blob file path: %s", rootConfig.Blob)
@@ -48,14 +123,21 @@ and printed to standard output.`,
 			if err := readCredFile(&fileCred); err != nil {
 				return fmt.Errorf("failed to read credential from file: %w", err)
 			}
-			fmt.Printf("%+v\n", fileCred)
+			report, stored = newBlobCredentialReport(fileCred, showSecrets), fileCred
 		}
-		return nil
+
+		return writeCredentialReport(cmd.OutOrStdout(), output, report, stored, showSecrets)
 	},
 }
 
-func init() {
+func printCmdInit() {
 	rootCmd.AddCommand(printCmd)
+	printCmd.Flags().String("output", "text", "Output format [options: text, json, yaml, cbor-diag]")
+	printCmd.Flags().Bool("show-secrets", false, "Include the HMAC secret, private key and Wi-Fi passwords in the output")
+}
+
+func init() {
+	printCmdInit()
 }
 
 func isValidPath(p string) bool {
@@ -65,3 +147,237 @@ func isValidPath(p string) bool {
 	absPath, err := filepath.Abs(p)
 	return err == nil && absPath != ""
 }
+
+func newBlobCredentialReport(dc fdoDeviceCredential, showSecrets bool) *credentialReport {
+	report := newCredentialReport(dc.DC.DeviceCredential, dc.State, "blob", showSecrets)
+	report.DeviceKey.Type = signerKeyType(dc.DC.PrivateKey.Signer)
+	if !showSecrets {
+		report.HmacSecret = redacted
+		report.DeviceKey.PrivateKey = redacted
+		return report
+	}
+
+	report.HmacSecret = hex.EncodeToString(dc.DC.HmacSecret)
+	der, err := x509.MarshalPKCS8PrivateKey(dc.DC.PrivateKey.Signer)
+	if err != nil {
+		report.DeviceKey.PrivateKey = fmt.Sprintf("<invalid: %v>", err)
+	} else {
+		report.DeviceKey.PrivateKey = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	}
+	return report
+}
+
+func newTpmCredentialReport(dc fdoTpmDeviceCredential, showSecrets bool) *credentialReport {
+	// The HMAC and device keys never leave the TPM, only RvInfo may hold secrets
+	report := newCredentialReport(dc.DC.DeviceCredential, dc.State, "tpm", showSecrets)
+	report.DeviceKey.Type = tpmDeviceKeyType(dc.DC.DeviceKey)
+	handle := dc.DC.DeviceKeyHandle
+	report.DeviceKey.Handle = &handle
+	return report
+}
+
+func newCredentialReport(dc fdo.DeviceCredential, state FdoDeviceState, storage string, showSecrets bool) *credentialReport {
+	report := &credentialReport{
+		State:         state.String(),
+		Storage:       storage,
+		Version:       dc.Version,
+		DeviceInfo:    dc.DeviceInfo,
+		GUID:          hex.EncodeToString(dc.GUID[:]),
+		PublicKeyHash: newHashReport(dc.PublicKeyHash),
+		RvInfo:        []rvDirectiveReport{},
+	}
+	report.Progress = storedProgress(report.GUID)
+	for _, directive := range protocol.ParseDeviceRvInfo(dc.RvInfo) {
+		report.RvInfo = append(report.RvInfo, newRvDirectiveReport(directive, showSecrets))
+	}
+	return report
+}
+
+func newRvDirectiveReport(directive protocol.RvDirective, showSecrets bool) rvDirectiveReport {
+	report := rvDirectiveReport{
+		URLs:         []string{},
+		Bypass:       directive.Bypass,
+		DelaySeconds: int64(directive.Delay.Seconds()),
+		EthIface:     directive.EthIface,
+		WlanIface:    directive.WlanIface,
+		WlanSSID:     directive.WlanSSID,
+		WlanPass:     directive.WlanPass,
+		ExtMechanism: directive.ExtMechanism,
+	}
+	for _, u := range directive.URLs {
+		report.URLs = append(report.URLs, u.String())
+	}
+	if report.WlanPass != "" && !showSecrets {
+		report.WlanPass = redacted
+	}
+	if directive.ServerCert != nil {
+		h := newHashReport(*directive.ServerCert)
+		report.ServerCertHash = &h
+	}
+	if directive.ServerCA != nil {
+		h := newHashReport(*directive.ServerCA)
+		report.ServerCAHash = &h
+	}
+	return report
+}
+
+func newHashReport(h protocol.Hash) hashReport {
+	return hashReport{Algorithm: hashAlgName(h.Algorithm), Value: hex.EncodeToString(h.Value)}
+}
+
+// hashAlgName names a hash algorithm without panicking on unknown values.
+func hashAlgName(alg protocol.HashAlg) string {
+	switch alg {
+	case protocol.Sha256Hash, protocol.Sha384Hash, protocol.HmacSha256Hash, protocol.HmacSha384Hash:
+		return alg.String()
+	default:
+		return strconv.FormatInt(int64(alg), 10)
+	}
+}
+
+func signerKeyType(key crypto.Signer) string {
+	switch key := key.(type) {
+	case *ecdsa.PrivateKey:
+		return "ecdsa-" + strings.ToLower(key.Curve.Params().Name)
+	case *rsa.PrivateKey:
+		return "rsa-" + strconv.Itoa(key.Size()*8)
+	case nil:
+		return "none"
+	default:
+		return fmt.Sprintf("%T", key)
+	}
+}
+
+func tpmDeviceKeyType(t tpm.DeviceKeyType) string {
+	switch t {
+	case tpm.FdoDeviceKey:
+		return "tpm-fdo"
+	case tpm.IDevIDDeviceKey:
+		return "tpm-idevid"
+	case tpm.LDevIDDeviceKey:
+		return "tpm-ldevid"
+	default:
+		return fmt.Sprintf("tpm-%d", t)
+	}
+}
+
+func writeCredentialReport(w io.Writer, output string, report *credentialReport, stored any, showSecrets bool) error {
+	switch output {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+		return enc.Close()
+	case "cbor-diag":
+		diag, err := credentialDiag(stored, showSecrets)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, diag)
+		return err
+	default:
+		writeCredentialText(w, report)
+		return nil
+	}
+}
+
+func writeCredentialText(w io.Writer, report *credentialReport) {
+	fmt.Fprintf(w, "State:           %s\n", report.State)
+	fmt.Fprintf(w, "Storage:         %s\n", report.Storage)
+	fmt.Fprintf(w, "Version:         %d\n", report.Version)
+	fmt.Fprintf(w, "Device Info:     %s\n", report.DeviceInfo)
+	fmt.Fprintf(w, "GUID:            %s\n", report.GUID)
+	fmt.Fprintf(w, "Public Key Hash: %s %s\n", report.PublicKeyHash.Algorithm, report.PublicKeyHash.Value)
+	fmt.Fprintf(w, "Device Key:      %s\n", report.DeviceKey.Type)
+	if report.DeviceKey.Handle != nil {
+		fmt.Fprintf(w, "Key Handle:      0x%08X\n", *report.DeviceKey.Handle)
+	}
+	if report.DeviceKey.PrivateKey != "" {
+		fmt.Fprintf(w, "Private Key:     %s\n", strings.TrimSpace(report.DeviceKey.PrivateKey))
+	}
+	if report.HmacSecret != "" {
+		fmt.Fprintf(w, "HMAC Secret:     %s\n", report.HmacSecret)
+	}
+	fmt.Fprintf(w, "RV Directives:   %d\n", len(report.RvInfo))
+	for i, d := range report.RvInfo {
+		fmt.Fprintf(w, "  [%d] urls=%s bypass=%t delay=%ds\n", i, strings.Join(d.URLs, ","), d.Bypass, d.DelaySeconds)
+		if d.WlanSSID != "" {
+			fmt.Fprintf(w, "      wlan_ssid=%s wlan_password=%s\n", d.WlanSSID, d.WlanPass)
+		}
+		if d.ExtMechanism != "" {
+			fmt.Fprintf(w, "      ext_mechanism=%s\n", d.ExtMechanism)
+		}
+	}
+	writeProgressText(w, report.Progress, "Onboarding:      ")
+}
+
+// credentialDiag renders the stored credential CBOR in diagnostic notation.
+// Unless showSecrets is set, the HMAC secret, private key and Wi-Fi passwords
+// of a blob credential are replaced with a redaction marker.
+func credentialDiag(stored any, showSecrets bool) (string, error) {
+	data, err := cbor.Marshal(stored)
+	if err != nil {
+		return "", fmt.Errorf("error encoding credential: %w", err)
+	}
+	if !showSecrets {
+		var v any
+		if err := cbor.Unmarshal(data, &v); err != nil {
+			return "", fmt.Errorf("error decoding credential: %w", err)
+		}
+		redactCredentialValue(v, stored)
+		if data, err = cbor.Marshal(v); err != nil {
+			return "", fmt.Errorf("error encoding credential: %w", err)
+		}
+	}
+	return cdn.FromCBOR(data)
+}
+
+// Array positions of the flattened fields in a CBOR encoded credential
+const (
+	credRvInfoIdx     = 4 // after Active, Version, DeviceInfo, GUID
+	credHmacSecretIdx = 6
+	credPrivateKeyIdx = 7
+)
+
+func redactCredentialValue(v any, stored any) {
+	outer, ok := v.([]any)
+	if !ok || len(outer) == 0 {
+		return
+	}
+	dc, ok := outer[0].([]any)
+	if !ok {
+		return
+	}
+
+	rvIdx := credRvInfoIdx - 1 // TPM credentials have no leading Active field
+	if _, isBlob := stored.(fdoDeviceCredential); isBlob {
+		rvIdx = credRvInfoIdx
+		if len(dc) > credPrivateKeyIdx {
+			dc[credHmacSecretIdx] = redacted
+			dc[credPrivateKeyIdx] = redacted
+		}
+	}
+
+	if len(dc) <= rvIdx {
+		return
+	}
+	directives, _ := dc[rvIdx].([]any)
+	for _, directive := range directives {
+		instructions, _ := directive.([]any)
+		for _, instruction := range instructions {
+			pair, ok := instruction.([]any)
+			if !ok || len(pair) != 2 {
+				continue
+			}
+			if rvVar, ok := pair[0].(int64); ok && protocol.RvVar(rvVar) == protocol.RVWifiPw {
+				pair[1] = redacted
+			}
+		}
+	}
+}
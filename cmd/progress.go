@@ -0,0 +1,238 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+package cmd
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fido-device-onboard/go-fdo/protocol"
+)
+
+// onboardProgress is the retry progress of onboarding, persisted so that a
+// restarted client resumes where it stopped instead of at the first
+// rendezvous directive.
+type onboardProgress struct {
+	GUID         string     `json:"guid" yaml:"guid"`
+	Attempts     int        `json:"attempts" yaml:"attempts"`
+	Pass         int        `json:"pass" yaml:"pass"`
+	Directive    int        `json:"directive" yaml:"directive"`
+	LastError    string     `json:"last_error,omitempty" yaml:"last_error,omitempty"`
+	FirstAttempt time.Time  `json:"first_attempt" yaml:"first_attempt"`
+	LastAttempt  time.Time  `json:"last_attempt" yaml:"last_attempt"`
+	Completed    *time.Time `json:"completed,omitempty" yaml:"completed,omitempty"`
+
+	hooks onboardHooks
+}
+
+// onboardHooks let the daemon follow the retry loop.
+type onboardHooks struct {
+	// status receives a summary of the current phase and directive whenever
+	// it changes, see report.
+	status func(string)
+	// idle is called with true when the loop stops between attempts or
+	// waits, and with false when it starts an exchange, see setIdle.
+	idle func(bool)
+}
+
+// defaultStateDir holds the onboarding progress of TPM credentials unless a
+// progress file is configured.
+var defaultStateDir = "/var/lib/go-fdo-client"
+
+// progressPath returns the file holding the onboarding progress. It defaults
+// to a file next to the blob credential, or in defaultStateDir for TPM
+// credentials.
+func progressPath() string {
+	if rootConfig.ProgressFile != "" {
+		return rootConfig.ProgressFile
+	}
+	if rootConfig.TPM != "" {
+		return filepath.Join(defaultStateDir, "progress.json")
+	}
+	if rootConfig.Blob != "" {
+		return rootConfig.Blob + ".progress"
+	}
+	return ""
+}
+
+// readProgress returns the stored onboarding progress, or nil if progress is
+// not persisted or none was stored yet.
+func readProgress() (*onboardProgress, error) {
+	path := progressPath()
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(filepath.Clean(path))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading onboarding progress: %w", err)
+	}
+	var progress onboardProgress
+	if err := json.Unmarshal(data, &progress); err != nil {
+		return nil, fmt.Errorf("error parsing onboarding progress %q: %w", path, err)
+	}
+	return &progress, nil
+}
+
+// loadProgress returns the onboarding progress to resume for a credential.
+// Progress of another credential or of a completed onboarding starts over.
+func loadProgress(guid protocol.GUID) *onboardProgress {
+	fresh := &onboardProgress{GUID: hex.EncodeToString(guid[:])}
+	progress, err := readProgress()
+	if err != nil {
+		slog.Warn("Ignoring stored onboarding progress", "error", err)
+		return fresh
+	}
+	if progress == nil || progress.GUID != fresh.GUID || progress.Completed != nil {
+		return fresh
+	}
+	slog.Info("Resuming onboarding", "attempts", progress.Attempts, "directive", progress.Directive,
+		"last_attempt", progress.LastAttempt, "last_error", progress.LastError)
+	return progress
+}
+
+// storedProgress returns the stored onboarding progress of the credential
+// with the given hex encoded GUID, or nil if there is none.
+func storedProgress(guid string) *onboardProgress {
+	progress, err := readProgress()
+	if err != nil {
+		slog.Debug("Ignoring stored onboarding progress", "error", err)
+		return nil
+	}
+	if progress == nil || progress.GUID != guid {
+		return nil
+	}
+	return progress
+}
+
+// resumePoint returns the pass and the directive index to continue with
+// after the last recorded attempt.
+func (p *onboardProgress) resumePoint(directives int) (pass, directive int) {
+	if p.Attempts == 0 || p.Directive < 0 || p.Directive >= directives {
+		return p.Pass, 0
+	}
+	if p.Directive == directives-1 {
+		return p.Pass + 1, 0
+	}
+	return p.Pass, p.Directive + 1
+}
+
+// report passes a summary of the current onboarding phase and directive to
+// the status callback, if any.
+func (p *onboardProgress) report(format string, args ...any) {
+	if p.hooks.status != nil {
+		p.hooks.status(fmt.Sprintf(format, args...))
+	}
+}
+
+// setIdle tells the idle callback, if any, whether the retry loop is
+// between attempts, where onboarding can be stopped without interrupting an
+// exchange.
+func (p *onboardProgress) setIdle(idle bool) {
+	if p.hooks.idle != nil {
+		p.hooks.idle(idle)
+	}
+}
+
+// wait applies a delay of the retry loop, during which it is idle.
+func (p *onboardProgress) wait(ctx context.Context, clock retryClock, delay time.Duration) error {
+	p.setIdle(true)
+	defer p.setIdle(false)
+	return applyDelay(ctx, clock, delay)
+}
+
+// recordAttempt records a failed attempt of a directive and persists it.
+func (p *onboardProgress) recordAttempt(pass, directive int, attemptErr error) {
+	now := time.Now().UTC()
+	if p.Attempts == 0 {
+		p.FirstAttempt = now
+	}
+	p.Attempts++
+	p.Pass = pass
+	p.Directive = directive
+	p.LastAttempt = now
+	p.LastError = ""
+	if attemptErr != nil {
+		p.LastError = attemptErr.Error()
+	}
+	p.save()
+}
+
+// recordCompleted marks onboarding as completed and persists it under the
+// GUID of the resulting credential.
+func (p *onboardProgress) recordCompleted(guid protocol.GUID) {
+	now := time.Now().UTC()
+	p.GUID = hex.EncodeToString(guid[:])
+	p.Completed = &now
+	p.LastError = ""
+	p.save()
+}
+
+// save persists the progress. Failing to do so only costs the ability to
+// resume, so errors are logged rather than aborting onboarding.
+func (p *onboardProgress) save() {
+	path := progressPath()
+	if path == "" {
+		return
+	}
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		slog.Warn("Failed to encode onboarding progress", "error", err)
+		return
+	}
+	if rootConfig.ProgressFile == "" && rootConfig.TPM != "" {
+		if err := os.MkdirAll(defaultStateDir, 0o700); err != nil {
+			slog.Warn("Failed to create the state directory", "dir", defaultStateDir, "error", err)
+			return
+		}
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		slog.Warn("Failed to save onboarding progress", "file", path, "error", err)
+	}
+}
+
+// removeProgress deletes the stored onboarding progress.
+func removeProgress() error {
+	path := progressPath()
+	if path == "" {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("error removing onboarding progress: %w", err)
+	}
+	return nil
+}
+
+// writeProgressText prints a summary of the onboarding progress with each
+// line starting with prefix, or nothing if there is no progress.
+func writeProgressText(w io.Writer, progress *onboardProgress, prefix string) {
+	if progress == nil {
+		return
+	}
+	indent := strings.Repeat(" ", len(prefix))
+	if progress.Completed != nil {
+		fmt.Fprintf(w, "%scompleted %s after %d failed attempts\n", prefix, progress.Completed.Format(time.RFC3339), progress.Attempts)
+		return
+	}
+	if progress.Attempts == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%s%d failed attempts since %s, last %s (directive %d)\n", prefix,
+		progress.Attempts, progress.FirstAttempt.Format(time.RFC3339), progress.LastAttempt.Format(time.RFC3339), progress.Directive)
+	if progress.LastError != "" {
+		fmt.Fprintf(w, "%slast error: %s\n", indent, progress.LastError)
+	}
+}
@@ -0,0 +1,166 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+package cmd
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fido-device-onboard/go-fdo"
+)
+
+func TestProgressResumePoint(t *testing.T) {
+	tests := []struct {
+		name          string
+		progress      onboardProgress
+		wantPass      int
+		wantDirective int
+	}{
+		{"no attempts", onboardProgress{}, 0, 0},
+		{"middle directive", onboardProgress{Attempts: 2, Pass: 0, Directive: 1}, 0, 2},
+		{"last directive", onboardProgress{Attempts: 3, Pass: 1, Directive: 2}, 2, 0},
+		{"directive out of range", onboardProgress{Attempts: 9, Pass: 4, Directive: 7}, 4, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pass, directive := tt.progress.resumePoint(3)
+			if pass != tt.wantPass || directive != tt.wantDirective {
+				t.Errorf("resumePoint = (%d, %d), want (%d, %d)", pass, directive, tt.wantPass, tt.wantDirective)
+			}
+		})
+	}
+}
+
+// TestProgressTPM checks that the progress of a TPM credential is persisted
+// in the state directory by default.
+func TestProgressTPM(t *testing.T) {
+	resetState(t)
+	rootConfig = FDOClientConfig{TPM: "/dev/tpmrm0"}
+	t.Cleanup(func() { rootConfig = FDOClientConfig{} })
+	if got, want := progressPath(), filepath.Join(defaultStateDir, "progress.json"); got != want {
+		t.Fatalf("progressPath() = %q, want %q", got, want)
+	}
+
+	guid := strings.Repeat("ab", 16)
+	(&onboardProgress{GUID: guid}).recordAttempt(0, 1, errors.New("TO1 failed"))
+	if progress := storedProgress(guid); progress == nil || progress.Attempts != 1 || progress.Directive != 1 {
+		t.Errorf("stored progress = %+v, want 1 attempt of directive 1", progress)
+	}
+	if info, err := os.Stat(defaultStateDir); err != nil || info.Mode().Perm() != 0o700 {
+		t.Errorf("state directory %v, %v, want mode 0700", info, err)
+	}
+
+	rootConfig.ProgressFile = filepath.Join(t.TempDir(), "progress.json")
+	if got := progressPath(); got != rootConfig.ProgressFile {
+		t.Errorf("progressPath() = %q, want --progress-file", got)
+	}
+}
+
+// TestProgressPersistAndResume runs onboarding twice against unreachable
+// rendezvous servers and checks that the second run continues with the
+// directive after the last one tried by the first run.
+func TestProgressPersistAndResume(t *testing.T) {
+	dc := newTestBlobCred(t, FDO_STATE_PRE_TO1)
+	dc.DC.RvInfo = unreachableRvInfo(t, 3, 0)
+	blob := writeTestBlobCred(t, dc)
+	conf := fdo.TO2Config{Cred: dc.DC.DeviceCredential, Key: dc.DC.PrivateKey.Signer}
+	t.Cleanup(func() { onboardConfig = OnboardClientConfig{} })
+
+	run := func(maxAttempts int) *onboardProgress {
+		t.Helper()
+		onboardConfig = OnboardClientConfig{Onboard: OnboardConfig{MaxAttempts: maxAttempts}}
+		policy, _ := newTestRetryPolicy(onboardConfig.Onboard)
+		_, err := transferOwnership(context.Background(), dc.DC.RvInfo, conf, policy, loadProgress(dc.DC.GUID))
+		if !errors.Is(err, errMaxAttempts) {
+			t.Fatalf("transferOwnership error = %v, want %v", err, errMaxAttempts)
+		}
+		progress, err := readProgress()
+		if err != nil || progress == nil {
+			t.Fatalf("readProgress = %v, %v", progress, err)
+		}
+		return progress
+	}
+
+	first := run(2)
+	if first.Attempts != 2 || first.Directive != 1 || first.Pass != 0 {
+		t.Errorf("progress after first run = %+v, want 2 attempts ending at directive 1", first)
+	}
+	if !strings.Contains(first.LastError, "TO1") {
+		t.Errorf("last error = %q, want TO1 failure", first.LastError)
+	}
+	if first.FirstAttempt.IsZero() || first.LastAttempt.Before(first.FirstAttempt) {
+		t.Errorf("invalid timestamps: first %v, last %v", first.FirstAttempt, first.LastAttempt)
+	}
+
+	second := run(2)
+	if second.Attempts != 4 || second.Directive != 0 || second.Pass != 1 {
+		t.Errorf("progress after second run = %+v, want 4 attempts ending at directive 0 of pass 1", second)
+	}
+	if !second.FirstAttempt.Equal(first.FirstAttempt) {
+		t.Errorf("first attempt changed from %v to %v", first.FirstAttempt, second.FirstAttempt)
+	}
+
+	// A completed onboarding or another credential starts over
+	second.recordCompleted(dc.DC.GUID)
+	if got := loadProgress(dc.DC.GUID); got.Attempts != 0 {
+		t.Errorf("progress after completion = %+v, want fresh progress", got)
+	}
+
+	if _, err := os.Stat(blob + ".progress"); err != nil {
+		t.Errorf("progress file not stored next to the blob: %v", err)
+	}
+}
+
+func TestProgressReportedByStatus(t *testing.T) {
+	dc := newTestBlobCred(t, FDO_STATE_PRE_TO1)
+	path := writeTestBlobCred(t, dc)
+	progress := &onboardProgress{GUID: hex.EncodeToString(dc.DC.GUID[:])}
+	progress.recordAttempt(0, 0, errors.New("TO1 with http://127.0.0.1:8041 failed"))
+
+	out, err := runCommand(t, "status", "--blob", path, "--output", "json")
+	if got := ExitCode(err); got != statusExitPreTO1 {
+		t.Fatalf("exit code = %d, want %d (err: %v)", got, statusExitPreTO1, err)
+	}
+	var report deviceStatusReport
+	if err := json.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatalf("invalid JSON output: %v\n%s", err, out)
+	}
+	if report.Progress == nil || report.Progress.Attempts != 1 || report.Progress.LastError == "" {
+		t.Errorf("status progress = %+v, want 1 failed attempt", report.Progress)
+	}
+
+	out, _ = runCommand(t, "status", "--blob", path)
+	if !strings.Contains(out, "1 failed attempts") || !strings.Contains(out, "last error: TO1") {
+		t.Errorf("status text does not report progress:\n%s", out)
+	}
+
+	// Progress of another credential is not reported
+	progress.GUID = strings.Repeat("0", 32)
+	progress.LastAttempt = time.Now()
+	progress.save()
+	out, _ = runCommand(t, "status", "--blob", path, "--output", "json")
+	if strings.Contains(out, `"progress"`) {
+		t.Errorf("status reports progress of another credential:\n%s", out)
+	}
+}
+
+func TestProgressRemovedByReset(t *testing.T) {
+	dc := newTestBlobCred(t, FDO_STATE_PRE_TO1)
+	path := writeTestBlobCred(t, dc)
+	(&onboardProgress{GUID: hex.EncodeToString(dc.DC.GUID[:])}).recordAttempt(0, 0, nil)
+
+	if err := runReset(t, "", "--blob", path, "--state", "PRE_TO1", "--yes"); err != nil {
+		t.Fatalf("reset failed: %v", err)
+	}
+	if _, err := os.Stat(path + ".progress"); !os.IsNotExist(err) {
+		t.Errorf("progress file not removed by reset: %v", err)
+	}
+}
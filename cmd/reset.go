@@ -0,0 +1,204 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+package cmd
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/fido-device-onboard/go-fdo-client/internal/tpm_utils"
+	"github.com/google/go-tpm/tpm2"
+	"github.com/spf13/cobra"
+)
+
+var resetCmd = &cobra.Command{
+	Use:   "reset",
+	Short: "Wipe or re-arm the device credential",
+	Long: `Wipe the stored device credential, or re-arm it for another onboarding.
+
+By default the credential is destroyed: a blob file is overwritten and
+removed, and the TPM NV index holding the credential is undefined. Afterwards
+the device must run device initialization (DI) again.
+
+With --state the credential is kept and only its state is changed:
+  PRE_TO1  re-onboard the device with its current credential (TO1/TO2)
+  PRE_DI   re-manufacture the device (the next device-init replaces the credential)
+
+Both wiping and re-arming remove the persisted onboarding progress.
+
+The command asks for confirmation unless --yes is given. Re-arming a TPM
+credential requires --key to match the key type used at device-init.`,
+	Example: `  # Destroy a blob credential without prompting:
+  go-fdo-client reset --blob cred.bin --yes
+
+  # Re-arm a TPM credential for another onboarding:
+  go-fdo-client reset --tpm /dev/tpmrm0 --key ec256 --state PRE_TO1`,
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		state, err := cmd.Flags().GetString("state")
+		if err != nil {
+			return err
+		}
+		if _, err := parseResetState(state); err != nil {
+			return err
+		}
+		if state != "" && rootConfig.TPM != "" {
+			if rootConfig.Key == "" {
+				return fmt.Errorf("--key is required to re-arm a TPM credential (via CLI flag or config file)")
+			}
+			return validateKey(rootConfig.Key)
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		stateName, err := cmd.Flags().GetString("state")
+		if err != nil {
+			return err
+		}
+		state, _ := parseResetState(stateName)
+		yes, err := cmd.Flags().GetBool("yes")
+		if err != nil {
+			return err
+		}
+
+		if rootConfig.TPM != "" {
+			tpmc, err = tpm_utils.TpmOpen(rootConfig.TPM)
+			if err != nil {
+				return err
+			}
+			defer tpmc.Close()
+		}
+
+		current, err := loadDeviceStatus()
+		if err != nil && stateName != "" {
+			return fmt.Errorf("load device status failed: %w", err)
+		}
+		if err == nil && current == FDO_STATE_PRE_DI && !credentialStored() {
+			slog.Info("No device credential stored, nothing to reset")
+			return nil
+		}
+
+		var prompt string
+		if stateName == "" {
+			prompt = fmt.Sprintf("This will permanently destroy the device credential in %s.", credentialLocation())
+		} else {
+			prompt = fmt.Sprintf("This will change the device credential in %s from %s to %s.", credentialLocation(), current, state)
+		}
+		if !yes {
+			ok, err := confirm(cmd.InOrStdin(), cmd.ErrOrStderr(), prompt)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return errors.New("reset aborted")
+			}
+		}
+
+		if stateName == "" {
+			return wipeCred()
+		}
+		return rearmCred(state)
+	},
+}
+
+func resetCmdInit() {
+	rootCmd.AddCommand(resetCmd)
+	resetCmd.Flags().String("state", "", "Keep the credential and set its state instead of destroying it [options: PRE_TO1, PRE_DI]")
+	resetCmd.Flags().BoolP("yes", "y", false, "Do not ask for confirmation")
+}
+
+func init() {
+	resetCmdInit()
+}
+
+func parseResetState(name string) (FdoDeviceState, error) {
+	switch strings.ToUpper(name) {
+	case "":
+		return FDO_STATE_PC, nil
+	case FDO_STATE_PRE_TO1.String():
+		return FDO_STATE_PRE_TO1, nil
+	case FDO_STATE_PRE_DI.String():
+		return FDO_STATE_PRE_DI, nil
+	default:
+		return FDO_STATE_PC, fmt.Errorf("invalid reset state: '%s', options [PRE_TO1, PRE_DI]", name)
+	}
+}
+
+// credentialStored reports whether a credential exists in the configured
+// backend, regardless of whether it can be decoded.
+func credentialStored() bool {
+	if rootConfig.TPM != "" {
+		return tpm_utils.TpmNVGetSize(tpmc, tpm2.TPMHandle(FDO_CRED_NV_IDX)) != 0
+	}
+	_, err := os.Lstat(rootConfig.Blob)
+	return err == nil
+}
+
+func credentialLocation() string {
+	if rootConfig.TPM != "" {
+		return fmt.Sprintf("TPM %s NV index 0x%08X", rootConfig.TPM, FDO_CRED_NV_IDX)
+	}
+	return fmt.Sprintf("blob %q", rootConfig.Blob)
+}
+
+// confirm asks the user to confirm a destructive action on in.
+func confirm(in io.Reader, out io.Writer, prompt string) (bool, error) {
+	fmt.Fprintf(out, "%s Continue? [y/N]: ", prompt)
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, fmt.Errorf("error reading confirmation: %w", err)
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// wipeCred destroys the stored device credential.
+func wipeCred() error {
+	// The GUID is only used for logging, a corrupt credential is still wiped
+	var guid string
+	if dc, state, err := readStoredCred(); err == nil {
+		guid = hex.EncodeToString(dc.GUID[:])
+		slog.Debug("Destroying device credential", "guid", guid, "state", state)
+	}
+
+	if rootConfig.TPM != "" {
+		deleted, err := tpm_utils.TpmNVDelete(tpmc, tpm2.TPMHandle(FDO_CRED_NV_IDX))
+		if err != nil {
+			return fmt.Errorf("failed to undefine NV index 0x%08X: %w", FDO_CRED_NV_IDX, err)
+		}
+		if deleted {
+			slog.Info("Device credential destroyed", "tpm", rootConfig.TPM, "nv index", fmt.Sprintf("0x%08X", FDO_CRED_NV_IDX), "guid", guid)
+		}
+		return removeProgress()
+	}
+
+	if err := secureRemove(rootConfig.Blob); err != nil {
+		return fmt.Errorf("failed to remove blob credential: %w", err)
+	}
+	slog.Info("Device credential destroyed", "blob", rootConfig.Blob, "guid", guid)
+	return removeProgress()
+}
+
+// rearmCred moves the stored device credential to a new state.
+func rearmCred(state FdoDeviceState) error {
+	dc, prev, err := readStoredCred()
+	if err != nil {
+		return err
+	}
+	if err := updateCred(dc, state); err != nil {
+		return fmt.Errorf("failed to update device credential: %w", err)
+	}
+	slog.Info("Device credential re-armed", "guid", hex.EncodeToString(dc.GUID[:]), "from", prev, "to", state)
+	return removeProgress()
+}
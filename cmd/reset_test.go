@@ -0,0 +1,112 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runReset(t *testing.T, stdin string, args ...string) error {
+	t.Helper()
+	resetState(t)
+	rootCmd.SetIn(strings.NewReader(stdin))
+	rootCmd.SetErr(&bytes.Buffer{})
+	t.Cleanup(func() {
+		rootCmd.SetIn(nil)
+		rootCmd.SetErr(nil)
+	})
+	rootCmd.SetArgs(append([]string{"reset"}, args...))
+	return rootCmd.Execute()
+}
+
+func TestReset_WipeBlob(t *testing.T) {
+	path := writeTestBlobCred(t, newTestBlobCred(t, FDO_STATE_IDLE))
+
+	if err := runReset(t, "", "--blob", path, "--yes"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("blob credential still exists after reset: %v", err)
+	}
+}
+
+func TestReset_Confirmation(t *testing.T) {
+	t.Run("declined", func(t *testing.T) {
+		path := writeTestBlobCred(t, newTestBlobCred(t, FDO_STATE_IDLE))
+		if err := runReset(t, "n\n", "--blob", path); err == nil {
+			t.Fatal("expected error when confirmation is declined")
+		}
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("blob credential removed without confirmation: %v", err)
+		}
+	})
+
+	t.Run("no input", func(t *testing.T) {
+		path := writeTestBlobCred(t, newTestBlobCred(t, FDO_STATE_IDLE))
+		if err := runReset(t, "", "--blob", path); err == nil {
+			t.Fatal("expected error without confirmation")
+		}
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("blob credential removed without confirmation: %v", err)
+		}
+	})
+
+	t.Run("accepted", func(t *testing.T) {
+		path := writeTestBlobCred(t, newTestBlobCred(t, FDO_STATE_IDLE))
+		if err := runReset(t, "yes\n", "--blob", path); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("blob credential still exists after reset: %v", err)
+		}
+	})
+}
+
+func TestReset_Rearm(t *testing.T) {
+	for _, tt := range []struct {
+		arg  string
+		want FdoDeviceState
+	}{
+		{"PRE_TO1", FDO_STATE_PRE_TO1},
+		{"pre_di", FDO_STATE_PRE_DI},
+	} {
+		t.Run(tt.arg, func(t *testing.T) {
+			want := newTestBlobCred(t, FDO_STATE_IDLE)
+			path := writeTestBlobCred(t, want)
+
+			if err := runReset(t, "", "--blob", path, "--state", tt.arg, "--yes"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got fdoDeviceCredential
+			if err := readCredFile(&got); err != nil {
+				t.Fatalf("reading re-armed credential: %v", err)
+			}
+			if got.State != tt.want {
+				t.Errorf("State = %v, want %v", got.State, tt.want)
+			}
+			if got.DC.GUID != want.DC.GUID || !bytes.Equal(got.DC.HmacSecret, want.DC.HmacSecret) {
+				t.Error("re-arming changed the credential contents")
+			}
+		})
+	}
+}
+
+func TestReset_InvalidState(t *testing.T) {
+	path := writeTestBlobCred(t, newTestBlobCred(t, FDO_STATE_IDLE))
+	if err := runReset(t, "", "--blob", path, "--state", "IDLE", "--yes"); err == nil {
+		t.Fatal("expected error for invalid state")
+	}
+}
+
+func TestReset_NoCredential(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.bin")
+	if err := runReset(t, "", "--blob", path, "--yes"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
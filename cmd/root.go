@@ -5,10 +5,12 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
+	"syscall"
 
 	"github.com/fido-device-onboard/go-fdo/tpm"
 	"github.com/spf13/cobra"
@@ -27,30 +29,34 @@ var rootCmd = &cobra.Command{
 		DisableDefaultCmd: true,
 	},
 	SilenceUsage: true,
-	Use:          "go-fdo-client {device-init|onboard|print}",
+	Use:          "go-fdo-client {device-init|onboard|daemon|install-service|rv|print|status|verify|doctor|config|reset|export|import}",
 	Short:        "FIDO Device Onboard (FDO) client",
 	Long: `Run an FDO client to initialize or onboard a device.
 
 Use one of the subcommands to perform device initialization (DI) with a
-manufacturer server, onboard a device via TO1/TO2, or print the stored
-device credentials.`,
+manufacturer server, onboard a device via TO1/TO2 once or as a systemd
+service, install a systemd unit for onboarding at boot, inspect and probe the
+rendezvous directives, print the stored device credentials, report the device
+onboarding status, verify, wipe and re-arm the device credential, export and
+import it, diagnose common causes of onboarding failures, or inspect the
+configuration.`,
 	Example: `  # Initialize a device with a manufacturer server:
   go-fdo-client device-init http://127.0.0.1:8038 --key ec256 --blob cred.bin
 
   # Onboard a previously initialized device:
   go-fdo-client onboard --key ec256 --kex ECDH256 --blob cred.bin`,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-		if configFile != "" {
-			viper.SetConfigFile(configFile)
-			if err := viper.ReadInConfig(); err != nil {
-				return fmt.Errorf("failed to read config file: %w", err)
-			}
+		if err := loadConfig(); err != nil {
+			return err
 		}
 
 		err := viper.Unmarshal(&rootConfig)
 		if err != nil {
 			return err
 		}
+		if err := configureLogging(rootConfig); err != nil {
+			return err
+		}
 
 		return rootConfig.validate()
 	},
@@ -65,17 +71,45 @@ func (f *FDOClientConfig) validate() error {
 	return nil
 }
 
+// exitCodeError carries a specific process exit code back to main through
+// cobra's error return.
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+func (e *exitCodeError) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("exit status %d", e.code)
+	}
+	return e.err.Error()
+}
+
+func (e *exitCodeError) Unwrap() error { return e.err }
+
+// ExitCode returns the process exit code for an error returned by Execute.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exitCodeError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+	return 1
+}
+
 // Root returns the root command for use by doc generators.
 func Root() *cobra.Command { return rootCmd }
 
 // Called by main to parse the command line and execute the subcommand
 func Execute() error {
-	// Catch interrupts
+	// Catch interrupts and termination requests
 	var cancel context.CancelFunc
 	clientContext, cancel = context.WithCancel(context.Background())
 	defer cancel()
 	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, os.Interrupt)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		defer signal.Stop(sigs)
 		select {
@@ -85,7 +119,7 @@ func Execute() error {
 		}
 	}()
 
-	err := rootCmd.Execute()
+	err := rootCmd.ExecuteContext(clientContext)
 	if err != nil {
 		return err
 	}
@@ -95,11 +129,20 @@ func Execute() error {
 func rootCmdInit() {
 	rootCmd.SetHelpCommand(&cobra.Command{Hidden: true})
 	pflags := rootCmd.PersistentFlags()
-	pflags.StringVar(&configFile, "config", "", "Path to configuration file (YAML or TOML)")
+	pflags.StringVar(&configFile, "config", "", "Path to configuration file (YAML or TOML) (env FDO_CLIENT_CONFIG)")
+	pflags.String("kernel-cmdline", "", "File to read fdo.* kernel command line parameters from, such as /proc/cmdline (default: ignore the kernel command line)")
 	pflags.String("blob", "", "File path of device credential blob")
-	pflags.Bool("debug", false, "Print HTTP contents")
+	pflags.Bool("debug", false, "Log debug messages and a trace of the FDO messages with secrets redacted")
 	pflags.String("tpm", "", "Use a TPM at path for device credential secrets")
 	pflags.String("key", "", "Key type for device credential [options: ec256, ec384, rsa2048, rsa3072]")
+	pflags.String("profile", "", "Name of the configuration profile to apply (see the profiles section of the configuration file)")
+	pflags.String("progress-file", "", "File path of the onboarding retry progress (default: <blob>.progress, /var/lib/go-fdo-client/progress.json for --tpm)")
+	pflags.String("trace-file", "", "File to append a trace of the FDO messages to with secrets redacted, also without --debug")
+	pflags.String("log-format", "", "Log format [options: text, json, journald] (default: journald when the output goes to the systemd journal, text otherwise)")
+	pflags.String("log-level", "info", "Minimum level of logged messages [options: debug, info, warn, error], debug with --debug")
+	pflags.String("log-file", "", "File to write the log to instead of standard output, rotated by size")
+	pflags.Int("log-max-size", 10, "Size in MiB at which the log file is rotated (0 to never rotate)")
+	pflags.Int("log-max-backups", 3, "Number of rotated log files to keep")
 
 	// Bind global flags to viper
 	if err := viper.BindPFlag("blob", pflags.Lookup("blob")); err != nil {
@@ -114,6 +157,34 @@ func rootCmdInit() {
 	if err := viper.BindPFlag("key", pflags.Lookup("key")); err != nil {
 		slog.Error("configuration error - flag binding failed for 'key'", "error", err)
 	}
+	if err := viper.BindPFlag("progress-file", pflags.Lookup("progress-file")); err != nil {
+		slog.Error("configuration error - flag binding failed for 'progress-file'", "error", err)
+	}
+	if err := viper.BindPFlag("profile", pflags.Lookup("profile")); err != nil {
+		slog.Error("configuration error - flag binding failed for 'profile'", "error", err)
+	}
+	if err := viper.BindPFlag("kernel-cmdline", pflags.Lookup("kernel-cmdline")); err != nil {
+		slog.Error("configuration error - flag binding failed for 'kernel-cmdline'", "error", err)
+	}
+	if err := viper.BindPFlag("trace-file", pflags.Lookup("trace-file")); err != nil {
+		slog.Error("configuration error - flag binding failed for 'trace-file'", "error", err)
+	}
+	if err := viper.BindPFlag("log-format", pflags.Lookup("log-format")); err != nil {
+		slog.Error("configuration error - flag binding failed for 'log-format'", "error", err)
+	}
+	if err := viper.BindPFlag("log-level", pflags.Lookup("log-level")); err != nil {
+		slog.Error("configuration error - flag binding failed for 'log-level'", "error", err)
+	}
+	if err := viper.BindPFlag("log-file", pflags.Lookup("log-file")); err != nil {
+		slog.Error("configuration error - flag binding failed for 'log-file'", "error", err)
+	}
+	if err := viper.BindPFlag("log-max-size", pflags.Lookup("log-max-size")); err != nil {
+		slog.Error("configuration error - flag binding failed for 'log-max-size'", "error", err)
+	}
+	if err := viper.BindPFlag("log-max-backups", pflags.Lookup("log-max-backups")); err != nil {
+		slog.Error("configuration error - flag binding failed for 'log-max-backups'", "error", err)
+	}
+	bindEnv()
 }
 
 func init() {
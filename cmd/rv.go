@@ -0,0 +1,347 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+package cmd
+
+import (
+	"context"
+	"crypto"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fido-device-onboard/go-fdo-client/internal/coap"
+	"github.com/fido-device-onboard/go-fdo-client/internal/tpm_utils"
+	"github.com/fido-device-onboard/go-fdo/protocol"
+	"github.com/spf13/cobra"
+)
+
+var validRvOutputs = []string{"text", "json"}
+
+// rvPlanReport describes how onboard walks the rendezvous directives of the
+// stored credential.
+type rvPlanReport struct {
+	GUID       string            `json:"guid"`
+	Directives []rvPlanDirective `json:"directives"`
+	Probes     *rvProbeSummary   `json:"probe_summary,omitempty"`
+}
+
+type rvPlanDirective struct {
+	Index                 int       `json:"index"`
+	URLs                  []string  `json:"urls"`
+	Bypass                bool      `json:"bypass"`
+	DelaySeconds          int64     `json:"delay_seconds"`
+	EffectiveDelaySeconds int64     `json:"effective_delay_seconds"`
+	DefaultDelay          bool      `json:"default_delay"`
+	DelayOption           string    `json:"delay_option,omitempty"`
+	Probes                []rvProbe `json:"probes,omitempty"`
+}
+
+// rvProbe is the result of checking that a rendezvous or owner URL can be
+// reached without running any FDO protocol.
+type rvProbe struct {
+	URL       string   `json:"url"`
+	Role      string   `json:"role"`
+	Addresses []string `json:"addresses,omitempty"`
+	TCP       bool     `json:"tcp"`
+	CoAP      bool     `json:"coap,omitempty"`
+	TLS       string   `json:"tls,omitempty"`
+	LatencyMs int64    `json:"latency_ms,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// dnsLookupFailed starts the error of a probe whose host name does not
+// resolve.
+const dnsLookupFailed = "DNS lookup failed"
+
+type rvProbeSummary struct {
+	Reachable   int `json:"reachable"`
+	Unreachable int `json:"unreachable"`
+}
+
+var rvCmd = &cobra.Command{
+	Use:   "rv",
+	Short: "Show the rendezvous directives used by onboard",
+	Long: `Show the rendezvous (RV) directives of the stored device credential in the
+order onboard tries them: the URLs, whether RV bypass is set, the delay
+configured by the directive and the effective delay onboard waits after the
+directive first fails with the retry options of the onboard configuration:
+after a failed TO1, or a failed TO2 for bypass directives. A directive that
+does not configure a delay waits --to1-failure-delay or --to2-failure-delay
+if set, and the last directive waits 120 seconds otherwise. onboard adds ±25%
+jitter to every effective delay, and --backoff-multiplier grows it for each
+later pass over all directives.
+
+With --probe, each URL is resolved and a TCP connection (and a TLS handshake
+for https) is attempted, or a CoAP ping sent for coap. TLS handshakes are
+verified with the TO1 TLS options of onboard, or the TO2 options for owner
+URLs. No FDO messages are sent, so probing does not use up an onboarding
+attempt. URLs of bypass directives are owner URLs; for other directives the
+owner URLs are only known after TO1 and cannot be probed.`,
+	Example: `  # List the rendezvous directives of a blob credential:
+  go-fdo-client rv --blob cred.bin
+
+  # Check that every rendezvous server can be reached:
+  go-fdo-client rv --tpm /dev/tpmrm0 --probe --probe-timeout 3s`,
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return readOnboardConfig(cmd)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		output, err := cmd.Flags().GetString("output")
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(validRvOutputs, output) {
+			return fmt.Errorf("invalid output format: '%s', options [%s]", output, strings.Join(validRvOutputs, ", "))
+		}
+		probe, err := cmd.Flags().GetBool("probe")
+		if err != nil {
+			return err
+		}
+		timeout, err := cmd.Flags().GetDuration("probe-timeout")
+		if err != nil {
+			return err
+		}
+		if timeout <= 0 {
+			return fmt.Errorf("probe-timeout must be positive")
+		}
+		if rootConfig.TPM != "" {
+			tpmc, err = tpm_utils.TpmOpen(rootConfig.TPM)
+			if err != nil {
+				return err
+			}
+			defer tpmc.Close()
+		}
+
+		dc, _, err := readStoredCred()
+		if err != nil {
+			return fmt.Errorf("failed to read device credential: %w", err)
+		}
+		// Directives that are not for the device are parsed without URLs
+		directives := protocol.ParseDeviceRvInfo(dc.RvInfo)
+		if !slices.ContainsFunc(directives, func(d protocol.RvDirective) bool { return len(d.URLs) > 0 }) {
+			return errors.New("no rendezvous information found that's usable for the device")
+		}
+
+		report := newRvPlanReport(directives, newRetryPolicy(onboardConfig.Onboard))
+		report.GUID = hex.EncodeToString(dc.GUID[:])
+		if probe {
+			// The client certificate of the TPM device key is presented to
+			// servers requesting one
+			var deviceKey crypto.Signer
+			if onboardConfig.Onboard.TLSClientKey == tpmClientKey && rootConfig.TPM != "" {
+				key, err := tpmDeviceKey()
+				if err != nil {
+					return fmt.Errorf("failed to load the TPM device key: %w", err)
+				}
+				defer func() { _ = key.Close() }()
+				deviceKey = key
+			}
+			rvConf, err := tlsConfig(onboardConfig.Onboard.to1TLSOptions(), deviceKey)
+			if err != nil {
+				return fmt.Errorf("TO1 TLS: %w", err)
+			}
+			ownerConf, err := tlsConfig(onboardConfig.Onboard.to2TLSOptions(), deviceKey)
+			if err != nil {
+				return fmt.Errorf("TO2 TLS: %w", err)
+			}
+			probeRvPlan(cmd.Context(), report, timeout, rvConf, ownerConf)
+		}
+		if err := writeRvPlanReport(cmd.OutOrStdout(), report, output); err != nil {
+			return err
+		}
+
+		if report.Probes != nil && report.Probes.Unreachable > 0 {
+			return fmt.Errorf("%d of %d rendezvous URLs are unreachable",
+				report.Probes.Unreachable, report.Probes.Reachable+report.Probes.Unreachable)
+		}
+		return nil
+	},
+}
+
+func rvCmdInit() {
+	rootCmd.AddCommand(rvCmd)
+	rvCmd.Flags().String("output", "text", "Output format [options: text, json]")
+	rvCmd.Flags().Bool("probe", false, "Resolve and connect to every rendezvous URL without running TO1")
+	rvCmd.Flags().Duration("probe-timeout", 5*time.Second, "Timeout for each probe connection")
+	addOnboardFlags(rvCmd)
+}
+
+func init() {
+	rvCmdInit()
+}
+
+// newRvPlanReport returns the plan of the directives with the delays of the
+// first pass of the retry policy.
+func newRvPlanReport(directives []protocol.RvDirective, policy *retryPolicy) *rvPlanReport {
+	report := &rvPlanReport{Directives: []rvPlanDirective{}}
+	for i, directive := range directives {
+		// Bypass directives fail in TO2, others usually in TO1
+		phase, option, optionDelay := retryPhaseTO1, "to1-failure-delay", policy.TO1FailureDelay
+		if directive.Bypass {
+			phase, option, optionDelay = retryPhaseTO2, "to2-failure-delay", policy.TO2FailureDelay
+		}
+		delay := policy.delay(&directive, i == len(directives)-1, phase, 0)
+		plan := rvPlanDirective{
+			Index:                 i,
+			URLs:                  []string{},
+			Bypass:                directive.Bypass,
+			DelaySeconds:          int64(directive.Delay.Seconds()),
+			EffectiveDelaySeconds: int64(delay.Seconds()),
+			DefaultDelay:          directive.Delay != delay,
+		}
+		if directive.Delay == 0 && optionDelay > 0 {
+			plan.DelayOption = option
+		}
+		for _, u := range directive.URLs {
+			plan.URLs = append(plan.URLs, u.String())
+		}
+		report.Directives = append(report.Directives, plan)
+	}
+	return report
+}
+
+// probeRvPlan probes every URL of the plan and records the results in it.
+// TLS handshakes with rendezvous servers are verified with rvConf, and with
+// owner servers of bypass directives with ownerConf.
+func probeRvPlan(ctx context.Context, report *rvPlanReport, timeout time.Duration, rvConf, ownerConf *tls.Config) {
+	report.Probes = &rvProbeSummary{}
+	for i := range report.Directives {
+		directive := &report.Directives[i]
+		role, conf := "rv", rvConf
+		if directive.Bypass {
+			role, conf = "owner", ownerConf
+		}
+		for _, rawURL := range directive.URLs {
+			result := probeURL(ctx, rawURL, timeout, conf)
+			result.Role = role
+			if result.Error == "" {
+				report.Probes.Reachable++
+			} else {
+				report.Probes.Unreachable++
+			}
+			slog.Debug("Probed rendezvous URL", "url", rawURL, "role", role, "error", result.Error)
+			directive.Probes = append(directive.Probes, result)
+		}
+	}
+}
+
+// probeURL resolves the host of a URL and connects to it, performing a TLS
+// handshake with conf for TLS based schemes.
+func probeURL(ctx context.Context, rawURL string, timeout time.Duration, conf *tls.Config) rvProbe {
+	result := rvProbe{URL: rawURL}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		result.Error = fmt.Sprintf("invalid URL: %v", err)
+		return result
+	}
+	host, port := u.Hostname(), u.Port()
+	if port == "" {
+		result.Error = "URL has no port"
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if net.ParseIP(host) != nil {
+		result.Addresses = []string{host}
+	} else {
+		addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+		if err != nil {
+			result.Error = fmt.Sprintf("%s: %v", dnsLookupFailed, err)
+			return result
+		}
+		result.Addresses = addrs
+	}
+
+	start := time.Now()
+	if u.Scheme == "coap" {
+		if err := coap.Ping(ctx, nil, net.JoinHostPort(host, port)); err != nil {
+			result.Error = fmt.Sprintf("CoAP ping failed: %v", err)
+			return result
+		}
+		result.CoAP = true
+		result.LatencyMs = time.Since(start).Milliseconds()
+		return result
+	}
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		result.Error = fmt.Sprintf("TCP connect failed: %v", err)
+		return result
+	}
+	defer func() { _ = conn.Close() }()
+	result.TCP = true
+
+	if u.Scheme == "https" || u.Scheme == "tls" {
+		conf = conf.Clone()
+		if conf.ServerName == "" {
+			conf.ServerName = host
+		}
+		tlsConn := tls.Client(conn, conf)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			result.Error = fmt.Sprintf("TLS handshake failed: %v", err)
+			return result
+		}
+		result.TLS = tls.VersionName(tlsConn.ConnectionState().Version)
+	}
+	result.LatencyMs = time.Since(start).Milliseconds()
+	return result
+}
+
+func writeRvPlanReport(w io.Writer, report *rvPlanReport, output string) error {
+	if output == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+
+	fmt.Fprintf(w, "GUID: %s\n", report.GUID)
+	for _, directive := range report.Directives {
+		fmt.Fprintf(w, "Directive %d:\n", directive.Index)
+		if len(directive.URLs) == 0 {
+			fmt.Fprintf(w, "  URLs:            none\n")
+		}
+		for _, u := range directive.URLs {
+			fmt.Fprintf(w, "  URL:             %s\n", u)
+		}
+		fmt.Fprintf(w, "  Bypass:          %t\n", directive.Bypass)
+		fmt.Fprintf(w, "  Delay:           %ds\n", directive.DelaySeconds)
+		switch {
+		case directive.DelayOption != "":
+			fmt.Fprintf(w, "  Effective delay: %ds (--%s)\n", directive.EffectiveDelaySeconds, directive.DelayOption)
+		case directive.DefaultDelay:
+			fmt.Fprintf(w, "  Effective delay: %ds (default for last directive)\n", directive.EffectiveDelaySeconds)
+		default:
+			fmt.Fprintf(w, "  Effective delay: %ds\n", directive.EffectiveDelaySeconds)
+		}
+		for _, probe := range directive.Probes {
+			if probe.Error != "" {
+				fmt.Fprintf(w, "  Probe %s: FAIL %s\n", probe.URL, probe.Error)
+				continue
+			}
+			detail := "tcp"
+			if probe.CoAP {
+				detail = "coap"
+			}
+			if probe.TLS != "" {
+				detail += ", " + probe.TLS
+			}
+			fmt.Fprintf(w, "  Probe %s: OK %s (%s, %dms)\n", probe.URL, strings.Join(probe.Addresses, " "), detail, probe.LatencyMs)
+		}
+	}
+	if report.Probes != nil {
+		fmt.Fprintf(w, "Probes: %d reachable, %d unreachable\n", report.Probes.Reachable, report.Probes.Unreachable)
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/fido-device-onboard/go-fdo-client/internal/coap/coaptest"
+	"github.com/fido-device-onboard/go-fdo/protocol"
+)
+
+// testRvDirective returns a device directive for the host and port of a test
+// server URL.
+func testRvDirective(t *testing.T, serverURL string, prot uint8, extra ...protocol.RvInstruction) []protocol.RvInstruction {
+	t.Helper()
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.ParseUint(u.Port(), 10, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append([]protocol.RvInstruction{
+		testRvInstruction(t, protocol.RVProtocol, prot),
+		testRvInstruction(t, protocol.RVIPAddress, net.ParseIP(u.Hostname())),
+		testRvInstruction(t, protocol.RVDevPort, uint16(port)),
+	}, extra...)
+}
+
+func TestRv_Plan(t *testing.T) {
+	dc := newTestBlobCred(t, FDO_STATE_PRE_TO1)
+	dc.DC.RvInfo = [][]protocol.RvInstruction{
+		testRvDirective(t, "http://127.0.0.1:8041", protocol.RVProtHTTP,
+			testRvInstruction(t, protocol.RVDelaysec, uint32(30))),
+		testRvDirective(t, "https://127.0.0.1:8443", protocol.RVProtHTTPS,
+			testRvInstruction(t, protocol.RVBypass, nil)),
+	}
+	path := writeTestBlobCred(t, dc)
+
+	out, err := runCommand(t, "rv", "--blob", path, "--output", "json")
+	if err != nil {
+		t.Fatalf("rv failed: %v", err)
+	}
+	var report rvPlanReport
+	if err := json.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatalf("invalid JSON output: %v\n%s", err, out)
+	}
+	if len(report.Directives) != 2 {
+		t.Fatalf("got %d directives, want 2:\n%s", len(report.Directives), out)
+	}
+
+	first, last := report.Directives[0], report.Directives[1]
+	if first.DelaySeconds != 30 || first.EffectiveDelaySeconds != 30 || first.DefaultDelay {
+		t.Errorf("first directive delay = %+v, want configured 30s", first)
+	}
+	if first.Bypass || len(first.URLs) != 1 || first.URLs[0] != "http://127.0.0.1:8041" {
+		t.Errorf("first directive = %+v", first)
+	}
+	if last.DelaySeconds != 0 || last.EffectiveDelaySeconds != int64(defaultLastDirectiveDelay.Seconds()) || !last.DefaultDelay {
+		t.Errorf("last directive delay = %+v, want default %s", last, defaultLastDirectiveDelay)
+	}
+	if !last.Bypass {
+		t.Errorf("last directive bypass = false, want true")
+	}
+	if report.Probes != nil || first.Probes != nil {
+		t.Errorf("probes reported without --probe")
+	}
+}
+
+func TestRv_PlanRetryOptions(t *testing.T) {
+	dc := newTestBlobCred(t, FDO_STATE_PRE_TO1)
+	dc.DC.RvInfo = [][]protocol.RvInstruction{
+		testRvDirective(t, "http://127.0.0.1:8041", protocol.RVProtHTTP,
+			testRvInstruction(t, protocol.RVDelaysec, uint32(30))),
+		testRvDirective(t, "http://127.0.0.1:8042", protocol.RVProtHTTP),
+		testRvDirective(t, "https://127.0.0.1:8443", protocol.RVProtHTTPS,
+			testRvInstruction(t, protocol.RVBypass, nil)),
+	}
+	path := writeTestBlobCred(t, dc)
+
+	out, err := runCommand(t, "rv", "--blob", path, "--output", "json", "--to1-failure-delay", "10s", "--to2-failure-delay", "45s")
+	if err != nil {
+		t.Fatalf("rv failed: %v", err)
+	}
+	var report rvPlanReport
+	if err := json.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatalf("invalid JSON output: %v\n%s", err, out)
+	}
+	for i, want := range []struct {
+		delay  int64
+		option string
+	}{
+		{30, ""},
+		{10, "to1-failure-delay"},
+		{45, "to2-failure-delay"},
+	} {
+		if got := report.Directives[i]; got.EffectiveDelaySeconds != want.delay || got.DelayOption != want.option {
+			t.Errorf("directive %d delay = %ds from %q, want %ds from %q", i, got.EffectiveDelaySeconds, got.DelayOption, want.delay, want.option)
+		}
+	}
+}
+
+func TestRv_NoUsableDirective(t *testing.T) {
+	dc := newTestBlobCred(t, FDO_STATE_PRE_TO1)
+	dc.DC.RvInfo = [][]protocol.RvInstruction{
+		testRvDirective(t, "http://127.0.0.1:8041", protocol.RVProtHTTP,
+			testRvInstruction(t, protocol.RVOwnerOnly, nil)),
+	}
+	path := writeTestBlobCred(t, dc)
+
+	out, err := runCommand(t, "rv", "--blob", path)
+	if err == nil || !strings.Contains(err.Error(), "no rendezvous information found") {
+		t.Errorf("error = %v, want no usable rendezvous information:\n%s", err, out)
+	}
+}
+
+func TestRv_Probe(t *testing.T) {
+	httpSrv := httptest.NewServer(http.NotFoundHandler())
+	defer httpSrv.Close()
+	tlsSrv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer tlsSrv.Close()
+	coapSrv := coaptest.NewServer(nil)
+	defer coapSrv.Close()
+
+	dc := newTestBlobCred(t, FDO_STATE_PRE_TO1)
+	dc.DC.RvInfo = [][]protocol.RvInstruction{
+		testRvDirective(t, httpSrv.URL, protocol.RVProtHTTP),
+		testRvDirective(t, tlsSrv.URL, protocol.RVProtHTTPS),
+		testRvDirective(t, coapSrv.URL, protocol.RVProtCoapUDP),
+	}
+	path := writeTestBlobCred(t, dc)
+
+	t.Run("reachable", func(t *testing.T) {
+		out, err := runCommand(t, "rv", "--blob", path, "--probe", "--insecure-tls", "--output", "json")
+		if err != nil {
+			t.Fatalf("rv --probe failed: %v\n%s", err, out)
+		}
+		var report rvPlanReport
+		if err := json.Unmarshal([]byte(out), &report); err != nil {
+			t.Fatalf("invalid JSON output: %v\n%s", err, out)
+		}
+		if report.Probes == nil || report.Probes.Reachable != 3 || report.Probes.Unreachable != 0 {
+			t.Fatalf("probe summary = %+v, want 3 reachable", report.Probes)
+		}
+		if probe := report.Directives[1].Probes[0]; probe.TLS == "" || !probe.TCP || probe.Role != "rv" {
+			t.Errorf("TLS probe = %+v", probe)
+		}
+		if probe := report.Directives[2].Probes[0]; !probe.CoAP || probe.TCP {
+			t.Errorf("CoAP probe = %+v", probe)
+		}
+	})
+
+	t.Run("certificate not trusted", func(t *testing.T) {
+		out, err := runCommand(t, "rv", "--blob", path, "--probe")
+		if err == nil {
+			t.Fatalf("expected probe of untrusted TLS server to fail:\n%s", out)
+		}
+	})
+
+	t.Run("connection refused", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		closedURL := "http://" + l.Addr().String()
+		_ = l.Close()
+
+		dc := newTestBlobCred(t, FDO_STATE_PRE_TO1)
+		dc.DC.RvInfo = [][]protocol.RvInstruction{testRvDirective(t, closedURL, protocol.RVProtHTTP)}
+		path := writeTestBlobCred(t, dc)
+
+		out, err := runCommand(t, "rv", "--blob", path, "--probe")
+		if err == nil {
+			t.Fatalf("expected probe of closed port to fail:\n%s", out)
+		}
+	})
+}
+
+func TestRv_ProbeTLSOptions(t *testing.T) {
+	tlsSrv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer tlsSrv.Close()
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsSrv.Certificate().Raw}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	dc := newTestBlobCred(t, FDO_STATE_PRE_TO1)
+	dc.DC.RvInfo = [][]protocol.RvInstruction{testRvDirective(t, tlsSrv.URL, protocol.RVProtHTTPS)}
+	rvPath := writeTestBlobCred(t, dc)
+	dc.DC.RvInfo = [][]protocol.RvInstruction{testRvDirective(t, tlsSrv.URL, protocol.RVProtHTTPS,
+		testRvInstruction(t, protocol.RVBypass, nil))}
+	ownerPath := writeTestBlobCred(t, dc)
+
+	t.Run("TO1 CA bundle", func(t *testing.T) {
+		if out, err := runCommand(t, "rv", "--blob", rvPath, "--probe", "--to1-tls-ca-file", caFile); err != nil {
+			t.Fatalf("rv --probe failed: %v\n%s", err, out)
+		}
+	})
+
+	t.Run("TO2 CA bundle for owner URLs", func(t *testing.T) {
+		if out, err := runCommand(t, "rv", "--blob", ownerPath, "--probe", "--to1-tls-ca-file", caFile); err == nil {
+			t.Fatalf("owner URL verified with the TO1 CA bundle:\n%s", out)
+		}
+		if out, err := runCommand(t, "rv", "--blob", ownerPath, "--probe", "--to2-tls-ca-file", caFile); err != nil {
+			t.Fatalf("rv --probe failed: %v\n%s", err, out)
+		}
+	})
+}
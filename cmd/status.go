@@ -0,0 +1,197 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+package cmd
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/fido-device-onboard/go-fdo-client/internal/tpm_utils"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes returned by the status command for each device state.
+const (
+	statusExitIdle   = 0
+	statusExitPreDI  = 10
+	statusExitPreTO1 = 11
+	statusExitResale = 12
+	statusExitError  = 13
+)
+
+var validStatusOutputs = []string{"text", "json"}
+
+// deviceStatusReport is the machine-readable form of the device status.
+type deviceStatusReport struct {
+	State      string           `json:"state"`
+	GUID       string           `json:"guid,omitempty"`
+	Storage    string           `json:"storage"`
+	Location   string           `json:"location"`
+	Onboarding string           `json:"onboarding"`
+	Progress   *onboardProgress `json:"progress,omitempty"`
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Report the device onboarding status",
+	Long: `Report the FDO state of the device, its credential GUID, the credential
+storage backend and the outcome of the last onboarding.
+
+When onboarding progress is persisted (see --progress-file), the number of
+attempts, the last directive tried, the last error and the time of the first
+and last attempt are reported as well.
+
+The exit code reflects the device state so that scripts can act on it
+without parsing the output:
+  0   IDLE     onboarding completed
+  1            status could not be determined
+  10  PRE_DI   device initialization (DI) required
+  11  PRE_TO1  ready for onboarding (TO1/TO2)
+  12  RESALE   ready for resale onboarding
+  13  ERROR    device credential is in an error state
+
+With --pending the exit code is 0 while the device is ready for onboarding
+(PRE_TO1) and 1 in any other state, as expected by the ExecCondition= of the
+systemd units written by install-service.`,
+	Example: `  # Print the status of a blob credential as JSON:
+  go-fdo-client status --blob cred.bin --output json
+
+  # Print the status of a TPM credential:
+  go-fdo-client status --tpm /dev/tpmrm0`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		output, err := cmd.Flags().GetString("output")
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(validStatusOutputs, output) {
+			return fmt.Errorf("invalid output format: '%s', options [%s]", output, strings.Join(validStatusOutputs, ", "))
+		}
+		pending, err := cmd.Flags().GetBool("pending")
+		if err != nil {
+			return err
+		}
+
+		if rootConfig.TPM != "" {
+			tpmc, err = tpm_utils.TpmOpen(rootConfig.TPM)
+			if err != nil {
+				return err
+			}
+			defer tpmc.Close()
+		}
+
+		state, report, err := loadStatusReport()
+		if err != nil {
+			return fmt.Errorf("load device status failed: %w", err)
+		}
+		if err := writeStatusReport(cmd.OutOrStdout(), report, output); err != nil {
+			return err
+		}
+
+		// The report has already been printed, the exit code carries the state
+		if pending {
+			if state != FDO_STATE_PRE_TO1 {
+				cmd.SilenceErrors = true
+				return &exitCodeError{code: 1}
+			}
+			return nil
+		}
+		if code := statusExitCode(state); code != statusExitIdle {
+			cmd.SilenceErrors = true
+			return &exitCodeError{code: code}
+		}
+		return nil
+	},
+}
+
+func statusCmdInit() {
+	rootCmd.AddCommand(statusCmd)
+	statusCmd.Flags().String("output", "text", "Output format [options: text, json]")
+	statusCmd.Flags().Bool("pending", false, "Exit with 0 only while the device is ready for onboarding (PRE_TO1), 1 otherwise")
+}
+
+func init() {
+	statusCmdInit()
+}
+
+// loadStatusReport collects the device state and credential details from the
+// configured storage backend.
+func loadStatusReport() (FdoDeviceState, *deviceStatusReport, error) {
+	state, err := loadDeviceStatus()
+	if err != nil {
+		return state, nil, err
+	}
+
+	report := &deviceStatusReport{
+		State:      state.String(),
+		Onboarding: onboardingOutcome(state),
+	}
+	if rootConfig.TPM != "" {
+		report.Storage = "tpm"
+		report.Location = fmt.Sprintf("%s:0x%08X", rootConfig.TPM, FDO_CRED_NV_IDX)
+	} else {
+		report.Storage = "blob"
+		report.Location = rootConfig.Blob
+	}
+
+	if state != FDO_STATE_PRE_DI {
+		dc, _, err := readStoredCred()
+		if err != nil {
+			return state, nil, err
+		}
+		report.GUID = hex.EncodeToString(dc.GUID[:])
+		report.Progress = storedProgress(report.GUID)
+	}
+
+	return state, report, nil
+}
+
+// onboardingOutcome describes the result of the last onboarding for a state.
+func onboardingOutcome(state FdoDeviceState) string {
+	switch state {
+	case FDO_STATE_PRE_DI:
+		return "none"
+	case FDO_STATE_PRE_TO1, FDO_STATE_RESALE:
+		return "pending"
+	case FDO_STATE_IDLE:
+		return "completed"
+	default:
+		return "failed"
+	}
+}
+
+func statusExitCode(state FdoDeviceState) int {
+	switch state {
+	case FDO_STATE_IDLE:
+		return statusExitIdle
+	case FDO_STATE_PRE_DI:
+		return statusExitPreDI
+	case FDO_STATE_PRE_TO1:
+		return statusExitPreTO1
+	case FDO_STATE_RESALE:
+		return statusExitResale
+	default:
+		return statusExitError
+	}
+}
+
+func writeStatusReport(w io.Writer, report *deviceStatusReport, output string) error {
+	if output == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+
+	fmt.Fprintf(w, "State:      %s\n", report.State)
+	if report.GUID != "" {
+		fmt.Fprintf(w, "GUID:       %s\n", report.GUID)
+	}
+	fmt.Fprintf(w, "Storage:    %s (%s)\n", report.Storage, report.Location)
+	fmt.Fprintf(w, "Onboarding: %s\n", report.Onboarding)
+	writeProgressText(w, report.Progress, "Progress:   ")
+	return nil
+}
@@ -0,0 +1,84 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+package cmd
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStatus_ExitCodes(t *testing.T) {
+	tests := []struct {
+		state FdoDeviceState
+		want  int
+	}{
+		{FDO_STATE_PRE_TO1, statusExitPreTO1},
+		{FDO_STATE_IDLE, statusExitIdle},
+		{FDO_STATE_RESALE, statusExitResale},
+		{FDO_STATE_ERROR, statusExitError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.state.String(), func(t *testing.T) {
+			path := writeTestBlobCred(t, newTestBlobCred(t, tt.state))
+			_, err := runCommand(t, "status", "--blob", path)
+			if got := ExitCode(err); got != tt.want {
+				t.Errorf("exit code = %d, want %d (err: %v)", got, tt.want, err)
+			}
+		})
+	}
+
+	t.Run("missing credential", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "missing.bin")
+		out, err := runCommand(t, "status", "--blob", path)
+		if got := ExitCode(err); got != statusExitPreDI {
+			t.Errorf("exit code = %d, want %d (err: %v)", got, statusExitPreDI, err)
+		}
+		if !strings.Contains(out, "PRE_DI") {
+			t.Errorf("output = %q, want PRE_DI state", out)
+		}
+	})
+
+	t.Run("invalid output format", func(t *testing.T) {
+		path := writeTestBlobCred(t, newTestBlobCred(t, FDO_STATE_IDLE))
+		_, err := runCommand(t, "status", "--blob", path, "--output", "xml")
+		if got := ExitCode(err); got != 1 {
+			t.Errorf("exit code = %d, want 1 (err: %v)", got, err)
+		}
+	})
+}
+
+func TestStatus_JSONOutput(t *testing.T) {
+	dc := newTestBlobCred(t, FDO_STATE_IDLE)
+	path := writeTestBlobCred(t, dc)
+
+	out, err := runCommand(t, "status", "--blob", path, "--output", "json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var report deviceStatusReport
+	if err := json.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, out)
+	}
+
+	checks := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"State", report.State, "IDLE"},
+		{"GUID", report.GUID, hex.EncodeToString(dc.DC.GUID[:])},
+		{"Storage", report.Storage, "blob"},
+		{"Location", report.Location, path},
+		{"Onboarding", report.Onboarding, "completed"},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %q, want %q", c.name, c.got, c.want)
+		}
+	}
+}
@@ -0,0 +1,504 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+package cmd
+
+import (
+	"crypto"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"net/url"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fido-device-onboard/go-fdo"
+	"github.com/fido-device-onboard/go-fdo-client/internal/chaos"
+	"github.com/fido-device-onboard/go-fdo-client/internal/coap"
+	"github.com/fido-device-onboard/go-fdo-client/internal/recording"
+	fdotls "github.com/fido-device-onboard/go-fdo-client/internal/tls"
+	"github.com/spf13/cobra"
+)
+
+// to1TLSConfig and to2TLSConfig are the TLS client configurations of the TO1
+// and TO2 connections, and onboardTransportOptions their connection options,
+// loaded by loadOnboardTransport. Nil configurations use the system roots.
+var (
+	to1TLSConfig, to2TLSConfig *tls.Config
+	onboardTransportOptions    fdotls.TransportOptions
+)
+
+// traceLog is the logger of the trace file, opened by traceLogger and left
+// open for the lifetime of the process.
+var traceLog *slog.Logger
+
+// messageSession records or replays the FDO messages of the process. It is
+// opened once by openSession, so that the retries of the daemon are recorded
+// in one file.
+var messageSession *session
+
+// faultInjector injects the configured faults into the TO1 and TO2 messages,
+// loaded by loadOnboardTransport. Nil injects none.
+var faultInjector *chaos.Injector
+
+// tpmClientKey is the tls-client-key that selects the device key in the TPM.
+const tpmClientKey = "tpm"
+
+// directProxy is the proxy that disables proxies, including those of the
+// proxy environment variables.
+const directProxy = "direct"
+
+var (
+	validTLSVersions       = []string{"1.2", "1.3"}
+	validTLSCipherPolicies = []string{"default", "compatible"}
+	validProxySchemes      = []string{"http", "https", "socks5", "socks5h"}
+	validFaultActions      = []string{"drop", "delay", "truncate", "http-error", "dns-error"}
+)
+
+// addTransportFlags registers the flags of the connection options on cmd.
+func addTransportFlags(cmd *cobra.Command) {
+	cmd.Flags().Duration("connect-timeout", 30*time.Second, "Timeout of establishing TCP connections")
+	cmd.Flags().Duration("tls-handshake-timeout", 10*time.Second, "Timeout of TLS handshakes")
+	cmd.Flags().Duration("request-timeout", 0, "Timeout of each HTTP request including reading the response (0=unlimited)")
+	cmd.Flags().String("proxy", "", "Proxy URL (http, https or socks5) instead of the proxy environment variables, or \"direct\" to disable proxies")
+	cmd.Flags().StringSlice("no-proxy", nil, "Hosts, domains or CIDR ranges connected to directly instead of through --proxy (repeatable)")
+	cmd.Flags().String("proxy-credentials-file", "", "File holding the user:password of --proxy, readable by its owner only")
+	cmd.Flags().String("source-address", "", "Local IP address to make connections from")
+	cmd.Flags().String("interface", "", "Network interface to bind connections to (Linux only)")
+	cmd.Flags().String("tls-min-version", "1.2", "Minimum TLS version [options: 1.2, 1.3]")
+	cmd.Flags().String("tls-cipher-policy", "default", "TLS 1.2 cipher suites: default (AES-256-GCM) or compatible (adds AES-128-GCM and ChaCha20-Poly1305)")
+	cmd.Flags().String("record", "", "File to record the exchanged FDO messages to, including decrypted service info, for reproducing the session with --replay")
+	cmd.Flags().String("replay", "", "File of a session recorded with --record to replay instead of connecting to the servers, up to the first TO2 message")
+}
+
+// validate checks the connection options that do not depend on the host.
+func (t *TransportConfig) validate() error {
+	if t.ConnectTimeout < 0 || t.TLSHandshakeTimeout < 0 || t.RequestTimeout < 0 {
+		return fmt.Errorf("connect-timeout, tls-handshake-timeout and request-timeout must not be negative")
+	}
+	if t.TLSMinVersion != "" && !slices.Contains(validTLSVersions, t.TLSMinVersion) {
+		return fmt.Errorf("invalid TLS version: '%s', options [%s]", t.TLSMinVersion, strings.Join(validTLSVersions, ", "))
+	}
+	if t.TLSCipherPolicy != "" && !slices.Contains(validTLSCipherPolicies, t.TLSCipherPolicy) {
+		return fmt.Errorf("invalid TLS cipher policy: '%s', options [%s]", t.TLSCipherPolicy, strings.Join(validTLSCipherPolicies, ", "))
+	}
+	if t.Record != "" && t.Replay != "" {
+		return fmt.Errorf("record and replay are mutually exclusive")
+	}
+	if len(t.NoProxy) > 0 && (t.Proxy == "" || t.Proxy == directProxy) {
+		return fmt.Errorf("no-proxy requires a proxy URL, use NO_PROXY with the proxy environment variables")
+	}
+	if t.ProxyCredentialsFile != "" && (t.Proxy == "" || t.Proxy == directProxy) {
+		return fmt.Errorf("proxy-credentials-file requires a proxy URL")
+	}
+	opts, err := t.transportOptions()
+	if err == nil && t.ProxyCredentialsFile != "" && opts.Proxy.User != nil {
+		return fmt.Errorf("proxy-credentials-file cannot be used with credentials in the proxy URL")
+	}
+	return err
+}
+
+// checkProxyFlag refuses credentials in the --proxy flag of cmd, which other
+// users can read from the command line of the process.
+func checkProxyFlag(cmd *cobra.Command) error {
+	flag := cmd.Flags().Lookup("proxy")
+	if flag == nil || !flag.Changed {
+		return nil
+	}
+	if u, err := url.Parse(flag.Value.String()); err == nil && u.User != nil {
+		return fmt.Errorf("--proxy must not hold credentials, which other users can read from the process command line: " +
+			"use --proxy-credentials-file, the configuration file or the environment instead")
+	}
+	return nil
+}
+
+// connectOptions returns the connection options with the proxy credentials
+// of the credentials file.
+func (t *TransportConfig) connectOptions() (fdotls.TransportOptions, error) {
+	opts, err := t.transportOptions()
+	if err != nil || t.ProxyCredentialsFile == "" || opts.Proxy == nil {
+		return opts, err
+	}
+	f, err := os.Open(t.ProxyCredentialsFile)
+	if err != nil {
+		return opts, fmt.Errorf("failed to open proxy credentials file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	info, err := f.Stat()
+	if err != nil {
+		return opts, fmt.Errorf("failed to open proxy credentials file: %w", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0o077 != 0 {
+		return opts, fmt.Errorf("proxy credentials file %s must only be accessible by its owner (mode 0600), got %04o", t.ProxyCredentialsFile, info.Mode().Perm())
+	}
+	data, err := io.ReadAll(io.LimitReader(f, 4096))
+	if err != nil {
+		return opts, fmt.Errorf("failed to read proxy credentials file: %w", err)
+	}
+	credentials := strings.TrimRight(string(data), "\r\n")
+	if credentials == "" {
+		return opts, fmt.Errorf("proxy credentials file %s is empty", t.ProxyCredentialsFile)
+	}
+	user, password, hasPassword := strings.Cut(credentials, ":")
+	proxy := *opts.Proxy
+	if proxy.User = url.User(user); hasPassword {
+		proxy.User = url.UserPassword(user, password)
+	}
+	opts.Proxy = &proxy
+	return opts, nil
+}
+
+// transportOptions returns the connection options.
+func (t *TransportConfig) transportOptions() (fdotls.TransportOptions, error) {
+	opts := fdotls.TransportOptions{
+		ConnectTimeout:      t.ConnectTimeout,
+		TLSHandshakeTimeout: t.TLSHandshakeTimeout,
+		RequestTimeout:      t.RequestTimeout,
+		NoProxy:             t.NoProxy,
+		Direct:              t.Proxy == directProxy,
+		Interface:           t.Interface,
+	}
+	if t.Proxy != "" && !opts.Direct {
+		u, err := url.Parse(t.Proxy)
+		if err != nil || u.Host == "" || !slices.Contains(validProxySchemes, u.Scheme) {
+			return opts, fmt.Errorf("invalid proxy URL, expected %s://[user:password@]host:port", strings.Join(validProxySchemes, "|"))
+		}
+		opts.Proxy = u
+	}
+	if t.SourceAddress != "" {
+		if opts.SourceAddress = net.ParseIP(t.SourceAddress); opts.SourceAddress == nil {
+			return opts, fmt.Errorf("invalid source address: %s", t.SourceAddress)
+		}
+	}
+	return opts, nil
+}
+
+// tlsVersion returns the minimum TLS version.
+func (t *TransportConfig) tlsVersion() uint16 {
+	if t.TLSMinVersion == "1.3" {
+		return tls.VersionTLS13
+	}
+	return tls.VersionTLS12
+}
+
+// cipherSuites returns the TLS 1.2 cipher suites of the cipher policy, nil
+// for the default cipher suites.
+func (t *TransportConfig) cipherSuites() []uint16 {
+	if t.TLSCipherPolicy == "compatible" {
+		return fdotls.CompatibleCipherSuites()
+	}
+	return nil
+}
+
+// tlsOptions returns the options verifying the DI server.
+func (d *DeviceInitConfig) tlsOptions() fdotls.Options {
+	return fdotls.Options{
+		CAFile:             d.TLSCAFile,
+		PinSPKI:            d.TLSPinSPKI,
+		ServerName:         d.TLSServerName,
+		InsecureSkipVerify: d.InsecureTLS,
+		ClientCertFile:     d.TLSClientCert,
+		ClientKeyFile:      d.TLSClientKey,
+		MinVersion:         d.tlsVersion(),
+		CipherSuites:       d.cipherSuites(),
+	}
+}
+
+// to1TLSOptions returns the options verifying the rendezvous servers.
+func (o *OnboardConfig) to1TLSOptions() fdotls.Options {
+	return fdotls.Options{
+		CAFile:             o.TO1TLSCAFile,
+		PinSPKI:            o.TO1TLSPinSPKI,
+		ServerName:         o.TO1TLSServerName,
+		InsecureSkipVerify: o.InsecureTLS,
+		ClientCertFile:     o.TLSClientCert,
+		ClientKeyFile:      o.TLSClientKey,
+		MinVersion:         o.tlsVersion(),
+		CipherSuites:       o.cipherSuites(),
+	}
+}
+
+// to2TLSOptions returns the options verifying the owner servers.
+func (o *OnboardConfig) to2TLSOptions() fdotls.Options {
+	return fdotls.Options{
+		CAFile:             o.TO2TLSCAFile,
+		PinSPKI:            o.TO2TLSPinSPKI,
+		ServerName:         o.TO2TLSServerName,
+		InsecureSkipVerify: o.InsecureTLS,
+		ClientCertFile:     o.TLSClientCert,
+		ClientKeyFile:      o.TLSClientKey,
+		MinVersion:         o.tlsVersion(),
+		CipherSuites:       o.cipherSuites(),
+	}
+}
+
+// validateTLSOptions checks the TLS options that do not depend on the host.
+func validateTLSOptions(opts fdotls.Options) error {
+	for _, pin := range opts.PinSPKI {
+		if _, err := fdotls.ParseSPKIPin(pin); err != nil {
+			return err
+		}
+	}
+	if opts.ServerName != "" && !isValidHostname(opts.ServerName) {
+		return fmt.Errorf("invalid TLS server name: %s", opts.ServerName)
+	}
+	return nil
+}
+
+// validateClientCert checks that the client certificate and key are set
+// together, and that the TPM client key is only used with a TPM.
+func validateClientCert(certFile, keyFile string, useTPM bool) error {
+	if (certFile == "") != (keyFile == "") {
+		return fmt.Errorf("tls-client-cert and tls-client-key must be set together")
+	}
+	if keyFile == tpmClientKey && !useTPM {
+		return fmt.Errorf("tls-client-key %s requires --tpm", tpmClientKey)
+	}
+	return nil
+}
+
+// describeTLSOptions summarizes how the options verify servers.
+func describeTLSOptions(opts fdotls.Options) string {
+	var parts []string
+	switch {
+	case opts.InsecureSkipVerify:
+		parts = append(parts, "certificate verification disabled")
+	case opts.CAFile != "":
+		parts = append(parts, "CA bundle "+opts.CAFile)
+	default:
+		parts = append(parts, "system roots")
+	}
+	switch len(opts.PinSPKI) {
+	case 0:
+	case 1:
+		parts = append(parts, "1 SPKI pin")
+	default:
+		parts = append(parts, fmt.Sprintf("%d SPKI pins", len(opts.PinSPKI)))
+	}
+	if opts.ServerName != "" {
+		parts = append(parts, "server name "+opts.ServerName)
+	}
+	if opts.ClientCertFile != "" {
+		parts = append(parts, "client certificate "+opts.ClientCertFile)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// tlsConfig returns the TLS client configuration of the options, with the
+// device key signing for the client certificate if the TPM client key is
+// selected.
+func tlsConfig(opts fdotls.Options, deviceKey crypto.Signer) (*tls.Config, error) {
+	if opts.ClientKeyFile == tpmClientKey {
+		if deviceKey == nil {
+			return nil, errors.New("the TPM device key is not available for the client certificate")
+		}
+		opts.ClientKeyFile, opts.ClientKey = "", deviceKey
+	}
+	return fdotls.Config(opts)
+}
+
+// traceLogger returns the logger of the FDO message trace: the trace file if
+// one is set, the default logger with --debug, or nil to disable tracing.
+func traceLogger() (*slog.Logger, error) {
+	switch {
+	case traceLog != nil:
+		return traceLog, nil
+	case rootConfig.TraceFile != "":
+		f, err := os.OpenFile(rootConfig.TraceFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open trace file: %w", err)
+		}
+		traceLog = slog.New(slog.NewTextHandler(f, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		return traceLog, nil
+	case rootConfig.Debug:
+		return slog.Default(), nil
+	}
+	return nil, nil
+}
+
+// loadOnboardTransport reads the CA bundles and the client certificate of the
+// TO1 and TO2 connections, and their connection options. The device key is
+// used for the TPM client key.
+func loadOnboardTransport(deviceKey crypto.Signer) error {
+	var err error
+	if onboardTransportOptions, err = onboardConfig.Onboard.connectOptions(); err != nil {
+		return err
+	}
+	if onboardTransportOptions.Trace, err = traceLogger(); err != nil {
+		return err
+	}
+	if to1TLSConfig, err = tlsConfig(onboardConfig.Onboard.to1TLSOptions(), deviceKey); err != nil {
+		return fmt.Errorf("TO1 TLS: %w", err)
+	}
+	if to2TLSConfig, err = tlsConfig(onboardConfig.Onboard.to2TLSOptions(), deviceKey); err != nil {
+		return fmt.Errorf("TO2 TLS: %w", err)
+	}
+	faults, err := onboardConfig.Onboard.faults()
+	if err != nil {
+		return err
+	}
+	faultInjector = nil
+	if len(faults) > 0 {
+		slog.Warn("Injecting faults into the TO1 and TO2 messages, for testing only", "faults", len(faults))
+		faultInjector = chaos.New(faults)
+	}
+	return nil
+}
+
+// faults returns the faults injected into the TO1 and TO2 messages.
+func (o *OnboardConfig) faults() ([]chaos.Fault, error) {
+	var faults []chaos.Fault
+	for i, f := range o.Faults {
+		switch {
+		case !slices.Contains(validFaultActions, f.Action):
+			return nil, fmt.Errorf("faults[%d]: invalid action: '%s', options [%s]", i, f.Action, strings.Join(validFaultActions, ", "))
+		case f.MsgType < 0 || f.MsgType > math.MaxUint8:
+			return nil, fmt.Errorf("faults[%d]: msg-type must be between 0 and %d", i, math.MaxUint8)
+		case f.Delay < 0 || f.Count < 0:
+			return nil, fmt.Errorf("faults[%d]: delay and count must not be negative", i)
+		case f.Action == string(chaos.Delay) && f.Delay == 0:
+			return nil, fmt.Errorf("faults[%d]: the delay action requires a delay", i)
+		case f.Action == string(chaos.HTTPError) && (f.Status < 400 || f.Status > 599):
+			return nil, fmt.Errorf("faults[%d]: the http-error action requires a status between 400 and 599", i)
+		}
+		faults = append(faults, chaos.Fault{
+			MsgType: uint8(f.MsgType),
+			URL:     f.URL,
+			Action:  chaos.Action(f.Action),
+			Delay:   f.Delay,
+			Status:  f.Status,
+			Count:   f.Count,
+		})
+	}
+	return faults, nil
+}
+
+// session records the FDO messages exchanged with the servers, or replays a
+// recorded session instead.
+type session struct {
+	recorder *recording.Recorder
+	replayer *recording.Replayer
+}
+
+// openSession opens the record or replay file of the connection options.
+func openSession(t *TransportConfig) (*session, error) {
+	if messageSession != nil {
+		return messageSession, nil
+	}
+	s := &session{}
+	switch {
+	case t.Replay != "":
+		f, err := os.Open(t.Replay)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open replay file: %w", err)
+		}
+		defer func() { _ = f.Close() }()
+		if s.replayer, err = recording.NewReplayer(f); err != nil {
+			return nil, fmt.Errorf("%s: %w", t.Replay, err)
+		}
+		slog.Info("Replaying recorded FDO session", "file", t.Replay, "exchanges", s.replayer.Remaining())
+	case t.Record != "":
+		// Left open for the lifetime of the process
+		f, err := os.OpenFile(t.Record, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open record file: %w", err)
+		}
+		s.recorder = recording.NewRecorder(f)
+	}
+	messageSession = s
+	return s, nil
+}
+
+// replaying reports whether the session is replayed.
+func (s *session) replaying() bool {
+	return s != nil && s.replayer != nil
+}
+
+// transport returns the transport to the server at baseURL: the replay of the
+// recorded session, or the transport made by dial, recorded if recording.
+func (s *session) transport(baseURL string, dial func() fdo.Transport) fdo.Transport {
+	switch {
+	case s.replaying():
+		return s.replayer.Transport(baseURL)
+	case s != nil && s.recorder != nil:
+		return s.recorder.Transport(baseURL, dial())
+	}
+	return dial()
+}
+
+// replayEnded reports whether err ends a replayed session, which cannot go on
+// once the client diverges from the recording or reaches TO2.
+func replayEnded(err error) bool {
+	return errors.Is(err, recording.ErrDiverged) || errors.Is(err, recording.ErrTO2)
+}
+
+// warnRecordErr logs a failure to write the record file.
+func (s *session) warnRecordErr() {
+	if s == nil || s.recorder == nil {
+		return
+	}
+	if err := s.recorder.Err(); err != nil {
+		slog.Warn("Recording the FDO messages failed", "error", err)
+	}
+}
+
+// diTransport returns the transport of the DI connection. The device key is
+// used for the TPM client key.
+func diTransport(deviceKey crypto.Signer) (fdo.Transport, error) {
+	s, err := openSession(&diConf.DeviceInit.TransportConfig)
+	if err != nil {
+		return nil, err
+	}
+	if s.replaying() {
+		return s.replayer.Transport(diConf.DeviceInit.ServerURL), nil
+	}
+	conf, err := tlsConfig(diConf.DeviceInit.tlsOptions(), deviceKey)
+	if err != nil {
+		return nil, fmt.Errorf("DI TLS: %w", err)
+	}
+	opts, err := diConf.DeviceInit.connectOptions()
+	if err != nil {
+		return nil, err
+	}
+	if opts.Trace, err = traceLogger(); err != nil {
+		return nil, err
+	}
+	return s.transport(diConf.DeviceInit.ServerURL, func() fdo.Transport {
+		return fdotls.TlsTransport(diConf.DeviceInit.ServerURL, conf, diConf.DeviceInit.InsecureTLS, opts)
+	}), nil
+}
+
+// to1Transport returns the transport of a TO1 connection to a rendezvous
+// server.
+func to1Transport(baseURL string) fdo.Transport {
+	return messageSession.transport(baseURL, func() fdo.Transport {
+		return faultInjector.Transport(baseURL, onboardTransport(baseURL, to1TLSConfig))
+	})
+}
+
+// to2Transport returns the transport of a TO2 connection to an owner server.
+func to2Transport(baseURL string) fdo.Transport {
+	return messageSession.transport(baseURL, func() fdo.Transport {
+		return faultInjector.Transport(baseURL, onboardTransport(baseURL, to2TLSConfig))
+	})
+}
+
+// onboardTransport returns the transport of the scheme of a TO1 or TO2 URL,
+// CoAP for coap URLs and HTTP for the others.
+func onboardTransport(baseURL string, conf *tls.Config) fdo.Transport {
+	if strings.HasPrefix(baseURL, "coap") {
+		return &coap.Transport{
+			BaseURL: baseURL,
+			Dialer:  onboardTransportOptions.Dialer("udp"),
+			Timeout: onboardTransportOptions.RequestTimeout,
+		}
+	}
+	return fdotls.TlsTransport(baseURL, conf, onboardConfig.Onboard.InsecureTLS, onboardTransportOptions)
+}
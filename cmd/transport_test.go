@@ -0,0 +1,84 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fido-device-onboard/go-fdo-client/internal/fdotest"
+	"github.com/fido-device-onboard/go-fdo-client/internal/recording"
+)
+
+// TestRecordReplay_EndToEnd records DI, TO1 and TO2 with go-fdo servers and
+// replays the recordings without them: DI replays into a scratch blob and TO1
+// replays completely, while TO2 is refused.
+func TestRecordReplay_EndToEnd(t *testing.T) {
+	orig := clientContext
+	clientContext = context.Background()
+	t.Cleanup(func() { clientContext = orig })
+	srv := fdotest.NewServer()
+	defer srv.Close()
+
+	dir := t.TempDir()
+	blob, fresh, scratch := filepath.Join(dir, "cred.bin"), filepath.Join(dir, "fresh.bin"), filepath.Join(dir, "scratch.bin")
+	diRecord, onboardRecord := filepath.Join(dir, "di.jsonl"), filepath.Join(dir, "onboard.jsonl")
+	onboardArgs := []string{"onboard", "--key", "ec256", "--kex", "ECDH256", "--default-working-dir", dir, "--max-attempts", "1"}
+
+	if err := runCmd(t, "device-init", srv.URL, "--key", "ec256", "--blob", blob, "--serial-number", "1234", "--device-info", "gotest", "--record", diRecord); err != nil {
+		t.Fatalf("device-init: %v", err)
+	}
+	var dc fdoDeviceCredential
+	if err := readCredFileFrom(blob, &dc); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.RegisterOwner(context.Background(), dc.DC.GUID); err != nil {
+		t.Fatalf("TO0: %v", err)
+	}
+	data, err := os.ReadFile(blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(fresh, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := runCmd(t, append(onboardArgs, "--blob", blob, "--record", onboardRecord)...); err != nil {
+		t.Fatalf("onboard: %v", err)
+	}
+	var onboarded fdoDeviceCredential
+	if err := readCredFileFrom(blob, &onboarded); err != nil || onboarded.State != FDO_STATE_IDLE {
+		t.Fatalf("onboarded credential state = %v, %v, want IDLE", onboarded.State, err)
+	}
+	srv.Close()
+
+	// DI replays into a scratch blob, never over a credential
+	diArgs := []string{"device-init", srv.URL, "--key", "ec256", "--serial-number", "1234", "--device-info", "gotest", "--replay", diRecord}
+	if err := runCmd(t, append(diArgs, "--blob", blob)...); err == nil || !strings.Contains(err.Error(), "does not exist yet") {
+		t.Errorf("replayed DI over a credential: error = %v", err)
+	}
+	if err := runCmd(t, append(diArgs, "--blob", scratch)...); err != nil {
+		t.Fatalf("replayed DI: %v", err)
+	}
+	var replayed fdoDeviceCredential
+	if err := readCredFileFrom(scratch, &replayed); err != nil {
+		t.Fatal(err)
+	}
+	if replayed.DC.GUID != dc.DC.GUID || replayed.State != FDO_STATE_PRE_TO1 {
+		t.Errorf("replayed credential %x in state %v, want %x in PRE_TO1", replayed.DC.GUID, replayed.State, dc.DC.GUID)
+	}
+
+	// TO1 replays up to the owner URL, TO2 is refused
+	err = runCmd(t, append(onboardArgs, "--blob", fresh, "--replay", onboardRecord)...)
+	if !errors.Is(err, recording.ErrTO2) {
+		t.Errorf("replayed onboarding: error = %v, want %v", err, recording.ErrTO2)
+	}
+	var notOnboarded fdoDeviceCredential
+	if err := readCredFileFrom(fresh, &notOnboarded); err != nil || notOnboarded.State != FDO_STATE_PRE_TO1 {
+		t.Errorf("credential state after the replay = %v, %v, want PRE_TO1", notOnboarded.State, err)
+	}
+}
@@ -0,0 +1,462 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+package cmd
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/fido-device-onboard/go-fdo"
+	"github.com/fido-device-onboard/go-fdo-client/internal/tpm_utils"
+	"github.com/fido-device-onboard/go-fdo/cbor"
+	"github.com/fido-device-onboard/go-fdo/protocol"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes returned by the verify command for the first failed check.
+const (
+	verifyExitCredential = 20
+	verifyExitDeviceKey  = 21
+	verifyExitHmac       = 22
+	verifyExitRvInfo     = 23
+	verifyExitState      = 24
+	verifyExitVoucher    = 25
+)
+
+// Results of a single verification check.
+const (
+	verifyPass = "pass"
+	verifyFail = "fail"
+	verifySkip = "skip"
+)
+
+var validVerifyOutputs = []string{"text", "json"}
+
+type verifyCheck struct {
+	Name   string `json:"name"`
+	Result string `json:"result"`
+	Detail string `json:"detail,omitempty"`
+
+	exitCode int
+}
+
+// verifyReport is the itemized result of verifying a device credential.
+type verifyReport struct {
+	GUID    string        `json:"guid,omitempty"`
+	Storage string        `json:"storage"`
+	Passed  bool          `json:"passed"`
+	Checks  []verifyCheck `json:"checks"`
+}
+
+func (r *verifyReport) add(name string, exitCode int, err error) {
+	check := verifyCheck{Name: name, Result: verifyPass, exitCode: exitCode}
+	if err != nil {
+		check.Result = verifyFail
+		check.Detail = err.Error()
+	}
+	r.Checks = append(r.Checks, check)
+}
+
+func (r *verifyReport) skip(name, reason string) {
+	r.Checks = append(r.Checks, verifyCheck{Name: name, Result: verifySkip, Detail: reason})
+}
+
+// exitCode returns the exit code of the first failed check.
+func (r *verifyReport) exitCode() int {
+	for _, check := range r.Checks {
+		if check.Result == verifyFail {
+			return check.exitCode
+		}
+	}
+	return 0
+}
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify that the stored device credential is consistent",
+	Long: `Verify that the stored device credential is internally consistent before a
+device is shipped. The following checks are run and reported individually:
+  credential  the credential decodes from CBOR
+  device-key  the device private key (blob PKCS#8 or TPM key) is usable and
+              signs a challenge that verifies with its public key
+  hmac        the HMAC secret (or TPM HMAC key) produces an ownership voucher
+              header HMAC with SHA-256 and SHA-384
+  rv-info     the rendezvous info parses into at least one usable directive
+  state       the device state is PRE_TO1, IDLE or RESALE
+  voucher     the credential matches the ownership voucher given by --voucher
+
+The device credential does not record the device public key. Pass the
+ownership voucher issued by the manufacturer with --voucher to also check the
+private key against the device certificate and the HMAC against the voucher
+header HMAC.
+
+The exit code identifies the first failed check:
+  0   all checks passed
+  1   verification could not be run
+  20  credential
+  21  device-key
+  22  hmac
+  23  rv-info
+  24  state
+  25  voucher
+
+Verifying a TPM credential requires --key to match the key type used at
+device-init.`,
+	Example: `  # Verify a blob credential against its ownership voucher:
+  go-fdo-client verify --blob cred.bin --voucher device.ov
+
+  # Verify a TPM credential and print the result as JSON:
+  go-fdo-client verify --tpm /dev/tpmrm0 --key ec256 --output json`,
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		output, err := cmd.Flags().GetString("output")
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(validVerifyOutputs, output) {
+			return fmt.Errorf("invalid output format: '%s', options [%s]", output, strings.Join(validVerifyOutputs, ", "))
+		}
+		if rootConfig.Key != "" {
+			return validateKey(rootConfig.Key)
+		}
+		if rootConfig.TPM != "" {
+			return fmt.Errorf("--key is required to verify a TPM credential (via CLI flag or config file)")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		output, err := cmd.Flags().GetString("output")
+		if err != nil {
+			return err
+		}
+		voucherPath, err := cmd.Flags().GetString("voucher")
+		if err != nil {
+			return err
+		}
+		var ov *fdo.Voucher
+		if voucherPath != "" {
+			if ov, err = readVoucherFile(voucherPath); err != nil {
+				return err
+			}
+		}
+
+		if rootConfig.TPM != "" {
+			tpmc, err = tpm_utils.TpmOpen(rootConfig.TPM)
+			if err != nil {
+				return err
+			}
+			defer tpmc.Close()
+		}
+
+		report := verifyCredential(ov)
+		if err := writeVerifyReport(cmd.OutOrStdout(), report, output); err != nil {
+			return err
+		}
+
+		// The report has already been printed, the exit code names the failure
+		if code := report.exitCode(); code != 0 {
+			cmd.SilenceErrors = true
+			return &exitCodeError{code: code}
+		}
+		return nil
+	},
+}
+
+func verifyCmdInit() {
+	rootCmd.AddCommand(verifyCmd)
+	verifyCmd.Flags().String("output", "text", "Output format [options: text, json]")
+	verifyCmd.Flags().String("voucher", "", "Ownership voucher (PEM or CBOR) issued for the device at device-init")
+}
+
+func init() {
+	verifyCmdInit()
+}
+
+// verifyCredential runs all checks against the stored device credential. An
+// ownership voucher is optional.
+func verifyCredential(ov *fdo.Voucher) *verifyReport {
+	report := &verifyReport{Storage: "blob"}
+	if rootConfig.TPM != "" {
+		report.Storage = "tpm"
+	}
+
+	var (
+		dc         fdo.DeviceCredential
+		state      FdoDeviceState
+		hmacSecret []byte
+		key        crypto.Signer
+		hmacSha256 hash.Hash
+		hmacSha384 hash.Hash
+		keyErr     error
+	)
+	if rootConfig.TPM != "" {
+		var cred fdoTpmDeviceCredential
+		if err := readTpmCred(&cred); err != nil {
+			report.add("credential", verifyExitCredential, err)
+			return report
+		}
+		dc, state = cred.DC.DeviceCredential, cred.State
+		var cleanup func() error
+		hmacSha256, hmacSha384, key, cleanup, keyErr = tpmCred()
+		if keyErr == nil {
+			defer func() { _ = cleanup() }()
+		}
+	} else {
+		var cred fdoDeviceCredential
+		if err := readCredFile(&cred); err != nil {
+			report.add("credential", verifyExitCredential, err)
+			return report
+		}
+		dc, state = cred.DC.DeviceCredential, cred.State
+		hmacSecret = cred.DC.HmacSecret
+		hmacSha256, hmacSha384 = cred.DC.HMACs()
+		if !cred.DC.PrivateKey.IsValid() {
+			keyErr = errors.New("private key is missing or of an unsupported type")
+		} else {
+			key = cred.DC.PrivateKey.Signer
+		}
+	}
+	report.GUID = hex.EncodeToString(dc.GUID[:])
+	report.add("credential", verifyExitCredential, nil)
+
+	if keyErr != nil {
+		report.add("device-key", verifyExitDeviceKey, keyErr)
+		if rootConfig.TPM != "" {
+			report.skip("hmac", "TPM keys could not be loaded")
+		} else {
+			report.add("hmac", verifyExitHmac, verifyHmac(dc, hmacSecret, hmacSha256, hmacSha384, ov))
+		}
+	} else {
+		report.add("device-key", verifyExitDeviceKey, verifyDeviceKey(key, ov))
+		report.add("hmac", verifyExitHmac, verifyHmac(dc, hmacSecret, hmacSha256, hmacSha384, ov))
+	}
+	report.add("rv-info", verifyExitRvInfo, verifyRvInfo(dc.RvInfo))
+	report.add("state", verifyExitState, verifyState(state))
+	if ov == nil {
+		report.skip("voucher", "no ownership voucher given")
+	} else {
+		report.add("voucher", verifyExitVoucher, verifyVoucher(dc, ov))
+	}
+
+	report.Passed = report.exitCode() == 0
+	return report
+}
+
+// verifyDeviceKey checks that the device key matches the configured key type,
+// signs a challenge that verifies with its public key and, when a voucher is
+// given, matches the device certificate.
+func verifyDeviceKey(key crypto.Signer, ov *fdo.Voucher) error {
+	pub := key.Public()
+	if rootConfig.Key != "" {
+		if err := matchKeyType(pub, rootConfig.Key); err != nil {
+			return err
+		}
+	}
+
+	challenge := make([]byte, 32)
+	if _, err := rand.Read(challenge); err != nil {
+		return fmt.Errorf("error generating challenge: %w", err)
+	}
+	hashAlg := crypto.SHA256
+	if pub, ok := pub.(*ecdsa.PublicKey); ok && pub.Curve == elliptic.P384() {
+		hashAlg = crypto.SHA384
+	}
+	h := hashAlg.New()
+	_, _ = h.Write(challenge)
+	digest := h.Sum(nil)
+
+	sig, err := key.Sign(rand.Reader, digest, hashAlg)
+	if err != nil {
+		return fmt.Errorf("error signing with device key: %w", err)
+	}
+	switch pub := pub.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(pub, digest, sig) {
+			return errors.New("ECDSA signature does not verify with the device public key")
+		}
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(pub, hashAlg, digest, sig); err != nil {
+			return fmt.Errorf("RSA signature does not verify with the device public key: %w", err)
+		}
+	default:
+		return fmt.Errorf("unsupported device public key type %T", pub)
+	}
+
+	if ov == nil {
+		return nil
+	}
+	certPub, err := ov.DevicePublicKey()
+	if err != nil {
+		return fmt.Errorf("ownership voucher device certificate: %w", err)
+	}
+	if certPub == nil {
+		return errors.New("ownership voucher has no device certificate chain")
+	}
+	if eq, ok := pub.(interface{ Equal(crypto.PublicKey) bool }); !ok || !eq.Equal(certPub) {
+		return errors.New("device key does not match the public key of the voucher device certificate")
+	}
+	return nil
+}
+
+// matchKeyType checks a device public key against a --key option.
+func matchKeyType(pub crypto.PublicKey, keyType string) error {
+	var ok bool
+	switch pub := pub.(type) {
+	case *ecdsa.PublicKey:
+		ok = keyType == "ec256" && pub.Curve == elliptic.P256() ||
+			keyType == "ec384" && pub.Curve == elliptic.P384()
+	case *rsa.PublicKey:
+		ok = keyType == "rsa2048" && pub.Size() == 2048/8 ||
+			keyType == "rsa3072" && pub.Size() == 3072/8
+	}
+	if !ok {
+		return fmt.Errorf("device key is %T, which does not match key type %s", pub, keyType)
+	}
+	return nil
+}
+
+// verifyHmac checks that both HMACs can produce a voucher header HMAC and,
+// when a voucher is given, that they reproduce its header HMAC.
+func verifyHmac(dc fdo.DeviceCredential, secret []byte, hmacSha256, hmacSha384 hash.Hash, ov *fdo.Voucher) error {
+	if rootConfig.TPM == "" && len(secret) == 0 {
+		return errors.New("HMAC secret is empty")
+	}
+
+	ovh := fdo.VoucherHeader{
+		Version:    dc.Version,
+		GUID:       dc.GUID,
+		RvInfo:     dc.RvInfo,
+		DeviceInfo: dc.DeviceInfo,
+	}
+	for _, h := range []struct {
+		name string
+		hash hash.Hash
+		size int
+	}{
+		{"HMAC-SHA256", hmacSha256, sha256.Size},
+		{"HMAC-SHA384", hmacSha384, sha512.Size384},
+	} {
+		h.hash.Reset()
+		if err := cbor.NewEncoder(h.hash).Encode(&ovh); err != nil {
+			return fmt.Errorf("%s: error encoding header: %w", h.name, err)
+		}
+		mac := h.hash.Sum(nil)
+		if fallible, ok := h.hash.(interface{ Err() error }); ok {
+			if err := fallible.Err(); err != nil {
+				return fmt.Errorf("%s: %w", h.name, err)
+			}
+		}
+		if len(mac) != h.size {
+			return fmt.Errorf("%s: got %d bytes, expected %d", h.name, len(mac), h.size)
+		}
+	}
+
+	if ov == nil {
+		return nil
+	}
+	if err := ov.VerifyHeader(hmacSha256, hmacSha384); err != nil {
+		return fmt.Errorf("ownership voucher header HMAC: %w", err)
+	}
+	return nil
+}
+
+// verifyRvInfo checks that at least one rendezvous directive has an address
+// this client can contact.
+func verifyRvInfo(rvInfo [][]protocol.RvInstruction) error {
+	if len(rvInfo) == 0 {
+		return errors.New("no rendezvous directives")
+	}
+	for _, directive := range protocol.ParseDeviceRvInfo(rvInfo) {
+		for _, u := range directive.URLs {
+			if u.Scheme == "http" || u.Scheme == "https" || u.Scheme == "coap" {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("none of the %d rendezvous directives has a usable HTTP(S) or CoAP address", len(rvInfo))
+}
+
+func verifyState(state FdoDeviceState) error {
+	switch state {
+	case FDO_STATE_PRE_TO1, FDO_STATE_IDLE, FDO_STATE_RESALE:
+		return nil
+	case FDO_STATE_PRE_DI:
+		return errors.New("device is in state PRE_DI, device initialization is pending")
+	default:
+		return fmt.Errorf("invalid device state %s", state)
+	}
+}
+
+// verifyVoucher checks that the ownership voucher was issued for the
+// credential.
+func verifyVoucher(dc fdo.DeviceCredential, ov *fdo.Voucher) error {
+	if ov.Header.Val.GUID != dc.GUID {
+		return fmt.Errorf("voucher GUID %x does not match credential GUID %x", ov.Header.Val.GUID[:], dc.GUID[:])
+	}
+	if err := ov.VerifyManufacturerKey(dc.PublicKeyHash); err != nil {
+		return err
+	}
+	return ov.VerifyCertChainHash()
+}
+
+// readVoucherFile reads an ownership voucher, PEM encoded or raw CBOR.
+func readVoucherFile(path string) (*fdo.Voucher, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading voucher: %w", err)
+	}
+	if block, _ := pem.Decode(data); block != nil {
+		if block.Type != "OWNERSHIP VOUCHER" {
+			return nil, fmt.Errorf("%q: unexpected PEM block %q", path, block.Type)
+		}
+		data = block.Bytes
+	}
+	var ov fdo.Voucher
+	if err := cbor.NewDecoder(bytes.NewReader(data)).Decode(&ov); err != nil {
+		return nil, fmt.Errorf("error parsing voucher %q: %w", path, err)
+	}
+	return &ov, nil
+}
+
+func writeVerifyReport(w io.Writer, report *verifyReport, output string) error {
+	if output == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+
+	if report.GUID != "" {
+		fmt.Fprintf(w, "GUID:    %s\n", report.GUID)
+	}
+	fmt.Fprintf(w, "Storage: %s\n", report.Storage)
+	for _, check := range report.Checks {
+		fmt.Fprintf(w, "%-4s  %-10s", strings.ToUpper(check.Result), check.Name)
+		if check.Detail != "" {
+			fmt.Fprintf(w, "  %s", check.Detail)
+		}
+		fmt.Fprintln(w)
+	}
+	if report.Passed {
+		fmt.Fprintln(w, "Credential verified")
+	} else {
+		fmt.Fprintln(w, "Credential verification FAILED")
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+package cmd
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fido-device-onboard/go-fdo"
+	"github.com/fido-device-onboard/go-fdo/cbor"
+	"github.com/fido-device-onboard/go-fdo/protocol"
+)
+
+// writeTestVoucher issues an ownership voucher for dc, updating the
+// manufacturer key hash of dc to match, and writes it as PEM.
+func writeTestVoucher(t *testing.T, dc *fdoDeviceCredential) string {
+	t.Helper()
+	mfgKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mfgPub, err := protocol.NewPublicKey(protocol.Secp256r1KeyType, &mfgKey.PublicKey, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mfgPubCBOR, err := cbor.Marshal(mfgPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkHash := sha256.Sum256(mfgPubCBOR)
+	dc.DC.PublicKeyHash = protocol.Hash{Algorithm: protocol.Sha256Hash, Value: pkHash[:]}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "device.go-fdo"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, dc.DC.PrivateKey.Public(), mfgKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chainHash := sha256.Sum256(cert.Raw)
+
+	ovh := fdo.VoucherHeader{
+		Version:         dc.DC.Version,
+		GUID:            dc.DC.GUID,
+		RvInfo:          dc.DC.RvInfo,
+		DeviceInfo:      dc.DC.DeviceInfo,
+		ManufacturerKey: *mfgPub,
+		CertChainHash:   &protocol.Hash{Algorithm: protocol.Sha256Hash, Value: chainHash[:]},
+	}
+	ovhCBOR, err := cbor.Marshal(&ovh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mac := hmac.New(sha256.New, dc.DC.HmacSecret)
+	mac.Write(ovhCBOR)
+
+	chain := []*cbor.X509Certificate{(*cbor.X509Certificate)(cert)}
+	ov := fdo.Voucher{
+		Version:   dc.DC.Version,
+		Header:    *cbor.NewBstr(ovh),
+		Hmac:      protocol.Hmac{Algorithm: protocol.HmacSha256Hash, Value: mac.Sum(nil)},
+		CertChain: &chain,
+	}
+	data, err := cbor.Marshal(&ov)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "device.ov")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "OWNERSHIP VOUCHER", Bytes: data}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestVerify_Pass(t *testing.T) {
+	dc := newTestBlobCred(t, FDO_STATE_PRE_TO1)
+	ov := writeTestVoucher(t, &dc)
+	path := writeTestBlobCred(t, dc)
+
+	out, err := runCommand(t, "verify", "--blob", path, "--voucher", ov, "--output", "json")
+	if err != nil {
+		t.Fatalf("verify failed: %v\n%s", err, out)
+	}
+	var report verifyReport
+	if err := json.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatalf("invalid JSON output: %v\n%s", err, out)
+	}
+	if !report.Passed {
+		t.Errorf("report not passed:\n%s", out)
+	}
+	for _, check := range report.Checks {
+		if check.Result != verifyPass {
+			t.Errorf("check %s = %s (%s), want pass", check.Name, check.Result, check.Detail)
+		}
+	}
+}
+
+func TestVerify_Failures(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*fdoDeviceCredential)
+		args   []string
+		want   int
+		check  string
+	}{
+		{
+			name:   "empty HMAC secret",
+			modify: func(dc *fdoDeviceCredential) { dc.DC.HmacSecret = nil },
+			want:   verifyExitHmac,
+			check:  "hmac",
+		},
+		{
+			name: "no usable rendezvous address",
+			modify: func(dc *fdoDeviceCredential) {
+				dc.DC.RvInfo = [][]protocol.RvInstruction{{
+					testRvInstruction(t, protocol.RVProtocol, protocol.RVProtHTTP),
+				}}
+			},
+			want:  verifyExitRvInfo,
+			check: "rv-info",
+		},
+		{
+			name:   "error state",
+			modify: func(dc *fdoDeviceCredential) { dc.State = FDO_STATE_ERROR },
+			want:   verifyExitState,
+			check:  "state",
+		},
+		{
+			name:   "key type mismatch",
+			modify: func(dc *fdoDeviceCredential) {},
+			args:   []string{"--key", "ec384"},
+			want:   verifyExitDeviceKey,
+			check:  "device-key",
+		},
+		{
+			name: "voucher for another device",
+			modify: func(dc *fdoDeviceCredential) {
+				dc.DC.GUID[0] ^= 0xff
+			},
+			want:  verifyExitVoucher,
+			check: "voucher",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dc := newTestBlobCred(t, FDO_STATE_PRE_TO1)
+			ov := writeTestVoucher(t, &dc)
+			tt.modify(&dc)
+			path := writeTestBlobCred(t, dc)
+
+			args := append([]string{"--blob", path, "--voucher", ov}, tt.args...)
+			out, err := runCommand(t, append([]string{"verify"}, args...)...)
+			if got := ExitCode(err); got != tt.want {
+				t.Errorf("exit code = %d, want %d (err: %v)\n%s", got, tt.want, err, out)
+			}
+			if !strings.Contains(out, "FAIL  "+tt.check) {
+				t.Errorf("output does not itemize failed %s check:\n%s", tt.check, out)
+			}
+		})
+	}
+}
+
+func TestVerify_CorruptCredential(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cred.bin")
+	if err := os.WriteFile(path, []byte("not cbor"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	out, err := runCommand(t, "verify", "--blob", path)
+	if got := ExitCode(err); got != verifyExitCredential {
+		t.Errorf("exit code = %d, want %d (err: %v)", got, verifyExitCredential, err)
+	}
+	if !strings.Contains(out, "FAIL  credential") {
+		t.Errorf("output does not itemize failed credential check:\n%s", out)
+	}
+}
+
+func TestVerify_TPMRequiresKey(t *testing.T) {
+	_, err := runCommand(t, "verify", "--tpm", "simulator")
+	if err == nil || !strings.Contains(err.Error(), "--key is required") {
+		t.Errorf("err = %v, want --key required error", err)
+	}
+}
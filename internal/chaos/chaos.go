@@ -0,0 +1,174 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+// Package chaos injects faults into the FDO messages of a client, to test its
+// retry behavior without broken servers.
+package chaos
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fido-device-onboard/go-fdo"
+	"github.com/fido-device-onboard/go-fdo/kex"
+)
+
+// Action is the fault injected into a message.
+type Action string
+
+// Fault actions.
+const (
+	// Drop fails the message without sending it, after Delay, like a
+	// connection that times out.
+	Drop Action = "drop"
+	// Delay sends the message after Delay.
+	Delay Action = "delay"
+	// Truncate sends the message and cuts its response in half.
+	Truncate Action = "truncate"
+	// HTTPError fails the message without sending it, like a server
+	// responding with the HTTP status Status.
+	HTTPError Action = "http-error"
+	// DNSError fails the message without sending it, like a server name
+	// that does not resolve.
+	DNSError Action = "dns-error"
+)
+
+// Actions lists the valid fault actions.
+var Actions = []Action{Drop, Delay, Truncate, HTTPError, DNSError}
+
+// Fault is a failure injected into the messages that match it.
+type Fault struct {
+	// MsgType is the FDO message type the fault applies to, 0 for all.
+	MsgType uint8
+	// URL is a substring of the base URL of the servers the fault applies
+	// to, "" for all.
+	URL    string
+	Action Action
+	// Delay is the delay of Delay faults and the time until Drop faults
+	// fail.
+	Delay time.Duration
+	// Status is the HTTP status of HTTPError faults.
+	Status int
+	// Count is the number of messages the fault applies to, 0 for all.
+	Count int
+}
+
+// Injector injects faults into the messages of its transports. Each message
+// gets the first fault that matches it and has not reached its count. A nil
+// Injector injects no faults.
+type Injector struct {
+	mu     sync.Mutex
+	faults []Fault
+	hits   []int
+}
+
+// New returns an injector of the faults.
+func New(faults []Fault) *Injector {
+	return &Injector{faults: faults, hits: make([]int, len(faults))}
+}
+
+// Hits returns the number of messages each fault was injected into.
+func (in *Injector) Hits() []int {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return append([]int(nil), in.hits...)
+}
+
+// Transport returns a transport to the server at baseURL that sends the
+// messages with next and injects the faults into them.
+func (in *Injector) Transport(baseURL string, next fdo.Transport) fdo.Transport {
+	if in == nil {
+		return next
+	}
+	return &faultTransport{injector: in, url: baseURL, next: next}
+}
+
+// fault returns the fault to inject into a message, if any, and counts it.
+func (in *Injector) fault(baseURL string, msgType uint8) (Fault, bool) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	for i, f := range in.faults {
+		if f.MsgType != 0 && f.MsgType != msgType || !strings.Contains(baseURL, f.URL) {
+			continue
+		}
+		if f.Count > 0 && in.hits[i] >= f.Count {
+			continue
+		}
+		in.hits[i]++
+		return f, true
+	}
+	return Fault{}, false
+}
+
+type faultTransport struct {
+	injector *Injector
+	url      string
+	next     fdo.Transport
+}
+
+func (t *faultTransport) Send(ctx context.Context, msgType uint8, msg any, sess kex.Session) (uint8, io.ReadCloser, error) {
+	f, ok := t.injector.fault(t.url, msgType)
+	if !ok {
+		return t.next.Send(ctx, msgType, msg, sess)
+	}
+
+	switch f.Action {
+	case Drop:
+		if err := sleep(ctx, f.Delay); err != nil {
+			return 0, nil, err
+		}
+		return 0, nil, fmt.Errorf("fault injection: message %d to %s dropped", msgType, t.url)
+
+	case Delay:
+		if err := sleep(ctx, f.Delay); err != nil {
+			return 0, nil, err
+		}
+		return t.next.Send(ctx, msgType, msg, sess)
+
+	case Truncate:
+		respType, resp, err := t.next.Send(ctx, msgType, msg, sess)
+		if err != nil {
+			return 0, nil, err
+		}
+		defer func() { _ = resp.Close() }()
+		data, err := io.ReadAll(resp)
+		if err != nil {
+			return 0, nil, err
+		}
+		return respType, io.NopCloser(bytes.NewReader(data[:len(data)/2])), nil
+
+	case HTTPError:
+		return 0, nil, fmt.Errorf("fault injection: unexpected HTTP response code: %d %s", f.Status, http.StatusText(f.Status))
+
+	case DNSError:
+		host := t.url
+		if u, err := url.Parse(t.url); err == nil && u.Hostname() != "" {
+			host = u.Hostname()
+		}
+		return 0, nil, fmt.Errorf("fault injection: %w", &net.DNSError{Err: "no such host", Name: host, IsNotFound: true})
+	}
+	return 0, nil, fmt.Errorf("fault injection: unknown action %q", f.Action)
+}
+
+// sleep waits for d unless the context is done first.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,125 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+package chaos
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fido-device-onboard/go-fdo/kex"
+)
+
+// serverTransport responds to each message with the message type plus one and
+// a fixed body, counting the messages it received.
+type serverTransport struct {
+	sent int
+}
+
+func (s *serverTransport) Send(ctx context.Context, msgType uint8, msg any, sess kex.Session) (uint8, io.ReadCloser, error) {
+	s.sent++
+	return msgType + 1, io.NopCloser(strings.NewReader("0123456789")), nil
+}
+
+func send(t *testing.T, in *Injector, baseURL string, msgType uint8) (*serverTransport, string, error) {
+	t.Helper()
+	server := &serverTransport{}
+	_, resp, err := in.Transport(baseURL, server).Send(context.Background(), msgType, nil, nil)
+	if err != nil {
+		return server, "", err
+	}
+	defer func() { _ = resp.Close() }()
+	body, err := io.ReadAll(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, string(body), nil
+}
+
+func TestActions(t *testing.T) {
+	tests := []struct {
+		fault    Fault
+		wantSent int
+		wantBody string
+		wantErr  string
+	}{
+		{Fault{Action: Drop}, 0, "", "message 30 to https://rv.example.com:8041 dropped"},
+		{Fault{Action: Delay, Delay: 20 * time.Millisecond}, 1, "0123456789", ""},
+		{Fault{Action: Truncate}, 1, "01234", ""},
+		{Fault{Action: HTTPError, Status: 503}, 0, "", "unexpected HTTP response code: 503 Service Unavailable"},
+		{Fault{Action: DNSError}, 0, "", "lookup rv.example.com: no such host"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.fault.Action), func(t *testing.T) {
+			start := time.Now()
+			server, body, err := send(t, New([]Fault{tt.fault}), "https://rv.example.com:8041", 30)
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+			if server.sent != tt.wantSent || body != tt.wantBody {
+				t.Errorf("sent %d messages with response %q, want %d with %q", server.sent, body, tt.wantSent, tt.wantBody)
+			}
+			if elapsed := time.Since(start); elapsed < tt.fault.Delay {
+				t.Errorf("sent after %s, want at least %s", elapsed, tt.fault.Delay)
+			}
+		})
+	}
+}
+
+func TestDNSErrorIsNotFound(t *testing.T) {
+	_, _, err := send(t, New([]Fault{{Action: DNSError}}), "http://owner.example.com", 60)
+	var dnsErr *net.DNSError
+	if !errors.As(err, &dnsErr) || !dnsErr.IsNotFound || dnsErr.Name != "owner.example.com" {
+		t.Errorf("error = %v, want a not found DNS error for owner.example.com", err)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	in := New([]Fault{
+		{MsgType: 30, URL: "rv1", Action: HTTPError, Status: 500, Count: 2},
+		{URL: "owner", Action: Drop},
+	})
+	for _, msg := range []struct {
+		url     string
+		msgType uint8
+		fail    bool
+	}{
+		{"https://rv1.example.com", 30, true},
+		{"https://rv1.example.com", 32, false}, // other message type
+		{"https://rv2.example.com", 30, false}, // other server
+		{"https://rv1.example.com", 30, true},
+		{"https://rv1.example.com", 30, false}, // count reached
+		{"https://owner.example.com", 60, true},
+		{"https://owner.example.com", 62, true},
+	} {
+		if _, _, err := send(t, in, msg.url, msg.msgType); (err != nil) != msg.fail {
+			t.Errorf("message %d to %s: error = %v, want failure %t", msg.msgType, msg.url, err, msg.fail)
+		}
+	}
+	if got := in.Hits(); !slices.Equal(got, []int{2, 2}) {
+		t.Errorf("hits = %v, want [2 2]", got)
+	}
+}
+
+func TestNilInjector(t *testing.T) {
+	var in *Injector
+	server, body, err := send(t, in, "https://rv.example.com", 30)
+	if err != nil || server.sent != 1 || body != "0123456789" {
+		t.Errorf("sent %d messages with response %q, %v", server.sent, body, err)
+	}
+}
+
+func TestDropCanceled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	transport := New([]Fault{{Action: Drop, Delay: time.Minute}}).Transport("https://rv.example.com", &serverTransport{})
+	if _, _, err := transport.Send(ctx, 30, nil, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
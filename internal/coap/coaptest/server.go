@@ -0,0 +1,267 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+// Package coaptest provides a CoAP server standing in for FDO servers in
+// tests, like net/http/httptest.
+package coaptest
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fido-device-onboard/go-fdo-client/internal/coap"
+)
+
+// Request is an FDO message received by the server.
+type Request struct {
+	MsgType uint8
+	// Token is the authorization token sent with the message.
+	Token string
+	Body  []byte
+}
+
+// Response is the response to an FDO message. A zero Code responds with
+// 2.04 Changed, or 5.00 Internal Server Error for error messages.
+type Response struct {
+	Code    uint8
+	MsgType uint8
+	// Token, if not empty, is sent as the authorization token.
+	Token string
+	Body  []byte
+}
+
+// Handler responds to FDO messages.
+type Handler func(*Request) *Response
+
+// Server is a CoAP server on a loopback UDP port.
+type Server struct {
+	// URL is the base URL of the server, coap://127.0.0.1:<port>.
+	URL string
+
+	// BlockSize is the size of the blocks of responses, and of requests if
+	// it is smaller than the client's, 1024 by default.
+	BlockSize int
+	// Separate sends responses separately from the acknowledgement of the
+	// request.
+	Separate bool
+	// Drop is the number of requests dropped before responding, to test
+	// retransmissions.
+	Drop int
+
+	handler Handler
+	conn    *net.UDPConn
+	done    chan struct{}
+
+	mu        sync.Mutex
+	requests  int
+	bodies    map[string][]byte
+	responses map[string]*coap.Message
+	replies   map[string][]byte
+	nextID    uint16
+}
+
+// NewServer starts a server responding with the handler.
+func NewServer(handler Handler) *Server {
+	s := NewUnstartedServer(handler)
+	s.Start()
+	return s
+}
+
+// NewUnstartedServer returns a server that is configured before calling
+// Start.
+func NewUnstartedServer(handler Handler) *Server {
+	return &Server{
+		handler:   handler,
+		done:      make(chan struct{}),
+		bodies:    make(map[string][]byte),
+		responses: make(map[string]*coap.Message),
+		replies:   make(map[string][]byte),
+	}
+}
+
+// Start starts the server.
+func (s *Server) Start() {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		panic(fmt.Sprintf("coaptest: failed to listen: %v", err))
+	}
+	s.conn = conn
+	s.URL = "coap://" + conn.LocalAddr().String()
+	go s.serve()
+}
+
+// Close stops the server.
+func (s *Server) Close() {
+	_ = s.conn.Close()
+	<-s.done
+}
+
+// Requests returns the number of FDO messages the server responded to.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func (s *Server) serve() {
+	defer close(s.done)
+	buf := make([]byte, 1<<16)
+	for {
+		n, addr, err := s.conn.ReadFromUDP(buf)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			continue
+		}
+		m, err := coap.ParseMessage(buf[:n])
+		if err != nil || m.Type != coap.Confirmable {
+			continue
+		}
+		s.handle(addr, m)
+	}
+}
+
+func (s *Server) handle(addr *net.UDPAddr, req *coap.Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Ping
+	if req.Code == coap.CodeEmpty {
+		s.send(addr, &coap.Message{Type: coap.Reset, ID: req.ID})
+		return
+	}
+	// Retransmitted request
+	key := addr.String() + "/" + strconv.Itoa(int(req.ID))
+	if reply, ok := s.replies[key]; ok {
+		_, _ = s.conn.WriteToUDP(reply, addr)
+		return
+	}
+	if s.Drop > 0 {
+		s.Drop--
+		return
+	}
+
+	resp := s.respond(addr.String(), req)
+	resp.Token = req.Token
+	if !s.Separate {
+		resp.Type, resp.ID = coap.Acknowledgement, req.ID
+		s.replies[key] = s.send(addr, resp)
+		return
+	}
+	s.replies[key] = s.send(addr, &coap.Message{Type: coap.Acknowledgement, ID: req.ID})
+	resp.Type, resp.ID = coap.Confirmable, s.nextID
+	s.nextID++
+	s.send(addr, resp)
+}
+
+// respond returns the response to a request, or to a block of a request.
+func (s *Server) respond(client string, req *coap.Message) *coap.Message {
+	var path []string
+	for _, opt := range req.Options {
+		if opt.Number == coap.OptionURIPath {
+			path = append(path, string(opt.Value))
+		}
+	}
+	if req.Code != coap.CodePOST || len(path) < 4 || strings.Join(path[len(path)-4:len(path)-1], "/") != "fdo/101/msg" {
+		return &coap.Message{Code: coap.CodeNotFound}
+	}
+	msgType, err := strconv.ParseUint(path[len(path)-1], 10, 8)
+	if err != nil {
+		return &coap.Message{Code: coap.CodeNotFound}
+	}
+	blockSZX := uint8(6)
+	for blockSZX > 0 && s.BlockSize > 0 && 1<<(blockSZX+4) > s.BlockSize {
+		blockSZX--
+	}
+
+	// Following blocks of the last response
+	if b, ok := req.BlockOption(coap.OptionBlock2); ok && b.Num > 0 {
+		last, ok := s.responses[client]
+		if !ok {
+			return &coap.Message{Code: coap.CodeBadRequest}
+		}
+		return block(last, b)
+	}
+
+	body := req.Payload
+	b1, block1 := req.BlockOption(coap.OptionBlock1)
+	if block1 {
+		received := s.bodies[client]
+		if b1.Num == 0 {
+			received = nil
+		}
+		if int(b1.Num)*b1.Size() != len(received) {
+			delete(s.bodies, client)
+			return &coap.Message{Code: coap.CodeRequestEntityIncomplete}
+		}
+		received = append(received, req.Payload...)
+		if b1.More {
+			s.bodies[client] = received
+			resp := &coap.Message{Code: coap.CodeContinue}
+			resp.AddBlockOption(coap.OptionBlock1, coap.Block{Num: b1.Num, More: true, SZX: min(b1.SZX, blockSZX)})
+			return resp
+		}
+		delete(s.bodies, client)
+		body = received
+	}
+
+	var token string
+	if value, ok := req.Option(coap.OptionAuthorization); ok {
+		token = string(value)
+	}
+	s.requests++
+	r := s.handler(&Request{MsgType: uint8(msgType), Token: token, Body: body})
+
+	resp := &coap.Message{Code: r.Code, Payload: r.Body}
+	if resp.Code == 0 {
+		resp.Code = coap.CodeChanged
+		if r.MsgType == 255 {
+			resp.Code = coap.CodeInternalServerError
+		}
+	}
+	if resp.Code == coap.CodeChanged {
+		resp.AddUintOption(coap.OptionMessageType, uint32(r.MsgType))
+	}
+	if resp.Code == coap.CodeChanged || resp.Code == coap.CodeInternalServerError {
+		resp.AddUintOption(coap.OptionContentFormat, coap.ContentFormatCBOR)
+	}
+	if r.Token != "" {
+		resp.AddOption(coap.OptionAuthorization, []byte(r.Token))
+	}
+	if block1 {
+		resp.AddBlockOption(coap.OptionBlock1, b1)
+	}
+	if len(resp.Payload) > 1<<(blockSZX+4) {
+		s.responses[client] = resp
+		return block(resp, coap.Block{SZX: blockSZX})
+	}
+	return resp
+}
+
+// block returns a block of a response.
+func block(resp *coap.Message, b coap.Block) *coap.Message {
+	start := min(int(b.Num)*b.Size(), len(resp.Payload))
+	end := min(start+b.Size(), len(resp.Payload))
+	b.More = end < len(resp.Payload)
+	m := &coap.Message{Code: resp.Code, Payload: resp.Payload[start:end]}
+	if b.Num == 0 {
+		m.Options = append(m.Options, resp.Options...)
+	}
+	m.AddBlockOption(coap.OptionBlock2, b)
+	return m
+}
+
+// send sends a message and returns it encoded.
+func (s *Server) send(addr *net.UDPAddr, m *coap.Message) []byte {
+	data, err := m.Marshal()
+	if err != nil {
+		panic(fmt.Sprintf("coaptest: %v", err))
+	}
+	_, _ = s.conn.WriteToUDP(data, addr)
+	return data
+}
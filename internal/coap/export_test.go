@@ -0,0 +1,14 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+package coap
+
+import "time"
+
+// SetAckTimeout sets the initial retransmission timeout until the returned
+// function restores it.
+func SetAckTimeout(d time.Duration) (restore func()) {
+	orig := ackTimeout
+	ackTimeout = d
+	return func() { ackTimeout = orig }
+}
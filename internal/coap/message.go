@@ -0,0 +1,251 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+package coap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Message types.
+const (
+	Confirmable     uint8 = 0
+	NonConfirmable  uint8 = 1
+	Acknowledgement uint8 = 2
+	Reset           uint8 = 3
+)
+
+// Codes of requests and responses, class << 5 | detail.
+const (
+	CodeEmpty                   uint8 = 0x00 // 0.00
+	CodePOST                    uint8 = 0x02 // 0.02
+	CodeChanged                 uint8 = 0x44 // 2.04
+	CodeContent                 uint8 = 0x45 // 2.05
+	CodeContinue                uint8 = 0x5f // 2.31
+	CodeBadRequest              uint8 = 0x80 // 4.00
+	CodeNotFound                uint8 = 0x84 // 4.04
+	CodeRequestEntityIncomplete uint8 = 0x88 // 4.08
+	CodeRequestEntityTooLarge   uint8 = 0x8d // 4.13
+	CodeInternalServerError     uint8 = 0xa0 // 5.00
+)
+
+// CodeString formats a code as class.detail, such as 4.04.
+func CodeString(code uint8) string {
+	return fmt.Sprintf("%d.%02d", code>>5, code&0x1f)
+}
+
+// Option numbers.
+const (
+	OptionURIPath       uint16 = 11
+	OptionContentFormat uint16 = 12
+	OptionBlock2        uint16 = 23
+	OptionBlock1        uint16 = 27
+	OptionSize1         uint16 = 60
+
+	// OptionMessageType is the FDO message type of a response, and
+	// OptionAuthorization the FDO authorization token of a protocol, the
+	// Message-Type and Authorization headers of the HTTP transport. They are
+	// numbers of the experimental range, critical so that servers that do
+	// not know them reject the request.
+	OptionMessageType   uint16 = 65001
+	OptionAuthorization uint16 = 65003
+)
+
+// ContentFormatCBOR is the content format of application/cbor.
+const ContentFormatCBOR = 60
+
+// Option is an option of a message.
+type Option struct {
+	Number uint16
+	Value  []byte
+}
+
+// Message is a CoAP message over UDP (RFC 7252).
+type Message struct {
+	Type    uint8
+	Code    uint8
+	ID      uint16
+	Token   []byte
+	Options []Option
+	Payload []byte
+}
+
+// Option returns the value of the first option of a number.
+func (m *Message) Option(number uint16) ([]byte, bool) {
+	for _, opt := range m.Options {
+		if opt.Number == number {
+			return opt.Value, true
+		}
+	}
+	return nil, false
+}
+
+// UintOption returns the value of the first option of a number as an
+// unsigned integer.
+func (m *Message) UintOption(number uint16) (uint32, bool) {
+	value, ok := m.Option(number)
+	if !ok || len(value) > 4 {
+		return 0, false
+	}
+	var n uint32
+	for _, b := range value {
+		n = n<<8 | uint32(b)
+	}
+	return n, true
+}
+
+// AddOption appends an option.
+func (m *Message) AddOption(number uint16, value []byte) {
+	m.Options = append(m.Options, Option{Number: number, Value: value})
+}
+
+// AddUintOption appends an option with an unsigned integer value in the
+// fewest bytes.
+func (m *Message) AddUintOption(number uint16, n uint32) {
+	var value []byte
+	for ; n > 0; n >>= 8 {
+		value = append([]byte{byte(n)}, value...)
+	}
+	m.AddOption(number, value)
+}
+
+// Block is the value of a Block1 or Block2 option (RFC 7959).
+type Block struct {
+	Num  uint32
+	More bool
+	SZX  uint8
+}
+
+// Size returns the size of the block.
+func (b Block) Size() int {
+	return 1 << (b.SZX + 4)
+}
+
+// BlockOption returns the block of a Block1 or Block2 option.
+func (m *Message) BlockOption(number uint16) (Block, bool) {
+	n, ok := m.UintOption(number)
+	if !ok || n&0x7 == 7 {
+		return Block{}, false
+	}
+	return Block{Num: n >> 4, More: n&0x8 != 0, SZX: uint8(n & 0x7)}, true
+}
+
+// AddBlockOption appends a Block1 or Block2 option.
+func (m *Message) AddBlockOption(number uint16, b Block) {
+	n := b.Num<<4 | uint32(b.SZX)
+	if b.More {
+		n |= 0x8
+	}
+	m.AddUintOption(number, n)
+}
+
+// Marshal encodes the message.
+func (m *Message) Marshal() ([]byte, error) {
+	if len(m.Token) > 8 {
+		return nil, errors.New("token longer than 8 bytes")
+	}
+	data := []byte{1<<6 | m.Type<<4 | uint8(len(m.Token)), m.Code, 0, 0}
+	binary.BigEndian.PutUint16(data[2:], m.ID)
+	data = append(data, m.Token...)
+
+	options := slices.Clone(m.Options)
+	slices.SortStableFunc(options, func(a, b Option) int { return int(a.Number) - int(b.Number) })
+	var prev uint16
+	for _, opt := range options {
+		delta, deltaExt := optionNibble(int(opt.Number - prev))
+		length, lengthExt := optionNibble(len(opt.Value))
+		data = append(data, delta<<4|length)
+		data = append(data, deltaExt...)
+		data = append(data, lengthExt...)
+		data = append(data, opt.Value...)
+		prev = opt.Number
+	}
+
+	if len(m.Payload) > 0 {
+		data = append(data, 0xff)
+		data = append(data, m.Payload...)
+	}
+	return data, nil
+}
+
+// optionNibble encodes an option delta or length as its nibble and extended
+// bytes.
+func optionNibble(n int) (uint8, []byte) {
+	switch {
+	case n < 13:
+		return uint8(n), nil
+	case n < 269:
+		return 13, []byte{byte(n - 13)}
+	default:
+		return 14, binary.BigEndian.AppendUint16(nil, uint16(n-269))
+	}
+}
+
+// ParseMessage decodes a message.
+func ParseMessage(data []byte) (*Message, error) {
+	if len(data) < 4 || data[0]>>6 != 1 {
+		return nil, errors.New("not a CoAP version 1 message")
+	}
+	tokenLen := int(data[0] & 0xf)
+	if tokenLen > 8 || len(data) < 4+tokenLen {
+		return nil, errors.New("invalid token length")
+	}
+	m := &Message{
+		Type:  data[0] >> 4 & 0x3,
+		Code:  data[1],
+		ID:    binary.BigEndian.Uint16(data[2:]),
+		Token: slices.Clone(data[4 : 4+tokenLen]),
+	}
+
+	rest := data[4+tokenLen:]
+	var number int
+	for len(rest) > 0 {
+		if rest[0] == 0xff {
+			if len(rest) == 1 {
+				return nil, errors.New("payload marker without payload")
+			}
+			m.Payload = slices.Clone(rest[1:])
+			return m, nil
+		}
+		var delta, length int
+		var err error
+		head := rest[0]
+		rest = rest[1:]
+		if delta, rest, err = parseOptionNibble(head>>4, rest); err != nil {
+			return nil, err
+		}
+		if length, rest, err = parseOptionNibble(head&0xf, rest); err != nil {
+			return nil, err
+		}
+		if number += delta; number > 0xffff {
+			return nil, errors.New("invalid option number")
+		}
+		if len(rest) < length {
+			return nil, errors.New("truncated option value")
+		}
+		m.AddOption(uint16(number), slices.Clone(rest[:length]))
+		rest = rest[length:]
+	}
+	return m, nil
+}
+
+func parseOptionNibble(nibble uint8, rest []byte) (int, []byte, error) {
+	switch nibble {
+	case 13:
+		if len(rest) < 1 {
+			return 0, nil, errors.New("truncated option")
+		}
+		return int(rest[0]) + 13, rest[1:], nil
+	case 14:
+		if len(rest) < 2 {
+			return 0, nil, errors.New("truncated option")
+		}
+		return int(binary.BigEndian.Uint16(rest)) + 269, rest[2:], nil
+	case 15:
+		return 0, nil, errors.New("invalid option nibble")
+	}
+	return int(nibble), rest, nil
+}
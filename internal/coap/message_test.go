@@ -0,0 +1,71 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+package coap
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestMessage(t *testing.T) {
+	m := &Message{
+		Type:  Confirmable,
+		Code:  CodePOST,
+		ID:    0xbeef,
+		Token: []byte{1, 2, 3, 4},
+		Options: []Option{
+			{Number: OptionAuthorization, Value: []byte("token")},
+			{Number: OptionURIPath, Value: []byte("fdo")},
+			{Number: OptionURIPath, Value: bytes.Repeat([]byte("a"), 300)},
+			{Number: OptionContentFormat, Value: []byte{ContentFormatCBOR}},
+		},
+		Payload: []byte{0xa0},
+	}
+	m.AddBlockOption(OptionBlock1, Block{Num: 1000, More: true, SZX: 6})
+	data, err := m.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParseMessage(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Options are encoded in the order of their numbers
+	want := *m
+	want.Options = []Option{m.Options[1], m.Options[2], m.Options[3], m.Options[4], m.Options[0]}
+	if !reflect.DeepEqual(got, &want) {
+		t.Errorf("parsed %+v, want %+v", got, &want)
+	}
+	if b, ok := got.BlockOption(OptionBlock1); !ok || b != (Block{Num: 1000, More: true, SZX: 6}) || b.Size() != 1024 {
+		t.Errorf("Block1 = %+v", b)
+	}
+	if format, ok := got.UintOption(OptionContentFormat); !ok || format != ContentFormatCBOR {
+		t.Errorf("content format = %d", format)
+	}
+}
+
+func TestParseMessage_Invalid(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"short":              {0x40, 0x02},
+		"version":            {0x80, 0x02, 0, 1},
+		"token length":       {0x49, 0x02, 0, 1},
+		"truncated option":   {0x40, 0x02, 0, 1, 0xb5, 'f'},
+		"empty payload":      {0x40, 0x02, 0, 1, 0xff},
+		"reserved extension": {0x40, 0x02, 0, 1, 0xf1, 0},
+	} {
+		if _, err := ParseMessage(data); err == nil {
+			t.Errorf("%s: parsed invalid message", name)
+		}
+	}
+}
+
+func TestCodeString(t *testing.T) {
+	for code, want := range map[uint8]string{CodePOST: "0.02", CodeChanged: "2.04", CodeNotFound: "4.04", CodeInternalServerError: "5.00"} {
+		if got := CodeString(code); got != want {
+			t.Errorf("CodeString(%#x) = %s, want %s", code, got, want)
+		}
+	}
+}
@@ -0,0 +1,408 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+// Package coap implements the CoAP transport of FDO over UDP (RFC 7252).
+//
+// FDO messages are sent as confirmable POST requests to the path
+// /fdo/101/msg/<message type> with CBOR content, as with HTTP. The message type
+// of a response and the authorization token of a protocol, the Message-Type
+// and Authorization headers of HTTP, are carried in the options
+// OptionMessageType and OptionAuthorization. Messages larger than a block are
+// sent and received with block-wise transfers (RFC 7959). CoAP over DTLS and
+// over TCP are not supported.
+package coap
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fido-device-onboard/go-fdo/cbor"
+	"github.com/fido-device-onboard/go-fdo/kex"
+	"github.com/fido-device-onboard/go-fdo/protocol"
+)
+
+// DefaultPort is the port of coap URLs without one.
+const DefaultPort = "5683"
+
+// Transmission parameters of RFC 7252, section 4.8.
+const (
+	maxRetransmit = 4
+	// maxTransmitWait bounds the wait for a separate response after the
+	// request was acknowledged.
+	maxTransmitWait = 93 * time.Second
+	// blockSZX selects blocks of 1024 bytes.
+	blockSZX = 6
+)
+
+// ackTimeout is the initial retransmission timeout, a variable for tests.
+var ackTimeout = 2 * time.Second
+
+// errReset is returned when the server rejects a message with a reset.
+var errReset = errors.New("reset by the server")
+
+// Transport implements FDO message sending over CoAP. Send may be used for
+// sending one message and receiving one response message.
+type Transport struct {
+	// The coap URL, potentially including a path prefix, but without
+	// /fdo/101/msg.
+	BaseURL string
+
+	// Dialer creates the UDP sockets. Nil uses a zero dialer.
+	Dialer *net.Dialer
+
+	// Timeout limits each message exchange, including retransmissions and
+	// block-wise transfers. Zero leaves it to the retransmission limits.
+	Timeout time.Duration
+
+	// MaxContentLength defaults to 65535. Negative values disable content
+	// length checking.
+	MaxContentLength int64
+
+	tokens map[protocol.Protocol]string
+}
+
+// Send sends a single message and receives a single response message.
+func (t *Transport) Send(ctx context.Context, msgType uint8, msg any, sess kex.Session) (uint8, io.ReadCloser, error) {
+	u, err := url.Parse(t.BaseURL)
+	if err != nil {
+		return 0, nil, fmt.Errorf("error parsing base URL: %w", err)
+	}
+	if u.Scheme != "coap" {
+		return 0, nil, fmt.Errorf("unsupported CoAP scheme %q: only CoAP over UDP is supported", u.Scheme)
+	}
+
+	// Encrypt if a key exchange session is provided
+	if sess != nil {
+		if msg, err = sess.Encrypt(rand.Reader, msg); err != nil {
+			return 0, nil, fmt.Errorf("error encrypting message %d: %w", msgType, err)
+		}
+	}
+	body, err := cbor.Marshal(msg)
+	if err != nil {
+		return 0, nil, fmt.Errorf("error encoding message %d: %w", msgType, err)
+	}
+
+	prot := protocol.Of(msgType)
+	if errMsg, ok := msg.(protocol.ErrorMessage); ok {
+		// Error messages use the authorization token for the protocol where
+		// failure occurred
+		prot = protocol.Of(errMsg.PrevMsgType)
+	}
+	if prot == protocol.UnknownProtocol || prot == protocol.AnyProtocol {
+		return 0, nil, fmt.Errorf("invalid message type: unknown protocol or error message not using protocol.ErrorMessage type")
+	}
+
+	var options []Option
+	for _, segment := range strings.Split(strings.Trim(u.Path, "/"), "/") {
+		if segment != "" {
+			options = append(options, Option{Number: OptionURIPath, Value: []byte(segment)})
+		}
+	}
+	for _, segment := range []string{"fdo", "101", "msg", strconv.Itoa(int(msgType))} {
+		options = append(options, Option{Number: OptionURIPath, Value: []byte(segment)})
+	}
+	options = append(options, Option{Number: OptionContentFormat, Value: []byte{ContentFormatCBOR}})
+	if token := t.tokens[prot]; token != "" {
+		options = append(options, Option{Number: OptionAuthorization, Value: []byte(token)})
+	}
+
+	if t.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.Timeout)
+		defer cancel()
+	}
+	c, err := t.dial(ctx, u)
+	if err != nil {
+		return 0, nil, fmt.Errorf("error making CoAP request for message %d: %w", msgType, err)
+	}
+	defer func() { _ = c.Close() }()
+	resp, payload, err := c.post(ctx, options, body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("error making CoAP request for message %d: %w", msgType, err)
+	}
+
+	return t.handleResponse(resp, payload, prot, sess)
+}
+
+func (t *Transport) dial(ctx context.Context, u *url.URL) (*conn, error) {
+	port := u.Port()
+	if port == "" {
+		port = DefaultPort
+	}
+	dialer := t.Dialer
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+	udp, err := dialer.DialContext(ctx, "udp", net.JoinHostPort(u.Hostname(), port))
+	if err != nil {
+		return nil, err
+	}
+	return newConn(udp), nil
+}
+
+func (t *Transport) handleResponse(resp *Message, payload []byte, prot protocol.Protocol, sess kex.Session) (uint8, io.ReadCloser, error) {
+	// Store the token like the HTTP transport stores the Authorization header
+	if token, ok := resp.Option(OptionAuthorization); ok && len(token) > 0 {
+		if t.tokens == nil {
+			t.tokens = make(map[protocol.Protocol]string)
+		}
+		t.tokens[prot] = string(token)
+	}
+
+	// Parse message type from options (or implicit from response code)
+	var msgType uint8
+	switch resp.Code {
+	case CodeChanged, CodeContent:
+		typ, ok := resp.UintOption(OptionMessageType)
+		if !ok || typ > 255 {
+			return 0, nil, errors.New("response contains invalid message type option")
+		}
+		msgType = uint8(typ)
+	case CodeInternalServerError:
+		if format, ok := resp.UintOption(OptionContentFormat); !ok || format != ContentFormatCBOR {
+			return 0, nil, fmt.Errorf("%s did not include an error message body", CodeString(resp.Code))
+		}
+		msgType = protocol.ErrorMsgType
+	default:
+		if len(payload) > 0 {
+			return 0, nil, fmt.Errorf("unexpected CoAP response code: %s: %s", CodeString(resp.Code), payload)
+		}
+		return 0, nil, fmt.Errorf("unexpected CoAP response code: %s", CodeString(resp.Code))
+	}
+
+	maxSize := t.MaxContentLength
+	if maxSize == 0 {
+		maxSize = 65535
+	}
+	if maxSize > 0 && int64(len(payload)) > maxSize {
+		return 0, nil, fmt.Errorf("content too large (%d bytes)", len(payload))
+	}
+
+	// Decrypt if a key exchange session is provided for types other than error
+	if sess != nil && msgType != protocol.ErrorMsgType {
+		decrypted, err := sess.Decrypt(rand.Reader, bytes.NewReader(payload))
+		if err != nil {
+			return 0, nil, fmt.Errorf("error decrypting message %d: %w", msgType, err)
+		}
+		payload = decrypted
+	}
+	return msgType, io.NopCloser(bytes.NewReader(payload)), nil
+}
+
+// Ping sends a CoAP ping, an empty confirmable message that servers reset, to
+// the address.
+func Ping(ctx context.Context, dialer *net.Dialer, address string) error {
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+	udp, err := dialer.DialContext(ctx, "udp", address)
+	if err != nil {
+		return err
+	}
+	c := newConn(udp)
+	defer func() { _ = c.Close() }()
+	resp, err := c.exchange(ctx, &Message{Code: CodeEmpty})
+	if errors.Is(err, errReset) {
+		return nil
+	}
+	if err == nil {
+		err = fmt.Errorf("unexpected response %s to ping", CodeString(resp.Code))
+	}
+	return err
+}
+
+// conn exchanges the messages of requests with a server.
+type conn struct {
+	udp    net.Conn
+	nextID uint16
+	buf    []byte
+}
+
+func newConn(udp net.Conn) *conn {
+	return &conn{udp: udp, nextID: uint16(randomUint(1 << 16)), buf: make([]byte, 1<<16)}
+}
+
+func (c *conn) Close() error { return c.udp.Close() }
+
+// post sends a POST request with the options and body, sending a large body
+// in blocks, and returns the response with its payload, reassembled from
+// blocks if the server sends them.
+func (c *conn) post(ctx context.Context, options []Option, body []byte) (*Message, []byte, error) {
+	block := Block{SZX: blockSZX}
+	var resp *Message
+	for offset := 0; ; {
+		req := &Message{Code: CodePOST, Options: slices.Clone(options), Payload: body}
+		more := false
+		if len(body) > (Block{SZX: blockSZX}).Size() {
+			end := min(offset+block.Size(), len(body))
+			more = end < len(body)
+			block.Num, block.More = uint32(offset/block.Size()), more
+			req.AddBlockOption(OptionBlock1, block)
+			if offset == 0 {
+				req.AddUintOption(OptionSize1, uint32(len(body)))
+			}
+			req.Payload = body[offset:end]
+			offset = end
+		}
+
+		var err error
+		if resp, err = c.exchange(ctx, req); err != nil {
+			return nil, nil, err
+		}
+		if !more || resp.Code != CodeContinue {
+			break
+		}
+		// The server may ask for smaller blocks
+		if ack, ok := resp.BlockOption(OptionBlock1); ok && ack.SZX < block.SZX {
+			block.SZX = ack.SZX
+		}
+		if offset%block.Size() != 0 {
+			return nil, nil, errors.New("server changed the block size to one that does not divide the sent blocks")
+		}
+	}
+
+	// The options of the response are those of its first block
+	payload := resp.Payload
+	for last := resp; ; {
+		b, ok := last.BlockOption(OptionBlock2)
+		if !ok || !b.More {
+			return resp, payload, nil
+		}
+		if len(payload) > 1<<16 {
+			return nil, nil, errors.New("response larger than 64 KiB")
+		}
+		if len(payload)%b.Size() != 0 {
+			return nil, nil, errors.New("response block is not a multiple of the block size")
+		}
+		next := Block{Num: uint32(len(payload) / b.Size()), SZX: b.SZX}
+		req := &Message{Code: CodePOST, Options: slices.Clone(options)}
+		req.AddBlockOption(OptionBlock2, next)
+		var err error
+		if last, err = c.exchange(ctx, req); err != nil {
+			return nil, nil, err
+		}
+		if got, ok := last.BlockOption(OptionBlock2); !ok || got.Num != next.Num || last.Code != resp.Code {
+			return nil, nil, fmt.Errorf("server sent another response than block %d", next.Num)
+		}
+		payload = append(payload, last.Payload...)
+	}
+}
+
+// exchange sends a request as a confirmable message, retransmitting it until
+// it is acknowledged, and returns the piggybacked or separate response.
+func (c *conn) exchange(ctx context.Context, req *Message) (*Message, error) {
+	req.Type, req.ID = Confirmable, c.nextID
+	c.nextID++
+	if req.Code != CodeEmpty {
+		req.Token = binary.BigEndian.AppendUint32(nil, uint32(randomUint(1<<32)))
+	}
+	data, err := req.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	// Unblock reads when the context is done
+	stop := context.AfterFunc(ctx, func() { _ = c.udp.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	// The initial timeout is randomized between ackTimeout and 1.5 times it
+	timeout := ackTimeout + time.Duration(randomUint(uint64(ackTimeout/2)+1))
+	acknowledged := false
+	for retransmit := 0; ; {
+		deadline := time.Now().Add(timeout)
+		if acknowledged {
+			deadline = time.Now().Add(maxTransmitWait)
+		} else if _, err := c.udp.Write(data); err != nil {
+			return nil, err
+		}
+		if err := c.udp.SetReadDeadline(deadline); err != nil {
+			return nil, err
+		}
+
+		resp, err := c.read(ctx, req)
+		var netErr net.Error
+		switch {
+		case ctx.Err() != nil:
+			return nil, ctx.Err()
+		case errors.As(err, &netErr) && netErr.Timeout():
+			if acknowledged {
+				return nil, errors.New("no response after the request was acknowledged")
+			}
+			if retransmit++; retransmit > maxRetransmit {
+				return nil, fmt.Errorf("no response after %d retransmissions", maxRetransmit)
+			}
+			timeout *= 2
+		case errors.Is(err, errAcknowledged):
+			acknowledged = true
+		case err != nil:
+			return nil, err
+		default:
+			return resp, nil
+		}
+	}
+}
+
+// errAcknowledged is returned by read when the request was acknowledged by an
+// empty message, so that the response will be separate.
+var errAcknowledged = errors.New("acknowledged")
+
+// read reads messages until the acknowledgement or response of req.
+func (c *conn) read(ctx context.Context, req *Message) (*Message, error) {
+	for {
+		n, err := c.udp.Read(c.buf)
+		if err != nil {
+			return nil, err
+		}
+		m, err := ParseMessage(c.buf[:n])
+		if err != nil {
+			continue
+		}
+		switch {
+		case m.Type == Reset && m.ID == req.ID:
+			return nil, errReset
+		case m.Type == Acknowledgement && m.ID == req.ID && m.Code == CodeEmpty:
+			return nil, errAcknowledged
+		case m.Type == Acknowledgement && m.ID == req.ID && bytes.Equal(m.Token, req.Token):
+			return m, nil
+		case m.Type != Acknowledgement && m.Code != CodeEmpty && bytes.Equal(m.Token, req.Token):
+			// A separate response
+			if m.Type == Confirmable {
+				c.reply(m, Acknowledgement)
+			}
+			return m, nil
+		case m.Type == Confirmable:
+			// An unknown message, such as a retransmitted separate response
+			// that was already acknowledged
+			c.reply(m, Reset)
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// reply sends an empty acknowledgement or reset of a message.
+func (c *conn) reply(m *Message, typ uint8) {
+	data, _ := (&Message{Type: typ, ID: m.ID}).Marshal()
+	_, _ = c.udp.Write(data)
+}
+
+func randomUint(n uint64) uint64 {
+	v, err := rand.Int(rand.Reader, new(big.Int).SetUint64(n))
+	if err != nil {
+		return 0
+	}
+	return v.Uint64()
+}
@@ -0,0 +1,179 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+package coap_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fido-device-onboard/go-fdo-client/internal/coap"
+	"github.com/fido-device-onboard/go-fdo-client/internal/coap/coaptest"
+	"github.com/fido-device-onboard/go-fdo/cbor"
+	"github.com/fido-device-onboard/go-fdo/protocol"
+)
+
+// echo responds to each message with the message type plus one and the
+// request echoed, and hands out an authorization token on the first message.
+func echo(tokens *[]string) coaptest.Handler {
+	return func(req *coaptest.Request) *coaptest.Response {
+		*tokens = append(*tokens, req.Token)
+		resp := &coaptest.Response{MsgType: req.MsgType + 1, Body: req.Body}
+		if req.Token == "" {
+			resp.Token = "session-1"
+		}
+		return resp
+	}
+}
+
+func send(t *testing.T, transport *coap.Transport, msgType uint8, msg any) (uint8, []byte, error) {
+	t.Helper()
+	respType, resp, err := transport.Send(context.Background(), msgType, msg, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer func() { _ = resp.Close() }()
+	body, err := io.ReadAll(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return respType, body, nil
+}
+
+func TestSend(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		separate bool
+		drop     int
+		size     int
+	}{
+		{name: "piggybacked"},
+		{name: "separate", separate: true},
+		{name: "retransmitted", drop: 2},
+		{name: "blockwise", size: 5000},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			defer coap.SetAckTimeout(20 * time.Millisecond)()
+			var tokens []string
+			server := coaptest.NewUnstartedServer(echo(&tokens))
+			server.Separate, server.Drop = tt.separate, tt.drop
+			server.Start()
+
+			transport := &coap.Transport{BaseURL: server.URL}
+			msg := strings.Repeat("x", tt.size)
+			for _, msgType := range []uint8{protocol.TO1HelloRVMsgType, protocol.TO1ProveToRVMsgType} {
+				respType, body, err := send(t, transport, msgType, msg)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var got string
+				if err := cbor.Unmarshal(body, &got); err != nil || respType != msgType+1 || got != msg {
+					t.Fatalf("response %d with %d bytes, %v", respType, len(got), err)
+				}
+			}
+			server.Close()
+			if len(tokens) != 2 || tokens[0] != "" || tokens[1] != "session-1" {
+				t.Errorf("tokens = %q, want the token of the first response sent with the second message", tokens)
+			}
+		})
+	}
+}
+
+func TestSend_SmallBlocks(t *testing.T) {
+	server := coaptest.NewUnstartedServer(func(req *coaptest.Request) *coaptest.Response {
+		body := make([]byte, 3000)
+		copy(body, req.Body)
+		return &coaptest.Response{MsgType: 61, Body: body}
+	})
+	server.BlockSize = 256
+	server.Start()
+	defer server.Close()
+
+	msg := bytes.Repeat([]byte{0x42}, 2000)
+	respType, body, err := send(t, &coap.Transport{BaseURL: server.URL + "/prefix"}, 60, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := cbor.Marshal(msg)
+	if respType != 61 || len(body) != 3000 || !bytes.Equal(body[:len(want)], want) {
+		t.Errorf("response %d with %d bytes", respType, len(body))
+	}
+}
+
+func TestSend_Errors(t *testing.T) {
+	defer coap.SetAckTimeout(10 * time.Millisecond)()
+	errorMessage := func(*coaptest.Request) *coaptest.Response {
+		body, _ := cbor.Marshal(protocol.ErrorMessage{Code: protocol.ResourceNotFound, PrevMsgType: 30})
+		return &coaptest.Response{MsgType: protocol.ErrorMsgType, Body: body}
+	}
+	notFound := func(*coaptest.Request) *coaptest.Response {
+		return &coaptest.Response{Code: coap.CodeNotFound, Body: []byte("no such device")}
+	}
+
+	t.Run("error message", func(t *testing.T) {
+		server := coaptest.NewServer(errorMessage)
+		defer server.Close()
+		respType, _, err := send(t, &coap.Transport{BaseURL: server.URL}, 30, "hello")
+		if err != nil || respType != protocol.ErrorMsgType {
+			t.Errorf("response %d, %v, want an error message", respType, err)
+		}
+	})
+
+	for _, tt := range []struct {
+		name    string
+		baseURL func(t *testing.T) string
+		err     string
+	}{
+		{"response code", func(t *testing.T) string {
+			server := coaptest.NewServer(notFound)
+			t.Cleanup(server.Close)
+			return server.URL
+		}, "unexpected CoAP response code: 4.04: no such device"},
+		{"no server", func(t *testing.T) string {
+			conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { _ = conn.Close() })
+			return "coap://" + conn.LocalAddr().String()
+		}, "no response after 4 retransmissions"},
+		{"DTLS", func(*testing.T) string { return "coaps://127.0.0.1:5684" }, `unsupported CoAP scheme "coaps"`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := send(t, &coap.Transport{BaseURL: tt.baseURL(t)}, 30, "hello")
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestSend_Canceled(t *testing.T) {
+	server := coaptest.NewUnstartedServer(func(*coaptest.Request) *coaptest.Response { return &coaptest.Response{} })
+	server.Drop = 10
+	server.Start()
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, _, err := (&coap.Transport{BaseURL: server.URL}).Send(ctx, 30, "hello", nil); err == nil {
+		t.Fatal("expected an error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("canceled send returned after %s", elapsed)
+	}
+}
+
+func TestPing(t *testing.T) {
+	server := coaptest.NewServer(nil)
+	defer server.Close()
+	if err := coap.Ping(context.Background(), nil, strings.TrimPrefix(server.URL, "coap://")); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,527 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+// Package fdotest provides go-fdo DI, rendezvous and owner servers with their
+// state in memory, standing in for the FDO servers in end-to-end tests of the
+// client, like net/http/httptest.
+package fdotest
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fido-device-onboard/go-fdo"
+	"github.com/fido-device-onboard/go-fdo/cbor"
+	"github.com/fido-device-onboard/go-fdo/cose"
+	"github.com/fido-device-onboard/go-fdo/custom"
+	fdohttp "github.com/fido-device-onboard/go-fdo/http"
+	"github.com/fido-device-onboard/go-fdo/kex"
+	"github.com/fido-device-onboard/go-fdo/protocol"
+	"github.com/fido-device-onboard/go-fdo/serviceinfo"
+)
+
+// Server serves DI, TO0, TO1 and TO2 over HTTP on a loopback port. Devices
+// are initialized with a single rendezvous directive pointing at the server,
+// and their vouchers are extended to the server as the owner, so that a
+// device can be onboarded once its owner address is registered with
+// RegisterOwner. Only ECDSA device keys are supported.
+type Server struct {
+	// URL is the base URL of the server, http://127.0.0.1:<port>.
+	URL string
+
+	srv   *httptest.Server
+	state *state
+}
+
+// NewServer starts a server. It panics if the keys cannot be generated.
+func NewServer() *Server {
+	st, err := newState()
+	if err != nil {
+		panic(fmt.Sprintf("fdotest: %v", err))
+	}
+	s := &Server{state: st}
+	caKey, caChain := st.keys[protocol.Secp384r1KeyType].key, st.keys[protocol.Secp384r1KeyType].chain
+	handler := &fdohttp.Handler{
+		Tokens: st,
+		DIResponder: &fdo.DIServer[custom.DeviceMfgInfo]{
+			Session:               st,
+			Vouchers:              st,
+			SignDeviceCertificate: custom.SignDeviceCertificate(caKey, caChain),
+			DeviceInfo:            st.deviceInfo,
+			RvInfo: func(context.Context, *fdo.Voucher) ([][]protocol.RvInstruction, error) {
+				return s.rvInfo()
+			},
+			BeforeVoucherPersist: fdo.AllInOne{DIAndOwner: st}.Extend,
+		},
+		TO0Responder: &fdo.TO0Server{Session: st, RVBlobs: st},
+		TO1Responder: &fdo.TO1Server{Session: st, RVBlobs: st},
+		TO2Responder: &fdo.TO2Server{
+			Session:              st,
+			Modules:              noModules{},
+			Vouchers:             st,
+			OwnerKeys:            st,
+			VouchersForExtension: st,
+			RvInfo: func(context.Context, fdo.Voucher) ([][]protocol.RvInstruction, error) {
+				return s.rvInfo()
+			},
+			ReuseCredential: func(context.Context, fdo.Voucher) (bool, error) { return false, nil },
+			VerifyVoucher:   func(context.Context, fdo.Voucher) error { return nil },
+		},
+	}
+	s.srv = httptest.NewServer(handler)
+	s.URL = s.srv.URL
+	return s
+}
+
+// Close shuts down the server.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// RegisterOwner runs TO0 for the device guid, registering the server itself
+// as its owner.
+func (s *Server) RegisterOwner(ctx context.Context, guid protocol.GUID) error {
+	host, port, err := s.hostPort()
+	if err != nil {
+		return err
+	}
+	to0 := &fdo.TO0Client{Vouchers: s.state, OwnerKeys: s.state}
+	_, err = to0.RegisterBlob(ctx, &fdohttp.Transport{BaseURL: s.URL}, guid, []protocol.RvTO2Addr{{
+		IPAddress:         &host,
+		Port:              port,
+		TransportProtocol: protocol.HTTPTransport,
+	}})
+	return err
+}
+
+func (s *Server) hostPort() (net.IP, uint16, error) {
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		return nil, 0, err
+	}
+	port, err := strconv.ParseUint(u.Port(), 10, 16)
+	if err != nil {
+		return nil, 0, err
+	}
+	return net.ParseIP(u.Hostname()), uint16(port), nil
+}
+
+// rvInfo returns a rendezvous directive pointing at the server.
+func (s *Server) rvInfo() ([][]protocol.RvInstruction, error) {
+	host, port, err := s.hostPort()
+	if err != nil {
+		return nil, err
+	}
+	var directive []protocol.RvInstruction
+	for _, v := range []struct {
+		variable protocol.RvVar
+		value    any
+	}{
+		{protocol.RVProtocol, protocol.RVProtHTTP},
+		{protocol.RVIPAddress, host},
+		{protocol.RVDevPort, port},
+	} {
+		data, err := cbor.Marshal(v.value)
+		if err != nil {
+			return nil, err
+		}
+		directive = append(directive, protocol.RvInstruction{Variable: v.variable, Value: data})
+	}
+	return [][]protocol.RvInstruction{directive}, nil
+}
+
+// noModules runs TO2 without owner service info modules.
+type noModules struct{}
+
+func (noModules) Module(context.Context) (string, serviceinfo.OwnerModule, error) {
+	return "", nil, errors.New("no service info modules")
+}
+
+func (noModules) NextModule(context.Context) (bool, error) { return false, nil }
+
+func (noModules) CleanupModules(context.Context) {}
+
+// ownerKey is a manufacturer and owner key with its self-signed certificate.
+type ownerKey struct {
+	key   crypto.Signer
+	chain []*x509.Certificate
+}
+
+// session is the state of a protocol session, identified by its token.
+type session struct {
+	chain  []*x509.Certificate
+	ovh    *fdo.VoucherHeader
+	nonce  protocol.Nonce // TO0.OwnerSign or TO1.ProveToRV nonce
+	guid   protocol.GUID
+	rvInfo [][]protocol.RvInstruction
+
+	replacementGUID *protocol.GUID
+	replacementHmac *protocol.Hmac
+	suite           kex.Suite
+	xSession        []byte // encoded, as the server destroys the sessions it gets
+	proveDevice     protocol.Nonce
+	setupDevice     protocol.Nonce
+	mtu             uint16
+	devmod          *serviceinfo.Devmod
+	modules         []string
+	devmodComplete  bool
+}
+
+// state implements the session and persistent state of the servers.
+type state struct {
+	keys map[protocol.KeyType]ownerKey
+
+	mu       sync.Mutex
+	sessions map[string]*session
+	vouchers map[protocol.GUID]*fdo.Voucher
+	rvBlobs  map[protocol.GUID]*cose.Sign1[protocol.To1d, []byte]
+}
+
+func newState() (*state, error) {
+	st := &state{
+		keys:     map[protocol.KeyType]ownerKey{},
+		sessions: map[string]*session{},
+		vouchers: map[protocol.GUID]*fdo.Voucher{},
+		rvBlobs:  map[protocol.GUID]*cose.Sign1[protocol.To1d, []byte]{},
+	}
+	for keyType, curve := range map[protocol.KeyType]elliptic.Curve{
+		protocol.Secp256r1KeyType: elliptic.P256(),
+		protocol.Secp384r1KeyType: elliptic.P384(),
+	} {
+		key, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		template := &x509.Certificate{
+			SerialNumber:          big.NewInt(1),
+			Subject:               pkix.Name{CommonName: "fdotest " + keyType.String()},
+			NotBefore:             time.Now().Add(-time.Hour),
+			NotAfter:              time.Now().Add(24 * time.Hour),
+			BasicConstraintsValid: true,
+			IsCA:                  true,
+			KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+		if err != nil {
+			return nil, err
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+		st.keys[keyType] = ownerKey{key: key, chain: []*x509.Certificate{cert}}
+	}
+	return st, nil
+}
+
+// deviceInfo returns the manufacturer key of the voucher of a device being
+// initialized, in the key encoding the device asked for.
+func (st *state) deviceInfo(_ context.Context, info *custom.DeviceMfgInfo, _ []*x509.Certificate) (string, protocol.PublicKey, error) {
+	k, ok := st.keys[info.KeyType]
+	if !ok {
+		return "", protocol.PublicKey{}, fmt.Errorf("unsupported key type: %s", info.KeyType)
+	}
+	var pub *protocol.PublicKey
+	var err error
+	switch info.KeyEncoding {
+	case protocol.X509KeyEnc, protocol.CoseKeyEnc:
+		pub, err = protocol.NewPublicKey(info.KeyType, k.key.Public().(*ecdsa.PublicKey), info.KeyEncoding == protocol.CoseKeyEnc)
+	case protocol.X5ChainKeyEnc:
+		pub, err = protocol.NewPublicKey(info.KeyType, k.chain, false)
+	default:
+		err = fmt.Errorf("unsupported key encoding: %s", info.KeyEncoding)
+	}
+	if err != nil {
+		return "", protocol.PublicKey{}, err
+	}
+	return info.DeviceInfo, *pub, nil
+}
+
+type tokenKey struct{}
+
+// NewToken starts a session.
+func (st *state) NewToken(context.Context, protocol.Protocol) (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b[:])
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.sessions[token] = &session{}
+	return token, nil
+}
+
+// InvalidateToken ends the session of the context.
+func (st *state) InvalidateToken(ctx context.Context) error {
+	token, _ := st.TokenFromContext(ctx)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if _, ok := st.sessions[token]; !ok {
+		return fdo.ErrNotFound
+	}
+	delete(st.sessions, token)
+	return nil
+}
+
+// TokenContext returns a context of the session of token.
+func (st *state) TokenContext(parent context.Context, token string) context.Context {
+	return context.WithValue(parent, tokenKey{}, token)
+}
+
+// TokenFromContext returns the token of the session of the context.
+func (st *state) TokenFromContext(ctx context.Context) (string, bool) {
+	token, ok := ctx.Value(tokenKey{}).(string)
+	return token, ok
+}
+
+// update calls f with the session of the context.
+func (st *state) update(ctx context.Context, f func(*session)) error {
+	token, _ := st.TokenFromContext(ctx)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	sess, ok := st.sessions[token]
+	if !ok {
+		return fdo.ErrInvalidSession
+	}
+	f(sess)
+	return nil
+}
+
+// get returns a value of the session of the context, or fdo.ErrNotFound if
+// the value is not set.
+func get[T any](ctx context.Context, st *state, f func(*session) (T, bool)) (T, error) {
+	var v T
+	var ok bool
+	if err := st.update(ctx, func(sess *session) { v, ok = f(sess) }); err != nil {
+		return v, err
+	}
+	if !ok {
+		return v, fdo.ErrNotFound
+	}
+	return v, nil
+}
+
+func (st *state) SetDeviceCertChain(ctx context.Context, chain []*x509.Certificate) error {
+	return st.update(ctx, func(sess *session) { sess.chain = chain })
+}
+
+func (st *state) DeviceCertChain(ctx context.Context) ([]*x509.Certificate, error) {
+	return get(ctx, st, func(sess *session) ([]*x509.Certificate, bool) { return sess.chain, sess.chain != nil })
+}
+
+func (st *state) SetIncompleteVoucherHeader(ctx context.Context, ovh *fdo.VoucherHeader) error {
+	return st.update(ctx, func(sess *session) { sess.ovh = ovh })
+}
+
+func (st *state) IncompleteVoucherHeader(ctx context.Context) (*fdo.VoucherHeader, error) {
+	return get(ctx, st, func(sess *session) (*fdo.VoucherHeader, bool) { return sess.ovh, sess.ovh != nil })
+}
+
+func (st *state) SetTO0SignNonce(ctx context.Context, nonce protocol.Nonce) error {
+	return st.update(ctx, func(sess *session) { sess.nonce = nonce })
+}
+
+func (st *state) TO0SignNonce(ctx context.Context) (protocol.Nonce, error) {
+	return get(ctx, st, func(sess *session) (protocol.Nonce, bool) { return sess.nonce, true })
+}
+
+func (st *state) SetTO1ProofNonce(ctx context.Context, nonce protocol.Nonce) error {
+	return st.update(ctx, func(sess *session) { sess.nonce = nonce })
+}
+
+func (st *state) TO1ProofNonce(ctx context.Context) (protocol.Nonce, error) {
+	return get(ctx, st, func(sess *session) (protocol.Nonce, bool) { return sess.nonce, true })
+}
+
+func (st *state) SetGUID(ctx context.Context, guid protocol.GUID) error {
+	return st.update(ctx, func(sess *session) { sess.guid = guid })
+}
+
+func (st *state) GUID(ctx context.Context) (protocol.GUID, error) {
+	return get(ctx, st, func(sess *session) (protocol.GUID, bool) { return sess.guid, true })
+}
+
+func (st *state) SetRvInfo(ctx context.Context, rvInfo [][]protocol.RvInstruction) error {
+	return st.update(ctx, func(sess *session) { sess.rvInfo = rvInfo })
+}
+
+func (st *state) RvInfo(ctx context.Context) ([][]protocol.RvInstruction, error) {
+	return get(ctx, st, func(sess *session) ([][]protocol.RvInstruction, bool) { return sess.rvInfo, sess.rvInfo != nil })
+}
+
+func (st *state) SetReplacementGUID(ctx context.Context, guid protocol.GUID) error {
+	return st.update(ctx, func(sess *session) { sess.replacementGUID = &guid })
+}
+
+func (st *state) ReplacementGUID(ctx context.Context) (protocol.GUID, error) {
+	return get(ctx, st, func(sess *session) (protocol.GUID, bool) {
+		if sess.replacementGUID == nil {
+			return protocol.GUID{}, false
+		}
+		return *sess.replacementGUID, true
+	})
+}
+
+func (st *state) SetReplacementHmac(ctx context.Context, hmac protocol.Hmac) error {
+	return st.update(ctx, func(sess *session) { sess.replacementHmac = &hmac })
+}
+
+func (st *state) ReplacementHmac(ctx context.Context) (protocol.Hmac, error) {
+	return get(ctx, st, func(sess *session) (protocol.Hmac, bool) {
+		if sess.replacementHmac == nil {
+			return protocol.Hmac{}, false
+		}
+		return *sess.replacementHmac, true
+	})
+}
+
+func (st *state) SetXSession(ctx context.Context, suite kex.Suite, xs kex.Session) error {
+	data, err := cbor.Marshal(xs)
+	if err != nil {
+		return err
+	}
+	return st.update(ctx, func(sess *session) { sess.suite, sess.xSession = suite, data })
+}
+
+func (st *state) XSession(ctx context.Context) (kex.Suite, kex.Session, error) {
+	var suite kex.Suite
+	data, err := get(ctx, st, func(sess *session) ([]byte, bool) {
+		suite = sess.suite
+		return sess.xSession, sess.xSession != nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	xs := suite.New(nil, 1)
+	if err := cbor.Unmarshal(data, &xs); err != nil {
+		return "", nil, err
+	}
+	return suite, xs, nil
+}
+
+func (st *state) SetProveDeviceNonce(ctx context.Context, nonce protocol.Nonce) error {
+	return st.update(ctx, func(sess *session) { sess.proveDevice = nonce })
+}
+
+func (st *state) ProveDeviceNonce(ctx context.Context) (protocol.Nonce, error) {
+	return get(ctx, st, func(sess *session) (protocol.Nonce, bool) { return sess.proveDevice, true })
+}
+
+func (st *state) SetSetupDeviceNonce(ctx context.Context, nonce protocol.Nonce) error {
+	return st.update(ctx, func(sess *session) { sess.setupDevice = nonce })
+}
+
+func (st *state) SetupDeviceNonce(ctx context.Context) (protocol.Nonce, error) {
+	return get(ctx, st, func(sess *session) (protocol.Nonce, bool) { return sess.setupDevice, true })
+}
+
+func (st *state) SetMTU(ctx context.Context, mtu uint16) error {
+	return st.update(ctx, func(sess *session) { sess.mtu = mtu })
+}
+
+func (st *state) MTU(ctx context.Context) (uint16, error) {
+	return get(ctx, st, func(sess *session) (uint16, bool) { return sess.mtu, sess.mtu != 0 })
+}
+
+func (st *state) SetDevmod(ctx context.Context, devmod serviceinfo.Devmod, modules []string, complete bool) error {
+	return st.update(ctx, func(sess *session) {
+		sess.devmod, sess.modules, sess.devmodComplete = &devmod, modules, complete
+	})
+}
+
+func (st *state) Devmod(ctx context.Context) (serviceinfo.Devmod, []string, bool, error) {
+	var modules []string
+	var complete bool
+	devmod, err := get(ctx, st, func(sess *session) (*serviceinfo.Devmod, bool) {
+		modules, complete = sess.modules, sess.devmodComplete
+		return sess.devmod, sess.devmod != nil
+	})
+	if err != nil {
+		return serviceinfo.Devmod{}, nil, false, err
+	}
+	return *devmod, modules, complete, nil
+}
+
+func (st *state) SetRVBlob(_ context.Context, ov *fdo.Voucher, to1d *cose.Sign1[protocol.To1d, []byte], _ time.Time) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.rvBlobs[ov.Header.Val.GUID] = to1d
+	st.vouchers[ov.Header.Val.GUID] = ov
+	return nil
+}
+
+func (st *state) RVBlob(_ context.Context, guid protocol.GUID) (*cose.Sign1[protocol.To1d, []byte], *fdo.Voucher, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	to1d, ok := st.rvBlobs[guid]
+	if !ok {
+		return nil, nil, fdo.ErrNotFound
+	}
+	return to1d, st.vouchers[guid], nil
+}
+
+func (st *state) AddVoucher(_ context.Context, ov *fdo.Voucher) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.vouchers[ov.Header.Val.GUID] = ov
+	return nil
+}
+
+func (st *state) ReplaceVoucher(_ context.Context, oldGUID protocol.GUID, ov *fdo.Voucher) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	delete(st.vouchers, oldGUID)
+	st.vouchers[ov.Header.Val.GUID] = ov
+	return nil
+}
+
+func (st *state) RemoveVoucher(_ context.Context, guid protocol.GUID) (*fdo.Voucher, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	ov, ok := st.vouchers[guid]
+	if !ok {
+		return nil, fdo.ErrNotFound
+	}
+	delete(st.vouchers, guid)
+	return ov, nil
+}
+
+func (st *state) Voucher(_ context.Context, guid protocol.GUID) (*fdo.Voucher, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	ov, ok := st.vouchers[guid]
+	if !ok {
+		return nil, fdo.ErrNotFound
+	}
+	return ov, nil
+}
+
+func (st *state) OwnerKey(_ context.Context, keyType protocol.KeyType, _ int) (crypto.Signer, []*x509.Certificate, error) {
+	k, ok := st.keys[keyType]
+	if !ok {
+		return nil, nil, fdo.ErrNotFound
+	}
+	return k.key, k.chain, nil
+}
+
+// ManufacturerKey returns the owner key, the server being both the
+// manufacturer and the owner.
+func (st *state) ManufacturerKey(ctx context.Context, keyType protocol.KeyType, rsaBits int) (crypto.Signer, []*x509.Certificate, error) {
+	return st.OwnerKey(ctx, keyType, rsaBits)
+}
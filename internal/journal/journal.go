@@ -0,0 +1,179 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+// Package journal implements a log/slog handler that writes to the systemd
+// journal over its native protocol, so that the attributes of each record
+// are stored as journal fields that can be matched with journalctl, such as
+// journalctl GUID=<guid>.
+package journal
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// socketPath is the datagram socket journald reads native messages from.
+var socketPath = "/run/systemd/journal/socket"
+
+// Connected reports whether the standard output or standard error of the
+// process is connected to the journal, i.e. whether the process is run by
+// systemd with its output logged to the journal.
+func Connected() bool {
+	stream := os.Getenv("JOURNAL_STREAM")
+	if stream == "" {
+		return false
+	}
+	return isStream(os.Stderr, stream) || isStream(os.Stdout, stream)
+}
+
+// Handler is a slog.Handler writing to the journal. Attribute keys are
+// stored as journal fields named by upper-casing them, with groups joined by
+// underscores and other characters replaced by underscores: the attribute
+// guid becomes the field GUID, and last_error in the group retry becomes
+// RETRY_LAST_ERROR.
+type Handler struct {
+	conn       *net.UnixConn
+	identifier string
+	level      slog.Leveler
+
+	prefix string
+	fields []byte
+}
+
+// NewHandler connects to the journal. Records are logged with the syslog
+// identifier identifier, and from the level of opts, by default from
+// slog.LevelInfo.
+func NewHandler(identifier string, opts *slog.HandlerOptions) (*Handler, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to the journal: %w", err)
+	}
+	h := &Handler{conn: conn, identifier: identifier, level: slog.LevelInfo}
+	if opts != nil && opts.Level != nil {
+		h.level = opts.Level
+	}
+	return h, nil
+}
+
+// Close closes the connection to the journal.
+func (h *Handler) Close() error {
+	return h.conn.Close()
+}
+
+// Enabled reports whether records of the level are logged.
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle sends a record to the journal as a single datagram, or on Linux in
+// a sealed memory file passed with the datagram if the record is too large
+// for one.
+func (h *Handler) Handle(_ context.Context, r slog.Record) error {
+	msg := appendField(nil, "MESSAGE", r.Message)
+	msg = appendField(msg, "PRIORITY", strconv.Itoa(priority(r.Level)))
+	msg = appendField(msg, "SYSLOG_IDENTIFIER", h.identifier)
+	msg = append(msg, h.fields...)
+	r.Attrs(func(a slog.Attr) bool {
+		msg = appendAttr(msg, h.prefix, a)
+		return true
+	})
+	return h.send(msg)
+}
+
+// WithAttrs returns a handler that adds the attributes to every record.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.fields = slices.Clone(h.fields)
+	for _, a := range attrs {
+		h2.fields = appendAttr(h2.fields, h.prefix, a)
+	}
+	return &h2
+}
+
+// WithGroup returns a handler that prefixes the fields of the following
+// attributes with the group name.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix = h.prefix + name + "_"
+	return &h2
+}
+
+// priority returns the syslog priority of a level.
+func priority(level slog.Level) int {
+	switch {
+	case level >= slog.LevelError:
+		return 3 // err
+	case level >= slog.LevelWarn:
+		return 4 // warning
+	case level >= slog.LevelInfo:
+		return 6 // info
+	default:
+		return 7 // debug
+	}
+}
+
+func appendAttr(msg []byte, prefix string, a slog.Attr) []byte {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return msg
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "_"
+		}
+		for _, ga := range a.Value.Group() {
+			msg = appendAttr(msg, prefix, ga)
+		}
+		return msg
+	}
+	var value string
+	if a.Value.Kind() == slog.KindTime {
+		value = a.Value.Time().Format(time.RFC3339Nano)
+	} else {
+		value = a.Value.String()
+	}
+	return appendField(msg, fieldName(prefix+a.Key), value)
+}
+
+// fieldName returns the journal field name of an attribute key. Field names
+// consist of upper case letters, digits and underscores, start with a letter
+// and are at most 64 characters long.
+func fieldName(key string) string {
+	name := []byte(strings.ToUpper(key))
+	for i, c := range name {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			name[i] = '_'
+		}
+	}
+	if len(name) == 0 || name[0] < 'A' || name[0] > 'Z' {
+		name = append([]byte("X_"), name...)
+	}
+	return string(name[:min(len(name), 64)])
+}
+
+// appendField appends a field in the native protocol format: NAME=value for
+// single line values, and the name, the length as a little endian 64-bit
+// integer and the value for values with newlines.
+func appendField(msg []byte, name, value string) []byte {
+	msg = append(msg, name...)
+	if !strings.Contains(value, "\n") {
+		msg = append(msg, '=')
+		msg = append(msg, value...)
+		return append(msg, '\n')
+	}
+	msg = append(msg, '\n')
+	msg = binary.LittleEndian.AppendUint64(msg, uint64(len(value)))
+	msg = append(msg, value...)
+	return append(msg, '\n')
+}
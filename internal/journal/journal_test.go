@@ -0,0 +1,173 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+//go:build unix
+
+package journal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// listen stands in for journald and returns a function receiving the fields
+// of the next message, read from the datagram or from the file passed with
+// it.
+func listen(t *testing.T) func() map[string]string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	orig := socketPath
+	socketPath = path
+	t.Cleanup(func() { socketPath = orig })
+
+	return func() map[string]string {
+		t.Helper()
+		buf, oob := make([]byte, 1<<16), make([]byte, 64)
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg := buf[:n]
+		if oobn > 0 {
+			msg = readPassedFile(t, oob[:oobn])
+		}
+		fields, err := parse(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fields
+	}
+}
+
+// readPassedFile returns the contents of the file passed in the control
+// message of a datagram.
+func readPassedFile(t *testing.T, oob []byte) []byte {
+	t.Helper()
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("control messages %v, %v", msgs, err)
+	}
+	fds, err := syscall.ParseUnixRights(&msgs[0])
+	if err != nil || len(fds) != 1 {
+		t.Fatalf("passed descriptors %v, %v", fds, err)
+	}
+	f := os.NewFile(uintptr(fds[0]), "journal-message")
+	defer func() { _ = f.Close() }()
+	data, err := io.ReadAll(io.NewSectionReader(f, 0, 1<<30))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// parse decodes a message of the native protocol.
+func parse(msg []byte) (map[string]string, error) {
+	fields := map[string]string{}
+	for len(msg) > 0 {
+		end := bytes.IndexAny(msg, "=\n")
+		if end < 0 {
+			return nil, errors.New("field without value")
+		}
+		name := string(msg[:end])
+		if msg[end] == '=' {
+			value, rest, _ := bytes.Cut(msg[end+1:], []byte("\n"))
+			fields[name], msg = string(value), rest
+			continue
+		}
+		msg = msg[end+1:]
+		if len(msg) < 8 {
+			return nil, errors.New("truncated field length")
+		}
+		size := binary.LittleEndian.Uint64(msg)
+		if uint64(len(msg)) < 8+size+1 || msg[8+size] != '\n' {
+			return nil, errors.New("truncated field")
+		}
+		fields[name], msg = string(msg[8:8+size]), msg[8+size+1:]
+	}
+	return fields, nil
+}
+
+func TestHandler(t *testing.T) {
+	receive := listen(t)
+	var level slog.LevelVar
+	h, err := NewHandler("go-fdo-client", &slog.HandlerOptions{Level: &level})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = h.Close() }()
+	logger := slog.New(h).With("guid", "0123abcd")
+
+	logger.Debug("not logged")
+	logger.WithGroup("retry").Warn("TO1 failed\nwith a multi-line error", "directive", 1, "last error", errors.New("timeout"), slog.Group("progress", "attempts", 3))
+	want := map[string]string{
+		"MESSAGE":                 "TO1 failed\nwith a multi-line error",
+		"PRIORITY":                "4",
+		"SYSLOG_IDENTIFIER":       "go-fdo-client",
+		"GUID":                    "0123abcd",
+		"RETRY_DIRECTIVE":         "1",
+		"RETRY_LAST_ERROR":        "timeout",
+		"RETRY_PROGRESS_ATTEMPTS": "3",
+	}
+	if got := receive(); !reflect.DeepEqual(got, want) {
+		t.Errorf("fields = %q, want %q", got, want)
+	}
+
+	level.Set(slog.LevelDebug)
+	logger.Debug("logged", "_private", true, "", "empty key")
+	if got := receive(); got["PRIORITY"] != "7" || got["X__PRIVATE"] != "true" || got["X_"] != "empty key" {
+		t.Errorf("fields = %q", got)
+	}
+}
+
+func TestFieldName(t *testing.T) {
+	for key, want := range map[string]string{
+		"guid":           "GUID",
+		"total_attempts": "TOTAL_ATTEMPTS",
+		"base URL":       "BASE_URL",
+		"9lives":         "X_9LIVES",
+		"é":              "X___",
+	} {
+		if got := fieldName(key); got != want {
+			t.Errorf("fieldName(%q) = %q, want %q", key, got, want)
+		}
+	}
+	if got := fieldName(string(bytes.Repeat([]byte("a"), 100))); len(got) != 64 {
+		t.Errorf("field name of %d characters, want 64", len(got))
+	}
+}
+
+func TestNewHandler_NoJournal(t *testing.T) {
+	orig := socketPath
+	socketPath = filepath.Join(t.TempDir(), "socket")
+	defer func() { socketPath = orig }()
+	if _, err := NewHandler("go-fdo-client", nil); err == nil {
+		t.Error("expected an error without journald")
+	}
+}
+
+func TestConnected(t *testing.T) {
+	t.Setenv("JOURNAL_STREAM", "")
+	if Connected() {
+		t.Error("connected without JOURNAL_STREAM")
+	}
+	t.Setenv("JOURNAL_STREAM", "1:2")
+	if Connected() {
+		t.Error("connected to another stream")
+	}
+}
@@ -0,0 +1,12 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+//go:build !linux
+
+package journal
+
+// send writes a message to the journal as a single datagram.
+func (h *Handler) send(msg []byte) error {
+	_, err := h.conn.Write(msg)
+	return err
+}
@@ -0,0 +1,54 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+//go:build linux
+
+package journal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// send writes a message to the journal. Messages larger than the maximum
+// datagram size are written to a sealed memory file whose descriptor is
+// passed to journald instead, as sd_journal_send does.
+func (h *Handler) send(msg []byte) error {
+	_, err := h.conn.Write(msg)
+	if !errors.Is(err, syscall.EMSGSIZE) && !errors.Is(err, syscall.ENOBUFS) {
+		return err
+	}
+
+	fd, err := unix.MemfdCreate("journal-message", unix.MFD_CLOEXEC|unix.MFD_ALLOW_SEALING)
+	if err != nil {
+		return fmt.Errorf("error creating journal message file: %w", err)
+	}
+	f := os.NewFile(uintptr(fd), "journal-message")
+	defer func() { _ = f.Close() }()
+	if _, err := f.Write(msg); err != nil {
+		return fmt.Errorf("error writing journal message file: %w", err)
+	}
+	// journald only accepts memory files that cannot be modified anymore
+	seals := unix.F_SEAL_SHRINK | unix.F_SEAL_GROW | unix.F_SEAL_WRITE | unix.F_SEAL_SEAL
+	if _, err := unix.FcntlInt(f.Fd(), unix.F_ADD_SEALS, seals); err != nil {
+		return fmt.Errorf("error sealing journal message file: %w", err)
+	}
+	// WriteMsgUnix refuses connected datagram sockets
+	rc, err := h.conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	rights := unix.UnixRights(int(f.Fd()))
+	var sendErr error
+	if err := rc.Write(func(fd uintptr) bool {
+		sendErr = unix.Sendmsg(int(fd), nil, rights, nil, 0)
+		return !errors.Is(sendErr, unix.EAGAIN)
+	}); err != nil {
+		return err
+	}
+	return sendErr
+}
@@ -0,0 +1,30 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+//go:build linux
+
+package journal
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// TestHandler_LargeRecord checks that records too large for a datagram are
+// passed in a memory file.
+func TestHandler_LargeRecord(t *testing.T) {
+	receive := listen(t)
+	h, err := NewHandler("go-fdo-client", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = h.Close() }()
+
+	serviceInfo := strings.Repeat("0123456789abcdef", 1<<16)
+	slog.New(h).Info("Large service info", "service_info", serviceInfo)
+	got := receive()
+	if got["MESSAGE"] != "Large service info" || got["SERVICE_INFO"] != serviceInfo {
+		t.Errorf("received %q with a field of %d bytes, want %d bytes", got["MESSAGE"], len(got["SERVICE_INFO"]), len(serviceInfo))
+	}
+}
@@ -0,0 +1,12 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+//go:build !unix
+
+package journal
+
+import "os"
+
+// isStream reports whether f is the journal stream of $JOURNAL_STREAM, which
+// only exists on systems run by systemd.
+func isStream(*os.File, string) bool { return false }
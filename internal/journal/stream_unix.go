@@ -0,0 +1,23 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+//go:build unix
+
+package journal
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// isStream reports whether f is the journal stream of $JOURNAL_STREAM,
+// formatted as <device>:<inode>.
+func isStream(f *os.File, stream string) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	return ok && fmt.Sprintf("%d:%d", st.Dev, st.Ino) == stream
+}
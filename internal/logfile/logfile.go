@@ -0,0 +1,118 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+// Package logfile implements a log file that is rotated when it reaches a
+// maximum size, keeping a number of old files next to it as <path>.1 (the
+// most recent) to <path>.<backups>.
+package logfile
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// File is a log file, safe for concurrent use.
+type File struct {
+	path    string
+	maxSize int64
+	backups int
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+// Open opens the log file at path for appending, creating it if needed. The
+// file is rotated before a write would make it larger than maxSize bytes,
+// unless maxSize is 0.
+func Open(path string, maxSize int64, backups int) (*File, error) {
+	if maxSize < 0 || backups < 0 {
+		return nil, errors.New("log file size and number of backups must not be negative")
+	}
+	lf := &File{path: path, maxSize: maxSize, backups: backups}
+	if err := lf.open(); err != nil {
+		return nil, err
+	}
+	return lf, nil
+}
+
+func (lf *File) open() error {
+	f, err := os.OpenFile(lf.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	lf.f, lf.size = f, info.Size()
+	return nil
+}
+
+// Write appends p to the file, rotating it first if p does not fit. A single
+// write larger than the maximum size is written to a new file.
+func (lf *File) Write(p []byte) (int, error) {
+	lf.mu.Lock()
+	defer lf.mu.Unlock()
+	if lf.f == nil {
+		return 0, fs.ErrClosed
+	}
+	if lf.maxSize > 0 && lf.size > 0 && lf.size+int64(len(p)) > lf.maxSize {
+		if err := lf.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := lf.f.Write(p)
+	lf.size += int64(n)
+	return n, err
+}
+
+// rotate renames the file to <path>.1, shifting the older files up and
+// removing the oldest, and opens a new file. The file is reopened even if
+// renaming fails, so that logging continues.
+func (lf *File) rotate() error {
+	if err := lf.f.Close(); err != nil {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	lf.f = nil
+	var err error
+	if lf.backups == 0 {
+		err = os.Remove(lf.path)
+	}
+	for i := lf.backups - 1; i >= 0 && err == nil; i-- {
+		if err = os.Rename(lf.backup(i), lf.backup(i+1)); errors.Is(err, fs.ErrNotExist) {
+			err = nil
+		}
+	}
+	if openErr := lf.open(); openErr != nil {
+		return openErr
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	return nil
+}
+
+// backup returns the path of the ith old file, or of the file itself for 0.
+func (lf *File) backup(i int) string {
+	if i == 0 {
+		return lf.path
+	}
+	return fmt.Sprintf("%s.%d", lf.path, i)
+}
+
+// Close closes the file.
+func (lf *File) Close() error {
+	lf.mu.Lock()
+	defer lf.mu.Unlock()
+	if lf.f == nil {
+		return nil
+	}
+	err := lf.f.Close()
+	lf.f = nil
+	return err
+}
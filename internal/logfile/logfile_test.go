@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+package logfile
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "<none>"
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRotate(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		maxSize int64
+		backups int
+		want    []string // the file and its backups
+	}{
+		{"no rotation", 0, 2, []string{"aaaa\nbbbb\ncccc\ndddd\neeee\nffff\n", "<none>"}},
+		{"two backups", 10, 2, []string{"eeee\nffff\n", "cccc\ndddd\n", "aaaa\nbbbb\n", "<none>"}},
+		{"one backup", 10, 1, []string{"eeee\nffff\n", "cccc\ndddd\n", "<none>"}},
+		{"no backups", 10, 0, []string{"eeee\nffff\n", "<none>"}},
+		{"oversized writes", 3, 1, []string{"ffff\n", "eeee\n", "<none>"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "client.log")
+			lf, err := Open(path, tt.maxSize, tt.backups)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, line := range []string{"aaaa\n", "bbbb\n", "cccc\n", "dddd\n", "eeee\n", "ffff\n"} {
+				if _, err := lf.Write([]byte(line)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := lf.Close(); err != nil {
+				t.Fatal(err)
+			}
+			for i, want := range tt.want {
+				if got := readFile(t, lf.backup(i)); got != want {
+					t.Errorf("%s = %q, want %q", lf.backup(i), got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "client.log")
+	if err := os.WriteFile(path, []byte("aaaa\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// The existing content counts towards the size
+	lf, err := Open(path, 8, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = lf.Close() }()
+	if _, err := lf.Write([]byte("bbbb\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got, old := readFile(t, path), readFile(t, path+".1"); got != "bbbb\n" || old != "aaaa\n" {
+		t.Errorf("file = %q, backup = %q", got, old)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("mode = %o, want 600", perm)
+	}
+}
+
+func TestWriteClosed(t *testing.T) {
+	lf, err := Open(filepath.Join(t.TempDir(), "client.log"), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := lf.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lf.Write([]byte("x")); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("error = %v, want %v", err, fs.ErrClosed)
+	}
+}
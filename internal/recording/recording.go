@@ -0,0 +1,196 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+// Package recording records the FDO messages exchanged by a client and
+// replays them to reproduce a session without the servers.
+//
+// A recording is a file of JSON lines, one per exchange, holding the CBOR
+// encoded request and response messages as seen by the client: TO2 messages
+// are recorded decrypted, so recordings contain the service info and must be
+// handled like the device credential.
+//
+// Only DI and TO1 can be replayed. In TO2 the device sends a fresh nonce and
+// key exchange share, which the owner's recorded responses are bound to, so
+// the client rejects them; TO2 exchanges are recorded for inspection only.
+package recording
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/fido-device-onboard/go-fdo"
+	"github.com/fido-device-onboard/go-fdo/cbor"
+	"github.com/fido-device-onboard/go-fdo/kex"
+	"github.com/fido-device-onboard/go-fdo/protocol"
+)
+
+var (
+	// ErrDiverged is returned when the client sends a message that is not
+	// the next one of the recording.
+	ErrDiverged = errors.New("replay diverged from the recording")
+
+	// ErrTO2 is returned when the client sends a TO2 message to a replay.
+	ErrTO2 = errors.New("TO2 cannot be replayed: the owner's recorded responses are bound to the fresh nonces and key exchange of the recorded session")
+)
+
+// Exchange is a request and its response, or the error that the request
+// failed with.
+type Exchange struct {
+	Time     time.Time `json:"time"`
+	URL      string    `json:"url"`
+	MsgType  uint8     `json:"msg_type"`
+	Msg      []byte    `json:"msg"`
+	RespType uint8     `json:"resp_type,omitempty"`
+	Resp     []byte    `json:"resp,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// Recorder writes the exchanges of transports to a recording.
+type Recorder struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// NewRecorder returns a recorder writing to w.
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{enc: json.NewEncoder(w)}
+}
+
+// Err returns the first error writing the recording.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Transport returns a transport to the server at baseURL that sends the
+// messages with next and records them.
+func (r *Recorder) Transport(baseURL string, next fdo.Transport) fdo.Transport {
+	return &recordTransport{recorder: r, url: baseURL, next: next}
+}
+
+func (r *Recorder) record(x *Exchange) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.enc.Encode(x); err != nil && r.err == nil {
+		r.err = fmt.Errorf("error writing recording: %w", err)
+	}
+}
+
+type recordTransport struct {
+	recorder *Recorder
+	url      string
+	next     fdo.Transport
+}
+
+func (t *recordTransport) Send(ctx context.Context, msgType uint8, msg any, sess kex.Session) (uint8, io.ReadCloser, error) {
+	x := &Exchange{Time: time.Now().UTC(), URL: t.url, MsgType: msgType}
+	var err error
+	if x.Msg, err = cbor.Marshal(msg); err != nil {
+		return 0, nil, fmt.Errorf("error encoding message %d for the recording: %w", msgType, err)
+	}
+
+	respType, resp, err := t.next.Send(ctx, msgType, msg, sess)
+	if err != nil {
+		x.Error = err.Error()
+		t.recorder.record(x)
+		return 0, nil, err
+	}
+	defer func() { _ = resp.Close() }()
+	x.RespType = respType
+	if x.Resp, err = io.ReadAll(resp); err != nil {
+		x.Error = err.Error()
+		t.recorder.record(x)
+		return 0, nil, err
+	}
+	t.recorder.record(x)
+	return respType, io.NopCloser(bytes.NewReader(x.Resp)), nil
+}
+
+// Replayer serves the exchanges of a recording in order.
+type Replayer struct {
+	mu        sync.Mutex
+	exchanges []Exchange
+	next      int
+}
+
+// NewReplayer reads a recording.
+func NewReplayer(r io.Reader) (*Replayer, error) {
+	var exchanges []Exchange
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 16<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var x Exchange
+		if err := json.Unmarshal(scanner.Bytes(), &x); err != nil {
+			return nil, fmt.Errorf("invalid recording, line %d: %w", line, err)
+		}
+		exchanges = append(exchanges, x)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading recording: %w", err)
+	}
+	if len(exchanges) == 0 {
+		return nil, errors.New("recording holds no exchanges")
+	}
+	return &Replayer{exchanges: exchanges}, nil
+}
+
+// Remaining returns the number of exchanges not yet replayed.
+func (r *Replayer) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.exchanges) - r.next
+}
+
+// Transport returns a transport to the server at baseURL that responds with
+// the next exchange of the recording. The client must send the recorded
+// messages to the recorded servers in the same order: a request of another
+// message type or to another server fails with ErrDiverged, and TO2 messages
+// fail with ErrTO2. The message bodies are not compared, as they contain
+// fresh nonces.
+func (r *Replayer) Transport(baseURL string) fdo.Transport {
+	return &replayTransport{replayer: r, url: baseURL}
+}
+
+type replayTransport struct {
+	replayer *Replayer
+	url      string
+}
+
+func (t *replayTransport) Send(ctx context.Context, msgType uint8, msg any, sess kex.Session) (uint8, io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	if msgType >= protocol.TO2HelloDeviceMsgType && msgType <= protocol.TO2Done2MsgType {
+		return 0, nil, ErrTO2
+	}
+
+	r := t.replayer
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.next == len(r.exchanges) {
+		return 0, nil, fmt.Errorf("%w: message %d to %s sent after the end of the recording", ErrDiverged, msgType, t.url)
+	}
+	x := r.exchanges[r.next]
+	if x.MsgType != msgType || x.URL != t.url {
+		return 0, nil, fmt.Errorf("%w: exchange %d of the recording is message %d to %s, the client sent message %d to %s",
+			ErrDiverged, r.next+1, x.MsgType, x.URL, msgType, t.url)
+	}
+	r.next++
+	if x.Error != "" {
+		return 0, nil, errors.New(x.Error)
+	}
+	return x.RespType, io.NopCloser(bytes.NewReader(x.Resp)), nil
+}
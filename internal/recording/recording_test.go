@@ -0,0 +1,143 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+package recording
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/fido-device-onboard/go-fdo"
+	"github.com/fido-device-onboard/go-fdo/cbor"
+	"github.com/fido-device-onboard/go-fdo/kex"
+)
+
+// serverTransport responds to each message with the message type plus one and
+// the request echoed, or fails the message types in fail.
+type serverTransport struct {
+	fail map[uint8]error
+}
+
+func (s serverTransport) Send(ctx context.Context, msgType uint8, msg any, sess kex.Session) (uint8, io.ReadCloser, error) {
+	if err := s.fail[msgType]; err != nil {
+		return 0, nil, err
+	}
+	data, err := cbor.Marshal(msg)
+	if err != nil {
+		return 0, nil, err
+	}
+	return msgType + 1, io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func send(t *testing.T, transport fdo.Transport, msgType uint8, msg any) (uint8, string, error) {
+	t.Helper()
+	respType, resp, err := transport.Send(context.Background(), msgType, msg, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	defer func() { _ = resp.Close() }()
+	var body string
+	if err := cbor.NewDecoder(resp).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	return respType, body, nil
+}
+
+func TestRecordReplay(t *testing.T) {
+	var file bytes.Buffer
+	recorder := NewRecorder(&file)
+	server := serverTransport{fail: map[uint8]error{30: errors.New("connection refused")}}
+
+	// TO1 fails with the first rendezvous server and succeeds with the second
+	if _, _, err := send(t, recorder.Transport("https://rv1.example.com", server), 30, "hello"); err == nil {
+		t.Fatal("expected TO1 to fail")
+	}
+	server.fail = nil
+	for _, msg := range []struct {
+		url     string
+		msgType uint8
+		body    string
+	}{
+		{"https://rv2.example.com", 30, "hello rv"},
+		{"https://rv2.example.com", 32, "prove"},
+		{"https://owner.example.com", 60, "hello owner"},
+	} {
+		respType, body, err := send(t, recorder.Transport(msg.url, server), msg.msgType, msg.body)
+		if err != nil || respType != msg.msgType+1 || body != msg.body {
+			t.Fatalf("recorded send = %d, %q, %v", respType, body, err)
+		}
+	}
+	if err := recorder.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(file.String(), "\n"); lines != 4 {
+		t.Fatalf("recording has %d lines, want 4:\n%s", lines, file.String())
+	}
+
+	replayer, err := NewReplayer(bytes.NewReader(file.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := send(t, replayer.Transport("https://rv1.example.com"), 30, "new nonce"); err == nil || err.Error() != "connection refused" {
+		t.Errorf("replayed error = %v, want connection refused", err)
+	}
+	respType, body, err := send(t, replayer.Transport("https://rv2.example.com"), 30, "new nonce")
+	if err != nil || respType != 31 || body != "hello rv" {
+		t.Errorf("replayed send = %d, %q, %v, want the recorded response", respType, body, err)
+	}
+
+	// Diverging from the recording fails without consuming the exchange
+	if _, _, err := send(t, replayer.Transport("https://rv2.example.com"), 30, "x"); !errors.Is(err, ErrDiverged) || !strings.Contains(err.Error(), "exchange 3 of the recording is message 32 to https://rv2.example.com, the client sent message 30") {
+		t.Errorf("error = %v, want a divergence of the message type", err)
+	}
+	if _, _, err := send(t, replayer.Transport("https://rv3.example.com"), 32, "x"); !errors.Is(err, ErrDiverged) || !strings.Contains(err.Error(), "the client sent message 32 to https://rv3.example.com") {
+		t.Errorf("error = %v, want a divergence of the server", err)
+	}
+	if replayer.Remaining() != 2 {
+		t.Errorf("remaining = %d, want 2", replayer.Remaining())
+	}
+	if _, _, err := send(t, replayer.Transport("https://rv2.example.com"), 32, "x"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The recorded TO2 exchange is not replayed
+	if _, _, err := send(t, replayer.Transport("https://owner.example.com"), 60, "x"); !errors.Is(err, ErrTO2) {
+		t.Errorf("error = %v, want %v", err, ErrTO2)
+	}
+	if replayer.Remaining() != 1 {
+		t.Errorf("remaining = %d, want 1", replayer.Remaining())
+	}
+}
+
+func TestReplay_End(t *testing.T) {
+	replayer, err := NewReplayer(strings.NewReader(`{"url": "https://rv.example.com", "msg_type": 30, "error": "timeout"}` + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := replayer.Transport("https://rv.example.com")
+	if _, _, err := send(t, transport, 30, "x"); err == nil || err.Error() != "timeout" {
+		t.Errorf("replayed error = %v, want timeout", err)
+	}
+	if _, _, err := send(t, transport, 30, "x"); !errors.Is(err, ErrDiverged) || !strings.Contains(err.Error(), "after the end of the recording") {
+		t.Errorf("error = %v, want the end of the recording", err)
+	}
+}
+
+func TestNewReplayer_Errors(t *testing.T) {
+	for _, tt := range []struct {
+		name, data, err string
+	}{
+		{"empty", "\n", "no exchanges"},
+		{"invalid JSON", `{"msg_type": 30}` + "\nnot json\n", "line 2"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewReplayer(strings.NewReader(tt.data)); err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+// Package sdnotify implements the systemd service notification protocol
+// (sd_notify) over the datagram socket named by $NOTIFY_SOCKET.
+package sdnotify
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Service state notifications understood by systemd.
+const (
+	Ready     = "READY=1"
+	Reloading = "RELOADING=1"
+	Stopping  = "STOPPING=1"
+	Watchdog  = "WATCHDOG=1"
+)
+
+// Status returns a notification setting the free-form status shown by
+// systemctl status.
+func Status(status string) string {
+	return "STATUS=" + strings.ReplaceAll(status, "\n", " ")
+}
+
+// Notifier sends notifications to the service manager. A nil Notifier
+// discards all notifications.
+type Notifier struct {
+	conn *net.UnixConn
+}
+
+// New connects to the socket in $NOTIFY_SOCKET. It returns a nil Notifier
+// when the variable is not set, i.e. when the process is not run by systemd
+// as a Type=notify service.
+func New() (*Notifier, error) {
+	addr := os.Getenv("NOTIFY_SOCKET")
+	if addr == "" {
+		return nil, nil
+	}
+	// Filesystem and abstract ('@' prefixed) sockets are supported, vsock
+	// addresses used by virtual machine managers are not.
+	if !strings.HasPrefix(addr, "/") && !strings.HasPrefix(addr, "@") {
+		return nil, fmt.Errorf("unsupported NOTIFY_SOCKET address: %q", addr)
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to NOTIFY_SOCKET: %w", err)
+	}
+	return &Notifier{conn: conn}, nil
+}
+
+// Notify sends the given notifications in a single datagram.
+func (n *Notifier) Notify(states ...string) error {
+	if n == nil || len(states) == 0 {
+		return nil
+	}
+	if _, err := n.conn.Write([]byte(strings.Join(states, "\n"))); err != nil {
+		return fmt.Errorf("error sending notification: %w", err)
+	}
+	return nil
+}
+
+// Close closes the connection to the service manager.
+func (n *Notifier) Close() error {
+	if n == nil {
+		return nil
+	}
+	return n.conn.Close()
+}
+
+// WatchdogInterval returns the watchdog timeout configured with WatchdogSec=
+// for this process, or 0 if the watchdog is disabled. WATCHDOG=1 must be sent
+// more often than that, systemd recommends every half of the interval.
+func WatchdogInterval() (time.Duration, error) {
+	usec := os.Getenv("WATCHDOG_USEC")
+	if usec == "" {
+		return 0, nil
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0, nil
+	}
+	n, err := strconv.ParseUint(usec, 10, 63)
+	if err != nil || n == 0 {
+		return 0, fmt.Errorf("invalid WATCHDOG_USEC: %q", usec)
+	}
+	return time.Duration(n) * time.Microsecond, nil
+}
@@ -0,0 +1,100 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+//go:build unix
+
+package sdnotify
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func listen(t *testing.T) *net.UnixConn {
+	t.Helper()
+	// Socket paths are limited to about 100 bytes, t.TempDir may be longer
+	dir, err := os.MkdirTemp("", "sdnotify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	path := filepath.Join(dir, "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", path)
+	return conn
+}
+
+func TestNotify(t *testing.T) {
+	conn := listen(t)
+
+	n, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = n.Close() }()
+	if err := n.Notify(Ready, Status("TO1\nwith directive 0")); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 4096)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	size, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(buf[:size]), "READY=1\nSTATUS=TO1 with directive 0"; got != want {
+		t.Errorf("datagram = %q, want %q", got, want)
+	}
+}
+
+func TestNotifyUnset(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	n, err := New()
+	if err != nil || n != nil {
+		t.Fatalf("New() = %v, %v, want nil notifier", n, err)
+	}
+	if err := n.Notify(Ready); err != nil {
+		t.Errorf("Notify on nil notifier: %v", err)
+	}
+}
+
+func TestNotifyUnsupportedAddress(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "vsock:2:1234")
+	if _, err := New(); err == nil {
+		t.Error("expected error for vsock address")
+	}
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	tests := []struct {
+		name    string
+		usec    string
+		pid     string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "disabled"},
+		{name: "enabled", usec: "30000000", want: 30 * time.Second},
+		{name: "this process", usec: "1000", pid: strconv.Itoa(os.Getpid()), want: time.Millisecond},
+		{name: "other process", usec: "1000", pid: "1"},
+		{name: "invalid", usec: "soon", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("WATCHDOG_USEC", tt.usec)
+			t.Setenv("WATCHDOG_PID", tt.pid)
+			got, err := WatchdogInterval()
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("WatchdogInterval() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
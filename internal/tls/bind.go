@@ -0,0 +1,19 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+//go:build !linux
+
+package tls
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// bindToInterface returns a dialer control function failing every
+// connection, binding to a network interface is only supported on Linux.
+func bindToInterface(name string) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		return fmt.Errorf("binding to interface %s is only supported on Linux", name)
+	}
+}
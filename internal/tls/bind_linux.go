@@ -0,0 +1,27 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+package tls
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// bindToInterface returns a dialer control function binding sockets to a
+// network interface, so that connections leave through it whatever the
+// routing table prefers.
+func bindToInterface(name string) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		var err error
+		if ctrlErr := c.Control(func(fd uintptr) {
+			err = syscall.SetsockoptString(int(fd), syscall.SOL_SOCKET, syscall.SO_BINDTODEVICE, name)
+		}); ctrlErr != nil {
+			return ctrlErr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to bind to interface %s: %w", name, err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,224 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+package tls
+
+import (
+	"bytes"
+	"cmp"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// preferredCipherSuites are the cipher suites offered to servers.
+var preferredCipherSuites = []uint16{
+	tls.TLS_AES_256_GCM_SHA384,                  // TLS v1.3
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,   // TLS v1.2
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384, // TLS v1.2
+}
+
+// CompatibleCipherSuites returns the secure cipher suites of crypto/tls, which
+// add AES-128-GCM and ChaCha20-Poly1305 to the preferred cipher suites for
+// servers that do not support AES-256-GCM.
+func CompatibleCipherSuites() []uint16 {
+	var ids []uint16
+	for _, suite := range tls.CipherSuites() {
+		ids = append(ids, suite.ID)
+	}
+	return ids
+}
+
+// spkiPinPrefix is the optional prefix of SPKI pins, as used by curl's
+// --pinnedpubkey.
+const spkiPinPrefix = "sha256//"
+
+// Options configures how the server certificate of a connection is verified.
+type Options struct {
+	// CAFile is a PEM bundle of the CA certificates trusted instead of the
+	// system roots.
+	CAFile string
+	// PinSPKI lists the SHA-256 hashes of the SubjectPublicKeyInfo of
+	// which one must be in the verified certificate chain, base64 encoded
+	// and optionally prefixed with "sha256//".
+	PinSPKI []string
+	// ServerName is the name the server certificate is verified against
+	// and sent as SNI instead of the host of the URL.
+	ServerName string
+	// InsecureSkipVerify skips verifying the certificate chain. Pins are
+	// still checked, against the server's own certificate only.
+	InsecureSkipVerify bool
+
+	// ClientCertFile is a PEM file of the certificate chain presented to
+	// servers that request a client certificate, with the private key in
+	// ClientKeyFile.
+	ClientCertFile string
+	ClientKeyFile  string
+	// ClientKey, if not nil, is the private key of the client certificate
+	// instead of ClientKeyFile, such as a TPM key. It is expected to sign
+	// with the hash matching its size, and with PKCS #1 v1.5 for RSA keys.
+	ClientKey crypto.Signer
+
+	// MinVersion is the minimum TLS version, TLS 1.2 if zero.
+	MinVersion uint16
+	// CipherSuites are the TLS 1.2 cipher suites offered instead of the
+	// preferred AES-256-GCM suites. The TLS 1.3 suites are not configurable.
+	CipherSuites []uint16
+}
+
+// ParseSPKIPin decodes an SPKI pin to a SHA-256 hash.
+func ParseSPKIPin(pin string) ([]byte, error) {
+	hash, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(pin, spkiPinPrefix))
+	if err != nil || len(hash) != sha256.Size {
+		return nil, fmt.Errorf("invalid SPKI pin %q: must be a base64 encoded SHA-256 hash", pin)
+	}
+	return hash, nil
+}
+
+// SPKIPin returns the SPKI pin of a certificate.
+func SPKIPin(cert *x509.Certificate) string {
+	hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return spkiPinPrefix + base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// Config returns the TLS client configuration of the options.
+func Config(opts Options) (*tls.Config, error) {
+	conf := &tls.Config{
+		MinVersion:         cmp.Or(opts.MinVersion, tls.VersionTLS12),
+		CipherSuites:       opts.CipherSuites,
+		ServerName:         opts.ServerName,
+		InsecureSkipVerify: opts.InsecureSkipVerify, //nolint:gosec
+	}
+	if conf.CipherSuites == nil {
+		conf.CipherSuites = preferredCipherSuites
+	}
+
+	if opts.CAFile != "" {
+		data, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no PEM certificates found in CA bundle %s", opts.CAFile)
+		}
+		conf.RootCAs = pool
+	}
+
+	if len(opts.PinSPKI) > 0 {
+		var pins [][]byte
+		for _, pin := range opts.PinSPKI {
+			hash, err := ParseSPKIPin(pin)
+			if err != nil {
+				return nil, err
+			}
+			pins = append(pins, hash)
+		}
+		conf.VerifyConnection = func(cs tls.ConnectionState) error {
+			return verifyPins(cs, pins)
+		}
+	}
+
+	if opts.ClientCertFile != "" {
+		cert, err := loadClientCertificate(opts)
+		if err != nil {
+			return nil, err
+		}
+		// Present the certificate even if its issuer is not among the CAs
+		// the server lists as acceptable, the server decides
+		conf.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return &cert, nil
+		}
+		// TLS 1.3 requires RSA-PSS signatures, which the key cannot make
+		if opts.ClientKey != nil {
+			if _, ok := opts.ClientKey.Public().(*rsa.PublicKey); ok {
+				conf.MaxVersion = tls.VersionTLS12
+			}
+		}
+	}
+	if conf.MaxVersion != 0 && conf.MinVersion > conf.MaxVersion {
+		return nil, errors.New("the RSA client key only supports TLS 1.2, below the minimum TLS version")
+	}
+	return conf, nil
+}
+
+// loadClientCertificate reads the client certificate chain and its private
+// key.
+func loadClientCertificate(opts Options) (tls.Certificate, error) {
+	if opts.ClientKey == nil {
+		cert, err := tls.LoadX509KeyPair(opts.ClientCertFile, opts.ClientKeyFile)
+		if err != nil {
+			return tls.Certificate{}, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		return cert, nil
+	}
+
+	data, err := os.ReadFile(opts.ClientCertFile)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to read client certificate: %w", err)
+	}
+	var cert tls.Certificate
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type == "CERTIFICATE" {
+			cert.Certificate = append(cert.Certificate, block.Bytes)
+		}
+	}
+	if len(cert.Certificate) == 0 {
+		return tls.Certificate{}, fmt.Errorf("no PEM certificates found in client certificate %s", opts.ClientCertFile)
+	}
+	if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to parse client certificate: %w", err)
+	}
+	if pub, ok := cert.Leaf.PublicKey.(interface{ Equal(crypto.PublicKey) bool }); !ok || !pub.Equal(opts.ClientKey.Public()) {
+		return tls.Certificate{}, fmt.Errorf("client certificate %s is not issued for the client key", opts.ClientCertFile)
+	}
+	cert.PrivateKey = opts.ClientKey
+	cert.SupportedSignatureAlgorithms = signatureSchemes(opts.ClientKey.Public())
+	return cert, nil
+}
+
+// signatureSchemes returns the TLS signature schemes of a key signing with
+// the hash matching its size and PKCS #1 v1.5 for RSA.
+func signatureSchemes(pub crypto.PublicKey) []tls.SignatureScheme {
+	switch pub := pub.(type) {
+	case *ecdsa.PublicKey:
+		if pub.Curve.Params().BitSize > 256 {
+			return []tls.SignatureScheme{tls.ECDSAWithP384AndSHA384}
+		}
+		return []tls.SignatureScheme{tls.ECDSAWithP256AndSHA256}
+	case *rsa.PublicKey:
+		if pub.Size() > 256 {
+			return []tls.SignatureScheme{tls.PKCS1WithSHA384}
+		}
+		return []tls.SignatureScheme{tls.PKCS1WithSHA256}
+	}
+	return nil
+}
+
+// verifyPins checks that a certificate of the verified chains, or the server
+// certificate if the chain was not verified, has one of the pinned SPKI
+// hashes.
+func verifyPins(cs tls.ConnectionState, pins [][]byte) error {
+	certs := cs.PeerCertificates[:min(1, len(cs.PeerCertificates))]
+	for _, chain := range cs.VerifiedChains {
+		certs = append(certs, chain...)
+	}
+	for _, cert := range certs {
+		hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+		for _, pin := range pins {
+			if bytes.Equal(hash[:], pin) {
+				return nil
+			}
+		}
+	}
+	return errors.New("server certificate chain does not match any pinned SPKI hash")
+}
@@ -0,0 +1,263 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+package tls
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeCAFile writes the certificate of a test server to a PEM file.
+func writeCAFile(t *testing.T, srv *httptest.Server) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfig(t *testing.T) {
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+	caFile := writeCAFile(t, srv)
+	pin := SPKIPin(srv.Certificate())
+	otherPin := "sha256//" + strings.Repeat("A", 43) + "="
+
+	tests := []struct {
+		name string
+		opts Options
+		err  string
+	}{
+		{"system roots", Options{}, "certificate signed by unknown authority"},
+		{"CA bundle", Options{CAFile: caFile}, ""},
+		{"CA bundle and pin", Options{CAFile: caFile, PinSPKI: []string{otherPin, pin}}, ""},
+		{"pin without prefix", Options{CAFile: caFile, PinSPKI: []string{strings.TrimPrefix(pin, "sha256//")}}, ""},
+		{"pin mismatch", Options{CAFile: caFile, PinSPKI: []string{otherPin}}, "does not match any pinned SPKI hash"},
+		{"insecure with pin", Options{InsecureSkipVerify: true, PinSPKI: []string{pin}}, ""},
+		{"insecure with pin mismatch", Options{InsecureSkipVerify: true, PinSPKI: []string{otherPin}}, "does not match any pinned SPKI hash"},
+		{"server name", Options{CAFile: caFile, ServerName: "example.com"}, ""},
+		{"server name mismatch", Options{CAFile: caFile, ServerName: "owner.example.org"}, "not owner.example.org"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf, err := Config(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: conf}}
+			resp, err := client.Get(srv.URL)
+			if err == nil {
+				_ = resp.Body.Close()
+			}
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("request failed: %v", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Errorf("error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestConfig_Errors(t *testing.T) {
+	dir := t.TempDir()
+	notPEM := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opts Options
+		err  string
+	}{
+		{"missing CA bundle", Options{CAFile: filepath.Join(dir, "missing.pem")}, "failed to read CA bundle"},
+		{"no certificates", Options{CAFile: notPEM}, "no PEM certificates found"},
+		{"invalid pin", Options{PinSPKI: []string{"sha256//not-base64"}}, "invalid SPKI pin"},
+		{"short pin", Options{PinSPKI: []string{"AAAA"}}, "invalid SPKI pin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Config(tt.opts); err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestConfig_CipherSuites(t *testing.T) {
+	conf, err := Config(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conf.CipherSuites) == 0 || conf.CipherSuites[0] != tls.TLS_AES_256_GCM_SHA384 {
+		t.Errorf("cipher suites = %v, want the preferred cipher suites", conf.CipherSuites)
+	}
+}
+
+// pkcs1Signer signs with PKCS #1 v1.5 whatever the options, like an RSA key in
+// a TPM.
+type pkcs1Signer struct{ key *rsa.PrivateKey }
+
+func (s pkcs1Signer) Public() crypto.PublicKey { return s.key.Public() }
+
+func (s pkcs1Signer) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return rsa.SignPKCS1v15(rand, s.key, opts.HashFunc(), digest)
+}
+
+// issueCert issues a certificate for a key and writes it to a PEM file.
+func issueCert(t *testing.T, template, parent *x509.Certificate, pub crypto.PublicKey, parentKey crypto.Signer) (*x509.Certificate, string) {
+	t.Helper()
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, pub, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "cert.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return cert, path
+}
+
+func TestConfig_ClientCertificate(t *testing.T) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Client CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	ca, _ := issueCert(t, caTemplate, caTemplate, caKey.Public(), caKey)
+	clientTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "device"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ecCert := issueCert(t, clientTemplate, ca, ecKey.Public(), caKey)
+	ecKeyDER, err := x509.MarshalECPrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKeyFile := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(ecKeyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: ecKeyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, rsaCert := issueCert(t, clientTemplate, ca, rsaKey.Public(), caKey)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	srv.StartTLS()
+	defer srv.Close()
+
+	tests := []struct {
+		name string
+		opts Options
+		err  string
+	}{
+		{"no client certificate", Options{}, "certificate required"},
+		{"key file", Options{ClientCertFile: ecCert, ClientKeyFile: ecKeyFile}, ""},
+		{"signer of another key", Options{ClientCertFile: ecCert, ClientKey: pkcs1Signer{rsaKey}}, "not issued for the client key"},
+		{"EC signer", Options{ClientCertFile: ecCert, ClientKey: struct{ crypto.Signer }{ecKey}}, ""},
+		{"RSA PKCS #1 signer", Options{ClientCertFile: rsaCert, ClientKey: pkcs1Signer{rsaKey}}, ""},
+		{"missing key file", Options{ClientCertFile: ecCert, ClientKeyFile: filepath.Join(t.TempDir(), "missing.pem")}, "failed to load client certificate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.InsecureSkipVerify = true
+			conf, err := Config(tt.opts)
+			if err == nil {
+				client := &http.Client{Transport: &http.Transport{TLSClientConfig: conf}}
+				var resp *http.Response
+				if resp, err = client.Get(srv.URL); err == nil {
+					body, _ := io.ReadAll(resp.Body)
+					_ = resp.Body.Close()
+					if string(body) != "device" {
+						t.Errorf("server saw client certificate %q, want device", body)
+					}
+				}
+			}
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("request failed: %v", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Errorf("error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestConfig_MinVersion(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.NotFoundHandler())
+	srv.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	srv.StartTLS()
+	defer srv.Close()
+
+	for _, tt := range []struct {
+		minVersion uint16
+		err        string
+	}{
+		{0, ""},
+		{tls.VersionTLS13, "protocol version"},
+	} {
+		conf, err := Config(Options{InsecureSkipVerify: true, MinVersion: tt.minVersion})
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: conf}}
+		resp, err := client.Get(srv.URL)
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("min version %x: request failed: %v", tt.minVersion, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("min version %x: error = %v, want %q", tt.minVersion, err, tt.err)
+		}
+	}
+}
@@ -4,9 +4,13 @@
 package tls
 
 import (
+	"cmp"
 	"crypto/tls"
+	"log/slog"
 	"net"
 	net_http "net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/fido-device-onboard/go-fdo"
@@ -14,13 +18,39 @@ import (
 	"github.com/fido-device-onboard/go-fdo/http"
 )
 
-func TlsTransport(baseURL string, conf *tls.Config, insecureTLS bool) fdo.Transport {
-	preferredCipherSuites := []uint16{
-		tls.TLS_AES_256_GCM_SHA384,                  // TLS v1.3
-		tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,   // TLS v1.2
-		tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384, // TLS v1.2
-	}
+// TransportOptions configures the connections of a transport. Zero values
+// select the defaults.
+type TransportOptions struct {
+	// ConnectTimeout limits establishing TCP connections, 30s by default.
+	ConnectTimeout time.Duration
+	// TLSHandshakeTimeout limits TLS handshakes, 10s by default.
+	TLSHandshakeTimeout time.Duration
+	// RequestTimeout limits each request including reading the response,
+	// unlimited by default.
+	RequestTimeout time.Duration
+
+	// Proxy is the proxy of all requests instead of the proxy selected by
+	// the proxy environment variables. Its user info is sent as proxy
+	// credentials.
+	Proxy *url.URL
+	// NoProxy lists the hosts connected to directly instead of through
+	// Proxy, in the syntax of the NO_PROXY environment variable.
+	NoProxy []string
+	// Direct disables proxies, including those of the environment.
+	Direct bool
+
+	// SourceAddress is the local IP address connections are made from.
+	SourceAddress net.IP
+	// Interface is the name of the network interface connections are bound
+	// to.
+	Interface string
+
+	// Trace, if not nil, logs each FDO message at debug level with its
+	// secrets redacted.
+	Trace *slog.Logger
+}
 
+func TlsTransport(baseURL string, conf *tls.Config, insecureTLS bool, opts TransportOptions) fdo.Transport {
 	if conf == nil {
 		conf = &tls.Config{
 			CipherSuites:       preferredCipherSuites,
@@ -28,22 +58,115 @@ func TlsTransport(baseURL string, conf *tls.Config, insecureTLS bool) fdo.Transp
 		}
 	}
 
+	var transport net_http.RoundTripper = &userAgentTransport{&net_http.Transport{
+		Proxy:                 opts.proxy(),
+		DialContext:           opts.Dialer("tcp").DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSClientConfig:       conf,
+		TLSHandshakeTimeout:   cmp.Or(opts.TLSHandshakeTimeout, 10*time.Second),
+		ExpectContinueTimeout: 1 * time.Second,
+	}, "go-fdo-client/" + version.VERSION}
+	if opts.Trace != nil {
+		transport = &traceTransport{next: transport, log: opts.Trace}
+	}
+
 	return &http.Transport{
 		BaseURL: baseURL,
-		Client: &net_http.Client{Transport: &userAgentTransport{&net_http.Transport{
-			Proxy: net_http.ProxyFromEnvironment,
-			DialContext: (&net.Dialer{
-				Timeout:   30 * time.Second,
-				KeepAlive: 30 * time.Second,
-			}).DialContext,
-			ForceAttemptHTTP2:     true,
-			MaxIdleConns:          100,
-			IdleConnTimeout:       90 * time.Second,
-			TLSClientConfig:       conf,
-			TLSHandshakeTimeout:   10 * time.Second,
-			ExpectContinueTimeout: 1 * time.Second,
-		}, "go-fdo-client/" + version.VERSION}},
+		Client:  &net_http.Client{Timeout: opts.RequestTimeout, Transport: transport},
+	}
+}
+
+// Dialer returns the dialer of the connections of a network, "tcp" or "udp",
+// bound to the source address and interface of the options.
+func (opts TransportOptions) Dialer(network string) *net.Dialer {
+	dialer := &net.Dialer{
+		Timeout:   cmp.Or(opts.ConnectTimeout, 30*time.Second),
+		KeepAlive: 30 * time.Second,
+	}
+	if opts.SourceAddress != nil {
+		if network == "udp" {
+			dialer.LocalAddr = &net.UDPAddr{IP: opts.SourceAddress}
+		} else {
+			dialer.LocalAddr = &net.TCPAddr{IP: opts.SourceAddress}
+		}
+	}
+	if opts.Interface != "" {
+		dialer.Control = bindToInterface(opts.Interface)
+	}
+	return dialer
+}
+
+// proxy returns the proxy selection of the options.
+func (opts TransportOptions) proxy() func(*net_http.Request) (*url.URL, error) {
+	switch {
+	case opts.Direct:
+		return nil
+	case opts.Proxy == nil:
+		return net_http.ProxyFromEnvironment
+	}
+	return func(req *net_http.Request) (*url.URL, error) {
+		if NoProxy(req.URL, opts.NoProxy) {
+			return nil, nil
+		}
+		return opts.Proxy, nil
+	}
+}
+
+// NoProxy reports whether the host of u is connected to directly according to
+// a NO_PROXY list. Like for the proxy environment variables, localhost and
+// loopback addresses are never proxied. Entries are host names, which also
+// match their subdomains, domains with a leading dot, IP addresses, CIDR
+// ranges or "*" for all hosts; an entry with a port only matches that port,
+// the default port of the scheme if u has none.
+func NoProxy(u *url.URL, noProxy []string) bool {
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if port == "" {
+		port = defaultPort(u.Scheme)
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	if ip != nil && ip.IsLoopback() {
+		return true
+	}
+	for _, entry := range noProxy {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if h, p, err := net.SplitHostPort(entry); err == nil {
+			if p != port {
+				continue
+			}
+			entry = h
+		}
+		switch {
+		case entry == "":
+		case entry == "*":
+			return true
+		case strings.Contains(entry, "/"):
+			if _, cidr, err := net.ParseCIDR(entry); err == nil && ip != nil && cidr.Contains(ip) {
+				return true
+			}
+		case strings.HasPrefix(entry, "."):
+			if strings.HasSuffix(host, entry) {
+				return true
+			}
+		case host == entry || strings.HasSuffix(host, "."+entry):
+			return true
+		}
+	}
+	return false
+}
+
+func defaultPort(scheme string) string {
+	switch scheme {
+	case "http":
+		return "80"
+	case "https", "tls":
+		return "443"
 	}
+	return ""
 }
 
 type userAgentTransport struct {
@@ -0,0 +1,156 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+package tls
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	fdohttp "github.com/fido-device-onboard/go-fdo/http"
+)
+
+// get sends a GET request through the HTTP client of a transport.
+func get(t *testing.T, opts TransportOptions, conf *tls.Config, rawURL string) (string, error) {
+	t.Helper()
+	transport := TlsTransport(rawURL, conf, false, opts).(*fdohttp.Transport)
+	resp, err := transport.Client.Get(rawURL)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	return string(body), err
+}
+
+func TestNoProxy(t *testing.T) {
+	noProxy := []string{"internal.example.com", " .corp.example.com", "10.0.0.0/8", "192.168.1.5:8041", "rv.example.org:443"}
+	tests := []struct {
+		url    string
+		direct bool
+	}{
+		{"https://rv.example.com", false},
+		{"http://localhost:8080", true},
+		{"https://127.0.0.1:8443", true},
+		{"https://[::1]:8443", true},
+		{"https://internal.example.com", true},
+		{"https://rv.internal.example.com:8443", true},
+		{"https://RV.Corp.Example.com", true},
+		{"https://corp.example.com", false},
+		{"https://10.1.2.3:8443", true},
+		{"http://192.168.1.5:8041", true},
+		{"http://192.168.1.5:8080", false},
+		{"http://192.168.1.6:8041", false},
+		{"https://rv.example.org", true},
+		{"http://rv.example.org", false},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := NoProxy(u, noProxy); got != tt.direct {
+			t.Errorf("NoProxy(%s) = %t, want %t", tt.url, got, tt.direct)
+		}
+	}
+	if !NoProxy(&url.URL{Scheme: "https", Host: "rv.example.com"}, []string{"*"}) {
+		t.Error("* does not match every host")
+	}
+}
+
+func TestTlsTransport_Proxy(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.URL.Host+" "+r.Header.Get("Proxy-Authorization"))
+	}))
+	defer proxy.Close()
+	proxyURL, err := url.Parse(proxy.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxyURL.User = url.UserPassword("device", "secret")
+
+	body, err := get(t, TransportOptions{Proxy: proxyURL}, nil, "http://rv.example.com:8041/fdo/101/msg/30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// "device:secret" in base64
+	if want := "rv.example.com:8041 Basic ZGV2aWNlOnNlY3JldA=="; body != want {
+		t.Errorf("proxy received %q, want %q", body, want)
+	}
+
+	opts := TransportOptions{Proxy: proxyURL, NoProxy: []string{".example.com"}}
+	for _, tt := range []struct {
+		opts    TransportOptions
+		rawURL  string
+		proxied bool
+	}{
+		{opts, "https://rv.example.com", false},
+		{opts, "https://rv.example.org", true},
+		{TransportOptions{Direct: true}, "https://rv.example.org", false},
+	} {
+		req := httptest.NewRequest(http.MethodPost, tt.rawURL, nil)
+		var got *url.URL
+		if proxy := tt.opts.proxy(); proxy != nil {
+			if got, err = proxy(req); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if (got != nil) != tt.proxied {
+			t.Errorf("%s proxied = %v, want %t", tt.rawURL, got, tt.proxied)
+		}
+	}
+}
+
+func TestTlsTransport_Timeouts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	_, err := get(t, TransportOptions{RequestTimeout: 50 * time.Millisecond}, nil, srv.URL)
+	if err == nil || !strings.Contains(err.Error(), "Client.Timeout exceeded") {
+		t.Errorf("error = %v, want request timeout", err)
+	}
+
+	// A listener that never completes TLS handshakes
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = ln.Close() }()
+	_, err = get(t, TransportOptions{TLSHandshakeTimeout: 50 * time.Millisecond}, nil, "https://"+ln.Addr().String())
+	if err == nil || !strings.Contains(err.Error(), "TLS handshake timeout") {
+		t.Errorf("error = %v, want TLS handshake timeout", err)
+	}
+}
+
+func TestTlsTransport_SourceAddress(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("only Linux routes all of 127.0.0.0/8 to the loopback interface")
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.RemoteAddr)
+		_, _ = io.WriteString(w, host)
+	}))
+	defer srv.Close()
+
+	body, err := get(t, TransportOptions{SourceAddress: net.ParseIP("127.0.0.2")}, nil, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "127.0.0.2" {
+		t.Errorf("server saw a connection from %s, want 127.0.0.2", body)
+	}
+
+	if _, err := get(t, TransportOptions{Interface: "no-such-if0"}, nil, srv.URL); err == nil || !strings.Contains(err.Error(), "no-such-if0") {
+		t.Errorf("error = %v, want a failure to bind to no-such-if0", err)
+	}
+}
@@ -0,0 +1,393 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+package tls
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	net_http "net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fido-device-onboard/go-fdo/cbor"
+)
+
+// maxtraceBody is the size up to which message bodies are traced.
+const maxtraceBody = 1 << 20
+
+// msgNames are the names of the FDO message types.
+var msgNames = map[uint8]string{
+	10:  "DI.AppStart",
+	11:  "DI.SetCredentials",
+	12:  "DI.SetHMAC",
+	13:  "DI.Done",
+	30:  "TO1.HelloRV",
+	31:  "TO1.HelloRVAck",
+	32:  "TO1.ProveToRV",
+	33:  "TO1.RVRedirect",
+	60:  "TO2.HelloDevice",
+	61:  "TO2.ProveOVHdr",
+	62:  "TO2.GetOVNextEntry",
+	63:  "TO2.OVNextEntry",
+	64:  "TO2.ProveDevice",
+	65:  "TO2.SetupDevice",
+	66:  "TO2.DeviceServiceInfoReady",
+	67:  "TO2.OwnerServiceInfoReady",
+	68:  "TO2.DeviceServiceInfo",
+	69:  "TO2.OwnerServiceInfo",
+	70:  "TO2.Done",
+	71:  "TO2.Done2",
+	255: "Error",
+}
+
+// COSE tags and labels of the traced messages.
+const (
+	coseEncrypt0Tag = 16
+	coseMac0Tag     = 17
+	coseSign1Tag    = 18
+
+	eatNonceLabel        = 10   // EAT nonce claim
+	eatFDOLabel          = -257 // EAT FDO claim, the key exchange of TO2.ProveDevice
+	eatUnprotectedNonce  = -259 // EUPHNonce unprotected header
+	ovhProofNonceLabel   = 256  // CUPHNonce unprotected header of TO2.ProveOVHdr
+	ovhProofNonceIndex   = 3    // NonceTO2ProveOV of the TO2.ProveOVHdr payload
+	ovhProofKexIndex     = 5    // xAKeyExchange of the TO2.ProveOVHdr payload
+	helloRVAckNonceIndex = 0    // NonceTO1Proof of TO1.HelloRVAck
+	helloDeviceNonce     = 2    // NonceTO2ProveOV of TO2.HelloDevice
+)
+
+// traceTransport logs each request and response with the FDO message type,
+// status, latency and a CBOR diagnostic rendering of the body. Nonces, key
+// exchange parameters and encrypted payloads are redacted, so that a trace
+// can be shared to debug interoperability.
+type traceTransport struct {
+	next net_http.RoundTripper
+	log  *slog.Logger
+}
+
+func (t *traceTransport) RoundTrip(req *net_http.Request) (*net_http.Response, error) {
+	ctx := req.Context()
+	reqType, _ := strconv.ParseUint(path.Base(req.URL.Path), 10, 8)
+	// Read a copy of the body to leave the request untouched
+	var data []byte
+	if req.Body != nil && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			data, _ = io.ReadAll(io.LimitReader(body, maxtraceBody+1))
+			_ = body.Close()
+		}
+	}
+	t.log.DebugContext(ctx, "FDO request", traceAttrs(uint8(reqType), data,
+		slog.String("method", req.Method), slog.String("url", req.URL.Redacted()))...)
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		t.log.DebugContext(ctx, "FDO request failed", "url", req.URL.Redacted(),
+			"latency", time.Since(start).Round(time.Millisecond), "error", err)
+		return nil, err
+	}
+	latency := time.Since(start).Round(time.Millisecond)
+
+	// Trace a prefix of the body and pass on the whole body
+	data, readErr := io.ReadAll(io.LimitReader(resp.Body, maxtraceBody+1))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), resp.Body), resp.Body}
+	if readErr != nil {
+		t.log.DebugContext(ctx, "FDO response failed", "url", req.URL.Redacted(), "status", resp.StatusCode,
+			"latency", latency, "error", readErr)
+		return resp, nil
+	}
+	respType, _ := strconv.ParseUint(strings.TrimSpace(resp.Header.Get("Message-Type")), 10, 8)
+	t.log.DebugContext(ctx, "FDO response", traceAttrs(uint8(respType), data,
+		slog.Int("status", resp.StatusCode), slog.Duration("latency", latency))...)
+	return resp, nil
+}
+
+// traceAttrs returns the log attributes of a message.
+func traceAttrs(msgType uint8, body []byte, attrs ...slog.Attr) []any {
+	args := []any{slog.Int("msg_type", int(msgType))}
+	if name, ok := msgNames[msgType]; ok {
+		args = append(args, slog.String("msg_name", name))
+	}
+	for _, attr := range attrs {
+		args = append(args, attr)
+	}
+	return append(args, slog.Int("size", len(body)), slog.String("body", traceBody(msgType, body)))
+}
+
+// traceBody renders the body of an FDO message in CBOR diagnostic notation
+// with its secrets redacted. Bodies that are not CBOR are rendered as text
+// if they are printable, and by their size otherwise.
+func traceBody(msgType uint8, body []byte) string {
+	switch {
+	case len(body) == 0:
+		return ""
+	case len(body) > maxtraceBody:
+		return fmt.Sprintf("/%d+ bytes not traced/", maxtraceBody)
+	}
+	var v any
+	if err := cbor.Unmarshal(body, &v); err == nil {
+		v = expandTags(v)
+		redactMessage(msgType, v)
+		var b strings.Builder
+		writeDiag(&b, v)
+		return b.String()
+	}
+	if utf8.Valid(body) {
+		text := strings.TrimSpace(string(body))
+		if len(text) > 200 {
+			text = text[:200] + "..."
+		}
+		return strconv.Quote(text)
+	}
+	return fmt.Sprintf("/%d bytes of invalid CBOR/", len(body))
+}
+
+// tagged is a CBOR tag with a decoded value.
+type tagged struct {
+	num uint64
+	val any
+}
+
+// embedded is CBOR encoded in a byte string, rendered as <<value>>.
+type embedded struct{ val any }
+
+// redacted replaces a secret value, rendered as a comment with its size.
+type redacted struct{ size int }
+
+// expandTags decodes the values of tags, and the headers and payloads of
+// COSE structures which are encoded in byte strings.
+func expandTags(v any) any {
+	switch v := v.(type) {
+	case []any:
+		for i := range v {
+			v[i] = expandTags(v[i])
+		}
+	case map[any]any:
+		for key, val := range v {
+			v[key] = expandTags(val)
+		}
+	case cbor.Tag[cbor.RawBytes]:
+		var val any
+		if err := cbor.Unmarshal(v.Val, &val); err != nil {
+			return v.Val
+		}
+		t := &tagged{num: v.Num, val: expandTags(val)}
+		if arr, ok := t.val.([]any); ok && len(arr) >= 3 {
+			switch t.num {
+			case coseSign1Tag:
+				arr[0], arr[2] = embed(arr[0]), embed(arr[2])
+			case coseEncrypt0Tag, coseMac0Tag:
+				arr[0] = embed(arr[0])
+			}
+		}
+		return t
+	}
+	return v
+}
+
+// embed decodes a byte string holding CBOR.
+func embed(v any) any {
+	data, ok := v.([]byte)
+	if !ok || len(data) == 0 {
+		return v
+	}
+	var val any
+	if err := cbor.Unmarshal(data, &val); err != nil {
+		return v
+	}
+	return embedded{expandTags(val)}
+}
+
+// redactMessage redacts the nonces, key exchange parameters and encrypted
+// payloads of a message.
+func redactMessage(msgType uint8, v any) {
+	redactCiphertext(v)
+	switch msgType {
+	case 31:
+		redactIndex(v, helloRVAckNonceIndex)
+	case 60:
+		redactIndex(v, helloDeviceNonce)
+	case 61:
+		unprotected, payload := sign1(v)
+		redactLabel(unprotected, ovhProofNonceLabel)
+		redactIndex(payload, ovhProofNonceIndex)
+		redactIndex(payload, ovhProofKexIndex)
+	case 32, 64:
+		unprotected, payload := sign1(v)
+		redactLabel(unprotected, eatUnprotectedNonce)
+		claims, _ := payload.(map[any]any)
+		redactLabel(claims, eatNonceLabel)
+		redactLabel(claims, eatFDOLabel)
+	}
+}
+
+// redactCiphertext redacts the ciphertext of COSE_Encrypt0 and the payload of
+// COSE_Mac0 structures, which hold the encrypted TO2 messages.
+func redactCiphertext(v any) {
+	switch v := v.(type) {
+	case []any:
+		for _, val := range v {
+			redactCiphertext(val)
+		}
+	case map[any]any:
+		for _, val := range v {
+			redactCiphertext(val)
+		}
+	case embedded:
+		redactCiphertext(v.val)
+	case *tagged:
+		if v.num == coseEncrypt0Tag || v.num == coseMac0Tag {
+			redactIndex(v.val, 2)
+		}
+		redactCiphertext(v.val)
+	}
+}
+
+// sign1 returns the unprotected header and the decoded payload of a
+// COSE_Sign1 message.
+func sign1(v any) (map[any]any, any) {
+	t, ok := v.(*tagged)
+	if !ok || t.num != coseSign1Tag {
+		return nil, nil
+	}
+	arr, _ := t.val.([]any)
+	if len(arr) < 3 {
+		return nil, nil
+	}
+	unprotected, _ := arr[1].(map[any]any)
+	payload, _ := arr[2].(embedded)
+	return unprotected, payload.val
+}
+
+func redactIndex(v any, i int) {
+	if arr, ok := v.([]any); ok && i < len(arr) {
+		arr[i] = redact(arr[i])
+	}
+}
+
+func redactLabel(m map[any]any, label int64) {
+	if val, ok := m[label]; ok {
+		m[label] = redact(val)
+	}
+}
+
+// redact returns the replacement of a secret value with the size of its
+// encoding.
+func redact(v any) any {
+	switch v := v.(type) {
+	case []byte:
+		return redacted{len(v)}
+	case redacted:
+		return v
+	}
+	data, err := cbor.Marshal(toCBOR(v))
+	if err != nil {
+		return redacted{}
+	}
+	return redacted{len(data)}
+}
+
+// toCBOR reverts expandTags for measuring the size of a value.
+func toCBOR(v any) any {
+	switch v := v.(type) {
+	case []any:
+		out := make([]any, len(v))
+		for i, val := range v {
+			out[i] = toCBOR(val)
+		}
+		return out
+	case map[any]any:
+		out := make(map[any]any, len(v))
+		for key, val := range v {
+			out[key] = toCBOR(val)
+		}
+		return out
+	case embedded:
+		data, _ := cbor.Marshal(toCBOR(v.val))
+		return data
+	case *tagged:
+		return cbor.Tag[any]{Num: v.num, Val: toCBOR(v.val)}
+	case redacted:
+		return make([]byte, v.size)
+	}
+	return v
+}
+
+// writeDiag writes a value in CBOR diagnostic notation.
+func writeDiag(b *strings.Builder, v any) {
+	switch v := v.(type) {
+	case nil:
+		b.WriteString("null")
+	case []byte:
+		b.WriteString("h'" + hex.EncodeToString(v) + "'")
+	case string:
+		b.WriteString(strconv.Quote(v))
+	case redacted:
+		fmt.Fprintf(b, "/redacted %d bytes/", v.size)
+	case embedded:
+		b.WriteString("<<")
+		writeDiag(b, v.val)
+		b.WriteString(">>")
+	case *tagged:
+		b.WriteString(strconv.FormatUint(v.num, 10) + "(")
+		writeDiag(b, v.val)
+		b.WriteString(")")
+	case []any:
+		b.WriteString("[")
+		for i, val := range v {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			writeDiag(b, val)
+		}
+		b.WriteString("]")
+	case map[any]any:
+		keys := make([]string, 0, len(v))
+		entries := make(map[string]any, len(v))
+		for key, val := range v {
+			var kb strings.Builder
+			writeDiag(&kb, key)
+			keys = append(keys, kb.String())
+			entries[kb.String()] = val
+		}
+		slices.SortFunc(keys, compareDiagKeys)
+		b.WriteString("{")
+		for i, key := range keys {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(key + ": ")
+			writeDiag(b, entries[key])
+		}
+		b.WriteString("}")
+	default:
+		fmt.Fprint(b, v)
+	}
+}
+
+// compareDiagKeys orders integer map keys numerically before other keys.
+func compareDiagKeys(a, b string) int {
+	ai, aErr := strconv.ParseInt(a, 10, 64)
+	bi, bErr := strconv.ParseInt(b, 10, 64)
+	switch {
+	case aErr == nil && bErr == nil:
+		return cmp.Compare(ai, bi)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,115 @@
+// SPDX-FileCopyrightText: (C) 2025 Intel Corporation
+// SPDX-License-Identifier: Apache 2.0
+
+package tls
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fido-device-onboard/go-fdo/cbor"
+	fdohttp "github.com/fido-device-onboard/go-fdo/http"
+)
+
+func mustMarshal(t *testing.T, v any) []byte {
+	t.Helper()
+	data, err := cbor.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestTraceBody(t *testing.T) {
+	guid := bytes.Repeat([]byte{0x11}, 16)
+	nonce := bytes.Repeat([]byte{0xaa}, 16)
+	xB := bytes.Repeat([]byte{0xbb}, 32)
+
+	proveDevice := cbor.Tag[[]any]{Num: coseSign1Tag, Val: []any{
+		mustMarshal(t, map[int64]any{1: -7}),
+		map[int64]any{eatUnprotectedNonce: nonce},
+		mustMarshal(t, map[int64]any{eatNonceLabel: nonce, 256: append([]byte{1}, guid...), eatFDOLabel: []any{xB}}),
+		[]byte{0xcc},
+	}}
+	encrypted := cbor.Tag[[]any]{Num: coseEncrypt0Tag, Val: []any{
+		mustMarshal(t, map[int64]any{1: 1}),
+		map[int64]any{5: []byte{0x01, 0x02}},
+		bytes.Repeat([]byte{0xdd}, 40),
+	}}
+
+	tests := []struct {
+		name    string
+		msgType uint8
+		body    []byte
+		want    string
+	}{
+		{"TO2.HelloDevice", 60, mustMarshal(t, []any{65535, guid, nonce, "ECDH384", 1, []any{-35, []byte{}}}),
+			`[65535, h'11111111111111111111111111111111', /redacted 16 bytes/, "ECDH384", 1, [-35, h'']]`},
+		{"TO1.HelloRVAck", 31, mustMarshal(t, []any{nonce, []any{-7, []byte{}}}),
+			`[/redacted 16 bytes/, [-7, h'']]`},
+		{"TO2.ProveDevice", 64, mustMarshal(t, proveDevice),
+			`18([<<{1: -7}>>, {-259: /redacted 16 bytes/}, <<{-257: /redacted 35 bytes/, 10: /redacted 16 bytes/, 256: h'0111111111111111111111111111111111'}>>, h'cc'])`},
+		{"TO2.SetupDevice", 65, mustMarshal(t, encrypted),
+			`16([<<{1: 1}>>, {5: h'0102'}, /redacted 40 bytes/])`},
+		{"error message", 255, mustMarshal(t, []any{100, 60, "invalid message", 0, 0}),
+			`[100, 60, "invalid message", 0, 0]`},
+		{"text", 0, []byte("404 page not found\n"), `"404 page not found"`},
+		{"binary", 0, []byte{0xff, 0xfe}, "/2 bytes of invalid CBOR/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := traceBody(tt.msgType, tt.body); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTraceTransport(t *testing.T) {
+	nonce := bytes.Repeat([]byte{0xaa}, 16)
+	respBody := mustMarshal(t, []any{nonce, []any{-7, []byte{}}})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/cbor")
+		w.Header().Set("Message-Type", "31")
+		_, _ = w.Write(respBody)
+	}))
+	defer srv.Close()
+
+	var trace bytes.Buffer
+	log := slog.New(slog.NewTextHandler(&trace, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	transport := TlsTransport(srv.URL, nil, false, TransportOptions{Trace: log}).(*fdohttp.Transport)
+
+	guid := bytes.Repeat([]byte{0x11}, 16)
+	msgType, body, err := transport.Send(context.Background(), 30, []any{guid, []any{-7, []byte{}}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = body.Close() }()
+	got, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msgType != 31 || !bytes.Equal(got, respBody) {
+		t.Errorf("received message %d %x, want 31 %x", msgType, got, respBody)
+	}
+
+	for _, want := range []string{
+		`msg="FDO request" msg_type=30 msg_name=TO1.HelloRV method=POST url=` + srv.URL + `/fdo/101/msg/30`,
+		`body="[h'11111111111111111111111111111111', [-7, h'']]"`,
+		`msg="FDO response" msg_type=31 msg_name=TO1.HelloRVAck status=200 latency=`,
+		`body="[/redacted 16 bytes/, [-7, h'']]"`,
+	} {
+		if !strings.Contains(trace.String(), want) {
+			t.Errorf("trace does not contain %s:\n%s", want, trace.String())
+		}
+	}
+	if strings.Contains(trace.String(), "aaaaaaaa") {
+		t.Errorf("trace contains the nonce:\n%s", trace.String())
+	}
+}
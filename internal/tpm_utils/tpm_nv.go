@@ -159,3 +159,28 @@ func TpmNVWrite(thetpm transport.TPM, data []byte, nv tpm2.TPMHandle, tpmHashAlg
 	}
 	return nil
 }
+
+// TpmNVDelete undefines the specified NV index if it is defined. It returns
+// false if there was no NV index to delete.
+func TpmNVDelete(thetpm transport.TPM, nv tpm2.TPMHandle) (bool, error) {
+	readPub := tpm2.NVReadPublic{
+		NVIndex: nv,
+	}
+	readPubRsp, err := readPub.Execute(thetpm)
+	if err != nil {
+		// Assume the index is not defined
+		return false, nil
+	}
+	nvPublic, err := readPubRsp.NVPublic.Contents()
+	if err != nil {
+		return false, fmt.Errorf("getting NV public contents: %v", err)
+	}
+	nvName, err := tpm2.NVName(nvPublic)
+	if err != nil {
+		return false, fmt.Errorf("calculating name of NV index: %v", err)
+	}
+	if err := TpmNVUnDefine(thetpm, nv, nvName); err != nil {
+		return false, err
+	}
+	return true, nil
+}
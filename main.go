@@ -12,6 +12,6 @@ import (
 func main() {
 	err := cmd.Execute()
 	if err != nil {
-		os.Exit(1)
+		os.Exit(cmd.ExitCode(err))
 	}
 }